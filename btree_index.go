@@ -0,0 +1,1034 @@
+package skiptablev2
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync/atomic"
+)
+
+const (
+	//btree 每个结点最多的元素(叶子结点)或者子结点(内部结点)数量, 超过后分裂
+	btreeMaxItems = 64
+	//除了根结点, 每个结点最少的元素或者子结点数量, 少于这个数量时和相邻的结点合并或者从相邻的结点借
+	btreeMinItems = btreeMaxItems / 2
+)
+
+// btreeOwnerID
+// 分配B树所有者编号的计数器
+var btreeOwnerID atomic.Uint64
+
+// btreeNode
+// B+树的结点, 元素都在叶子结点中, 内部结点只记录子结点和每个子树的元素数量
+type btreeNode[S cmp.Ordered, V any] struct {
+	//所属B树的编号, 只有属于自己的结点可以直接修改, 其余的结点和别的树(快照)共享
+	owner uint64
+	//叶子结点的元素, 有序
+	items []listpackEntry[S, V]
+	//内部结点的子结点, 叶子结点是 nil
+	children []*btreeNode[S, V]
+	//每个子树的元素数量, 用来在 O(log n) 内计算排名和根据排名查找
+	counts []int64
+	//每个子树的分数之和, 用来在 O(log n) 内计算一段范围的分数之和
+	sums []float64
+	//每个子树中分数和前一个元素不同的元素数量, 子树的第一个元素和前一个子树的最后一个元素比较, 第一个子树的第一个元素不算
+	//和跳表的 groups 一样, 用来在 O(log n) 内计算排在前面的不同分数的数量(dense 排名)
+	groups []int64
+	//keys[i] 是 children[i+1] 中所有元素的下界, 删除元素后不需要更新, 仍然是合法的下界
+	keys []listpackEntry[S, V]
+}
+
+func (n *btreeNode[S, V]) leaf() bool {
+	return n.children == nil
+}
+
+// btreeIndex
+// 带子树元素数量的B+树(rank-augmented B-tree), 作为有序集合的另一种有序索引
+// 和跳表相比, 元素连续存放在叶子结点的数组中, 指针更少, 对缓存更友好
+// 除了根结点, 每个结点至少半满, 插入、删除、切开和拼接之后都保持这个要求, 树的高度是 O(log n)
+// 复制是 O(1) 的: 两棵树共享所有结点, 修改时只复制从根结点到修改位置路径上的结点(path copying)
+type btreeIndex[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
+	root *btreeNode[S, V]
+	size int64
+	//结点的 owner 和它相同时可以直接修改, 否则要先复制
+	owner uint64
+	//排序规则, 和跳表相同
+	scoreOrder[S, V]
+}
+
+func newBTreeIndex[K comparable, S cmp.Ordered, V ScoredItem[K, S]](order ScoreOrder, compare func(v1, v2 V) int) (*btreeIndex[K, S, V], error) {
+	if compare == nil {
+		return nil, errors.New("newBTreeIndex compare function is nil")
+	}
+	owner := btreeOwnerID.Add(1)
+	return &btreeIndex[K, S, V]{
+		root:  &btreeNode[S, V]{owner: owner},
+		owner: owner,
+		scoreOrder: scoreOrder[S, V]{
+			compare: compare,
+			desc:    order == SCORE_ORDER_DESC,
+		},
+	}, nil
+}
+
+// countPrefix
+// 满足 pred 的元素数量, pred 必须满足 从头开始连续为true, 之后都为false
+// 同时返回这些元素的分数之和
+func (tree *btreeIndex[K, S, V]) countPrefix(pred func(e *listpackEntry[S, V]) bool) (int64, float64) {
+	rank := int64(0)
+	sum := float64(0)
+	n := tree.root
+	for !n.leaf() {
+		//keys[c-1] 满足 pred, 所以前c个子树的元素都满足; keys[c] 不满足, 所以后面子树的元素都不满足
+		c := sort.Search(len(n.keys), func(i int) bool {
+			return !pred(&n.keys[i])
+		})
+		for i := 0; i < c; i++ {
+			rank += n.counts[i]
+			sum += n.sums[i]
+		}
+		n = n.children[c]
+	}
+	c := sort.Search(len(n.items), func(i int) bool {
+		return !pred(&n.items[i])
+	})
+	for i := 0; i < c; i++ {
+		sum += sumFloat(n.items[i].score)
+	}
+	return rank + int64(c), sum
+}
+
+// prefixByRank
+// 排名 <= rank 的所有元素的分数之和
+func (tree *btreeIndex[K, S, V]) prefixByRank(rank int64) float64 {
+	sum := float64(0)
+	n := tree.root
+	for !n.leaf() {
+		i := 0
+		for ; i < len(n.counts) && rank >= n.counts[i]; i++ {
+			rank -= n.counts[i]
+			sum += n.sums[i]
+		}
+		if i == len(n.counts) || rank == 0 {
+			return sum
+		}
+		n = n.children[i]
+	}
+	for i := 0; i < int(rank); i++ {
+		sum += sumFloat(n.items[i].score)
+	}
+	return sum
+}
+
+// prefixGroups
+// 排名 <= rank 的元素中分数和前一个元素不同的元素数量, 第一个元素不算, 复杂度 O(log n)
+func (tree *btreeIndex[K, S, V]) prefixGroups(rank int64) int64 {
+	groups := int64(0)
+	n := tree.root
+	for !n.leaf() {
+		i := 0
+		for ; i < len(n.counts) && rank >= n.counts[i]; i++ {
+			rank -= n.counts[i]
+			groups += n.groups[i]
+		}
+		if i == len(n.counts) || rank == 0 {
+			return groups
+		}
+		//子树 i 只包含一部分, 它的第一个元素和前一个子树的最后一个元素比较
+		if i > 0 && btreeLast(n.children[i-1]).score != btreeFirst(n.children[i]).score {
+			groups++
+		}
+		n = n.children[i]
+	}
+	for i := 1; i < int(rank); i++ {
+		if n.items[i].score != n.items[i-1].score {
+			groups++
+		}
+	}
+	return groups
+}
+
+// findRank
+// 查找 (score, value) 的排名, 不存在返回0
+func (tree *btreeIndex[K, S, V]) findRank(score S, value V) int64 {
+	rank, _ := tree.countPrefix(func(e *listpackEntry[S, V]) bool {
+		return tree.before(e.score, e.value, score, value)
+	})
+	rank++
+	e := tree.entryByRank(rank)
+	if e == nil || e.score != score || tree.compare(e.value, value) != 0 {
+		return 0
+	}
+	return rank
+}
+
+// entryByRank
+// 根据排名查找元素, 超出范围返回 nil
+func (tree *btreeIndex[K, S, V]) entryByRank(rank int64) *listpackEntry[S, V] {
+	if rank <= 0 || rank > tree.size {
+		return nil
+	}
+	n := tree.root
+	for !n.leaf() {
+		i := 0
+		for ; rank > n.counts[i]; i++ {
+			rank -= n.counts[i]
+		}
+		n = n.children[i]
+	}
+	return &n.items[rank-1]
+}
+
+// each
+// 从排名 rank 开始按顺序遍历元素, fn 返回 false 时停止
+func (tree *btreeIndex[K, S, V]) each(n *btreeNode[S, V], rank int64, fn func(e *listpackEntry[S, V]) bool) bool {
+	if n.leaf() {
+		for i := int(rank - 1); i < len(n.items); i++ {
+			if !fn(&n.items[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	for i, child := range n.children {
+		if rank > n.counts[i] {
+			rank -= n.counts[i]
+			continue
+		}
+		if !tree.each(child, rank, fn) {
+			return false
+		}
+		rank = 1
+	}
+	return true
+}
+
+func (tree *btreeIndex[K, S, V]) Size() int64 {
+	return tree.size
+}
+
+func (tree *btreeIndex[K, S, V]) insert(score S, value V) {
+	e := listpackEntry[S, V]{score: score, value: value}
+	tree.root = tree.mutable(tree.root)
+	if right, key := tree.insertNode(tree.root, e); right != nil {
+		//根结点分裂, 树长高一层
+		tree.root = newBTreeRoot(tree.owner, tree.root, right, key)
+	}
+	tree.size++
+}
+
+// insertNode
+// 在子树中插入元素, n 必须可以直接修改, 结点分裂时返回新的右结点和它的下界
+func (tree *btreeIndex[K, S, V]) insertNode(n *btreeNode[S, V], e listpackEntry[S, V]) (*btreeNode[S, V], listpackEntry[S, V]) {
+	//第一个排在 e 后面的位置
+	after := func(entries []listpackEntry[S, V]) int {
+		return sort.Search(len(entries), func(i int) bool {
+			return tree.before(e.score, e.value, entries[i].score, entries[i].value)
+		})
+	}
+	if n.leaf() {
+		i := after(n.items)
+		n.items = append(n.items, e)
+		copy(n.items[i+1:], n.items[i:])
+		n.items[i] = e
+		if len(n.items) <= btreeMaxItems {
+			return nil, e
+		}
+		return cutBTreeNode(n, len(n.items)/2)
+	}
+
+	c := after(n.keys)
+	n.counts[c]++
+	n.sums[c] += sumFloat(e.score)
+	n.children[c] = tree.mutable(n.children[c])
+	right, key := tree.insertNode(n.children[c], e)
+	if right == nil {
+		fixGroups(n, c, c+1)
+		return nil, e
+	}
+	n.counts[c] -= btreeCount(right)
+	n.sums[c] = btreeSum(n.children[c])
+	n.children = insertAt(n.children, c+1, right)
+	n.counts = insertAt(n.counts, c+1, btreeCount(right))
+	n.sums = insertAt(n.sums, c+1, btreeSum(right))
+	n.groups = insertAt(n.groups, c+1, 0)
+	n.keys = insertAt(n.keys, c, key)
+	fixGroups(n, c, c+2)
+	if len(n.children) <= btreeMaxItems {
+		return nil, e
+	}
+	return cutBTreeNode(n, len(n.children)/2)
+}
+
+// newBTreeRoot
+// 用两个高度相同的子树创建新的根结点, key 是 right 的下界, owner 是新结点所属的树
+func newBTreeRoot[S cmp.Ordered, V any](owner uint64, left, right *btreeNode[S, V], key listpackEntry[S, V]) *btreeNode[S, V] {
+	root := &btreeNode[S, V]{
+		owner:    owner,
+		children: []*btreeNode[S, V]{left, right},
+		counts:   []int64{btreeCount(left), btreeCount(right)},
+		sums:     []float64{btreeSum(left), btreeSum(right)},
+		groups:   make([]int64, 2),
+		keys:     []listpackEntry[S, V]{key},
+	}
+	fixGroups(root, 0, 1)
+	return root
+}
+
+// cutBTreeNode
+// 把结点从第 m 个元素(或者子结点)处切开, 后面的部分移到新的右结点中, 返回右结点和它的下界
+// n 必须可以直接修改, 右结点和 n 属于同一棵树
+func cutBTreeNode[S cmp.Ordered, V any](n *btreeNode[S, V], m int) (*btreeNode[S, V], listpackEntry[S, V]) {
+	if n.leaf() {
+		right := &btreeNode[S, V]{owner: n.owner, items: append([]listpackEntry[S, V](nil), n.items[m:]...)}
+		clear(n.items[m:])
+		n.items = n.items[:m]
+		return right, right.items[0]
+	}
+	key := n.keys[m-1]
+	right := &btreeNode[S, V]{
+		owner:    n.owner,
+		children: append([]*btreeNode[S, V](nil), n.children[m:]...),
+		counts:   append([]int64(nil), n.counts[m:]...),
+		sums:     append([]float64(nil), n.sums[m:]...),
+		groups:   append([]int64(nil), n.groups[m:]...),
+		keys:     append([]listpackEntry[S, V](nil), n.keys[m:]...),
+	}
+	//右结点的第一个子树不再和前一个子树比较
+	fixGroups(right, 0, 0)
+	clear(n.children[m:])
+	clear(n.keys[m-1:])
+	n.children, n.counts, n.sums, n.groups, n.keys = n.children[:m], n.counts[:m], n.sums[:m], n.groups[:m], n.keys[:m-1]
+	return right, key
+}
+
+// appendBTreeNode
+// 把同一层的结点 b 的元素(或者子结点)移到 a 的后面, b 的元素都排在 a 的元素后面, a 必须可以直接修改, b 不会被修改
+func appendBTreeNode[S cmp.Ordered, V any](a, b *btreeNode[S, V]) {
+	if a.leaf() {
+		a.items = append(a.items, b.items...)
+		return
+	}
+	c := len(a.children)
+	a.keys = append(a.keys, *btreeFirst(b))
+	a.keys = append(a.keys, b.keys...)
+	a.children = append(a.children, b.children...)
+	a.counts = append(a.counts, b.counts...)
+	a.sums = append(a.sums, b.sums...)
+	a.groups = append(a.groups, b.groups...)
+	//b 的第一个子树现在有前一个子树了
+	fixGroups(a, c, c)
+}
+
+// balance
+// children[i] 或者 children[i+1] 不到半满时调用, 两个结点合起来放得下时合并成一个, 否则平均分成两个, n 必须可以直接修改
+func (tree *btreeIndex[K, S, V]) balance(n *btreeNode[S, V], i int) {
+	a := tree.mutable(n.children[i])
+	n.children[i] = a
+	appendBTreeNode(a, n.children[i+1])
+	if btreeLen(a) <= btreeMaxItems {
+		n.counts[i] += n.counts[i+1]
+		n.sums[i] += n.sums[i+1]
+		n.children = removeAt(n.children, i+1)
+		n.counts = removeAt(n.counts, i+1)
+		n.sums = removeAt(n.sums, i+1)
+		n.groups = removeAt(n.groups, i+1)
+		n.keys = removeAt(n.keys, i)
+	} else {
+		right, key := cutBTreeNode(a, btreeLen(a)/2)
+		n.children[i+1] = right
+		n.keys[i] = key
+		n.counts[i], n.sums[i] = btreeCount(a), btreeSum(a)
+		n.counts[i+1], n.sums[i+1] = btreeCount(right), btreeSum(right)
+	}
+	fixGroups(n, i, i+2)
+}
+
+func (tree *btreeIndex[K, S, V]) remove(score S, value V) bool {
+	rank := tree.findRank(score, value)
+	if rank == 0 {
+		return false
+	}
+	tree.root = tree.mutable(tree.root)
+	tree.removeRank(tree.root, rank)
+	tree.size--
+	tree.shrink()
+	return true
+}
+
+// shrink
+// 根结点只剩一个子结点时, 树变矮一层
+func (tree *btreeIndex[K, S, V]) shrink() {
+	tree.root = btreeShrink(tree.root)
+}
+
+// btreeShrink
+// 去掉只有一个子结点的根结点, 没有子结点时返回空的叶子结点
+func btreeShrink[S cmp.Ordered, V any](n *btreeNode[S, V]) *btreeNode[S, V] {
+	for !n.leaf() && len(n.children) == 1 {
+		n = n.children[0]
+	}
+	if !n.leaf() && len(n.children) == 0 {
+		return &btreeNode[S, V]{}
+	}
+	return n
+}
+
+// removeRanks
+// 删除排名 [left,right] 内的元素: 在范围的两端切开, 再把两边拼接起来, 复杂度 O(树高 * 结点大小 + 删除的数量)
+func (tree *btreeIndex[K, S, V]) removeRanks(left, right int64, fn func(score S, value V)) int64 {
+	if left > right {
+		return 0
+	}
+	l, rest := tree.splitNode(tree.root, left-1)
+	removed, r := tree.splitNode(rest, right-left+1)
+	if fn != nil {
+		tree.each(removed, 1, func(e *listpackEntry[S, V]) bool {
+			fn(e.score, e.value)
+			return true
+		})
+	}
+	tree.root = tree.join(l, r)
+	tree.size -= right - left + 1
+	return right - left + 1
+}
+
+func (tree *btreeIndex[K, S, V]) scan(fn func(score S, value V) bool) {
+	if tree.size == 0 {
+		return
+	}
+	tree.each(tree.root, 1, func(e *listpackEntry[S, V]) bool {
+		return fn(e.score, e.value)
+	})
+}
+
+// load
+// 自底向上构建: 先把元素平均分到叶子结点中, 再一层一层平均分到上一层的内部结点中
+// 一层超过一个结点时每个结点都在半满和全满之间, 复杂度 O(n)
+func (tree *btreeIndex[K, S, V]) load(entries []listpackEntry[S, V]) {
+	if len(entries) == 0 {
+		return
+	}
+	var nodes []*btreeNode[S, V]
+	btreeChunks(len(entries), func(from, to int) {
+		nodes = append(nodes, &btreeNode[S, V]{owner: tree.owner, items: append([]listpackEntry[S, V](nil), entries[from:to]...)})
+	})
+	for len(nodes) > 1 {
+		var parents []*btreeNode[S, V]
+		btreeChunks(len(nodes), func(from, to int) {
+			n := &btreeNode[S, V]{
+				owner:    tree.owner,
+				children: nodes[from:to:to],
+				counts:   make([]int64, to-from),
+				sums:     make([]float64, to-from),
+				groups:   make([]int64, to-from),
+			}
+			for i, child := range n.children {
+				n.counts[i] = btreeCount(child)
+				n.sums[i] = btreeSum(child)
+				if i > 0 {
+					n.keys = append(n.keys, *btreeFirst(child))
+				}
+			}
+			fixGroups(n, 0, len(n.children)-1)
+			parents = append(parents, n)
+		})
+		nodes = parents
+	}
+	tree.root = nodes[0]
+	tree.size = int64(len(entries))
+}
+
+// btreeChunks
+// 把 n 个元素平均分成 ceil(n / btreeMaxItems) 段, 按顺序返回每一段的范围 [from, to)
+// n > btreeMaxItems 时每一段都超过 btreeMaxItems / 2, 满足除了根结点至少半满的要求
+func btreeChunks(n int, fn func(from, to int)) {
+	k := (n + btreeMaxItems - 1) / btreeMaxItems
+	from := 0
+	for i := 0; i < k; i++ {
+		//前 n % k 段多一个
+		to := from + n/k
+		if i < n%k {
+			to++
+		}
+		fn(from, to)
+		from = to
+	}
+}
+
+// splitIndex
+// 沿着排名 rank 和 rank+1 之间的路径切开, 左边留在原来的树中, 右边组成新的树, 复杂度 O(树高 * 结点大小)
+func (tree *btreeIndex[K, S, V]) splitIndex(rank int64) orderedIndex[K, S, V] {
+	c := &btreeIndex[K, S, V]{root: &btreeNode[S, V]{}, owner: btreeOwnerID.Add(1), scoreOrder: tree.scoreOrder}
+	if rank >= tree.size {
+		return c
+	}
+	if rank <= 0 {
+		c.root, c.size = tree.root, tree.size
+		tree.root, tree.size = &btreeNode[S, V]{}, 0
+		return c
+	}
+	tree.root, c.root = tree.splitNode(tree.root, rank)
+	c.size = tree.size - rank
+	tree.size = rank
+	return c
+}
+
+// splitNode
+// 把子树切成前 rank 个元素和其余的元素两棵树, 返回两棵树的根结点, 除了根结点都至少半满
+// 每一层把切开的子结点左边的子树和下一层切出来的左边的树拼接, 右边同样, 拼接的代价是高度差, 加起来是 O(树高 * 结点大小)
+func (tree *btreeIndex[K, S, V]) splitNode(n *btreeNode[S, V], rank int64) (*btreeNode[S, V], *btreeNode[S, V]) {
+	n = tree.mutable(n)
+	if n.leaf() {
+		right := &btreeNode[S, V]{owner: tree.owner, items: append([]listpackEntry[S, V](nil), n.items[rank:]...)}
+		clear(n.items[rank:])
+		n.items = n.items[:rank]
+		return n, right
+	}
+	c := 0
+	for ; c < len(n.counts)-1 && rank >= n.counts[c]; c++ {
+		rank -= n.counts[c]
+	}
+	l, r := tree.splitNode(n.children[c], rank)
+	//右边: 子树 c 后面的子树, keys[c] 是 children[c+1] 的下界, 它成为第一个子树后不需要下界
+	right := &btreeNode[S, V]{
+		owner:    tree.owner,
+		children: append([]*btreeNode[S, V](nil), n.children[c+1:]...),
+		counts:   append([]int64(nil), n.counts[c+1:]...),
+		sums:     append([]float64(nil), n.sums[c+1:]...),
+		groups:   append([]int64(nil), n.groups[c+1:]...),
+		keys:     append([]listpackEntry[S, V](nil), n.keys[min(c+1, len(n.keys)):]...),
+	}
+	fixGroups(right, 0, 0)
+	//左边: 子树 c 前面的子树, 复用原来的结点
+	clear(n.children[c:])
+	clear(n.keys[max(c-1, 0):])
+	n.children, n.counts, n.sums, n.groups, n.keys = n.children[:c], n.counts[:c], n.sums[:c], n.groups[:c], n.keys[:max(c-1, 0)]
+	return tree.join(btreeShrink(n), l), tree.join(r, btreeShrink(right))
+}
+
+// concatIndex
+// 把 other 拼接到后面, 复杂度 O(两棵树的高度差 * 结点大小)
+func (tree *btreeIndex[K, S, V]) concatIndex(other orderedIndex[K, S, V]) bool {
+	o, ok := other.(*btreeIndex[K, S, V])
+	if !ok {
+		return false
+	}
+	tree.root = tree.join(tree.root, o.root)
+	tree.size += o.size
+	o.root, o.size = &btreeNode[S, V]{}, 0
+	return true
+}
+
+// join
+// 拼接两棵树, a 的元素都排在 b 的元素前面, 返回新的根结点
+// 矮的树挂到高的树的边上同一高度的位置, 不到半满时和相邻的子树合并或者平均分配, 结点满了再向上分裂
+func (tree *btreeIndex[K, S, V]) join(a, b *btreeNode[S, V]) *btreeNode[S, V] {
+	if btreeLen(a) == 0 {
+		return b
+	}
+	if btreeLen(b) == 0 {
+		return a
+	}
+	ha, hb := btreeHeight(a), btreeHeight(b)
+	switch {
+	case ha > hb:
+		a = tree.mutable(a)
+		if right, key := tree.appendSubtree(a, b, ha-hb); right != nil {
+			return newBTreeRoot(tree.owner, a, right, key)
+		}
+		return a
+	case ha < hb:
+		b = tree.mutable(b)
+		if right, key := tree.prependSubtree(b, a, hb-ha); right != nil {
+			return newBTreeRoot(tree.owner, b, right, key)
+		}
+		return b
+	}
+	root := newBTreeRoot(tree.owner, a, b, *btreeFirst(b))
+	if btreeLen(a) < btreeMinItems || btreeLen(b) < btreeMinItems {
+		tree.balance(root, 0)
+	}
+	return btreeShrink(root)
+}
+
+// appendSubtree
+// 把 sub 挂到 n 的最右边, depth 是 n 和 sub 的高度差, n 必须可以直接修改, 结点分裂时返回新的右结点和它的下界
+func (tree *btreeIndex[K, S, V]) appendSubtree(n, sub *btreeNode[S, V], depth int) (*btreeNode[S, V], listpackEntry[S, V]) {
+	c := len(n.children) - 1
+	if depth == 1 {
+		n.children = append(n.children, sub)
+		n.counts = append(n.counts, btreeCount(sub))
+		n.sums = append(n.sums, btreeSum(sub))
+		n.groups = append(n.groups, 0)
+		n.keys = append(n.keys, *btreeFirst(sub))
+		if btreeLen(sub) < btreeMinItems {
+			tree.balance(n, c)
+		} else {
+			fixGroups(n, c+1, c+1)
+		}
+	} else {
+		n.children[c] = tree.mutable(n.children[c])
+		right, key := tree.appendSubtree(n.children[c], sub, depth-1)
+		n.counts[c], n.sums[c] = btreeCount(n.children[c]), btreeSum(n.children[c])
+		if right != nil {
+			n.children = append(n.children, right)
+			n.counts = append(n.counts, btreeCount(right))
+			n.sums = append(n.sums, btreeSum(right))
+			n.groups = append(n.groups, 0)
+			n.keys = append(n.keys, key)
+		}
+		fixGroups(n, c, c+1)
+	}
+	if len(n.children) <= btreeMaxItems {
+		return nil, listpackEntry[S, V]{}
+	}
+	return cutBTreeNode(n, len(n.children)/2)
+}
+
+// prependSubtree
+// 把 sub 挂到 n 的最左边, depth 是 n 和 sub 的高度差, n 必须可以直接修改, 结点分裂时返回新的右结点和它的下界
+func (tree *btreeIndex[K, S, V]) prependSubtree(n, sub *btreeNode[S, V], depth int) (*btreeNode[S, V], listpackEntry[S, V]) {
+	if depth == 1 {
+		//原来的第一个子树的下界就是 n 的第一个元素
+		key := *btreeFirst(n)
+		n.children = insertAt(n.children, 0, sub)
+		n.counts = insertAt(n.counts, 0, btreeCount(sub))
+		n.sums = insertAt(n.sums, 0, btreeSum(sub))
+		n.groups = insertAt(n.groups, 0, 0)
+		n.keys = insertAt(n.keys, 0, key)
+		if btreeLen(sub) < btreeMinItems {
+			tree.balance(n, 0)
+		} else {
+			fixGroups(n, 0, 1)
+		}
+	} else {
+		n.children[0] = tree.mutable(n.children[0])
+		right, key := tree.prependSubtree(n.children[0], sub, depth-1)
+		n.counts[0], n.sums[0] = btreeCount(n.children[0]), btreeSum(n.children[0])
+		if right != nil {
+			n.children = insertAt(n.children, 1, right)
+			n.counts = insertAt(n.counts, 1, btreeCount(right))
+			n.sums = insertAt(n.sums, 1, btreeSum(right))
+			n.groups = insertAt(n.groups, 1, 0)
+			n.keys = insertAt(n.keys, 0, key)
+		}
+		fixGroups(n, 0, 2)
+	}
+	if len(n.children) <= btreeMaxItems {
+		return nil, listpackEntry[S, V]{}
+	}
+	return cutBTreeNode(n, len(n.children)/2)
+}
+
+// btreeHeight
+// 树的高度, 只有一个叶子结点时是1
+func btreeHeight[S cmp.Ordered, V any](n *btreeNode[S, V]) int {
+	h := 1
+	for ; !n.leaf(); n = n.children[0] {
+		h++
+	}
+	return h
+}
+
+// btreeLen
+// 结点的元素(叶子结点)或者子结点(内部结点)数量
+func btreeLen[S cmp.Ordered, V any](n *btreeNode[S, V]) int {
+	if n.leaf() {
+		return len(n.items)
+	}
+	return len(n.children)
+}
+
+func (tree *btreeIndex[K, S, V]) removeRangeByRank(left, right int64, fn func(score S, value V)) int64 {
+	left, right = tree.rankRange(left, right)
+	return tree.removeRanks(left, right, fn)
+}
+
+func (tree *btreeIndex[K, S, V]) removeRangeByScore(findRange *ScoreRange[S], fn func(score S, value V)) int64 {
+	if findRange == nil {
+		return 0
+	}
+	left, right := tree.scoreRange(findRange)
+	return tree.removeRanks(left, right, fn)
+}
+
+// removeRank
+// 在子树中删除排名是 rank 的元素, n 必须可以直接修改, 返回被删除元素的分数
+func (tree *btreeIndex[K, S, V]) removeRank(n *btreeNode[S, V], rank int64) float64 {
+	if n.leaf() {
+		i := int(rank - 1)
+		f := sumFloat(n.items[i].score)
+		n.items = removeAt(n.items, i)
+		return f
+	}
+	c := 0
+	for ; rank > n.counts[c]; c++ {
+		rank -= n.counts[c]
+	}
+	n.children[c] = tree.mutable(n.children[c])
+	f := tree.removeRank(n.children[c], rank)
+	n.counts[c]--
+	n.sums[c] -= f
+	if btreeLen(n.children[c]) < btreeMinItems && len(n.children) > 1 {
+		//子结点不到半满, 和相邻的结点合并或者从相邻的结点借一部分
+		tree.balance(n, max(c-1, 0))
+		return f
+	}
+	fixGroups(n, c, c+1)
+	return f
+}
+
+func (tree *btreeIndex[K, S, V]) updateValueScore(value V, oldScore, newScore S) {
+	if tree.remove(oldScore, value) {
+		tree.insert(newScore, value)
+	}
+}
+
+func (tree *btreeIndex[K, S, V]) valueRank(score S, value V) int64 {
+	return tree.findRank(score, value)
+}
+
+// values
+// 排名 [left,right] 内的元素, reverse 时从后向前
+func (tree *btreeIndex[K, S, V]) values(left, right int64, reverse bool) []V {
+	if left > right {
+		return nil
+	}
+	result := make([]V, right-left+1)
+	i := 0
+	tree.each(tree.root, left, func(e *listpackEntry[S, V]) bool {
+		if reverse {
+			result[len(result)-1-i] = e.value
+		} else {
+			result[i] = e.value
+		}
+		i++
+		return i < len(result)
+	})
+	return result
+}
+
+// rankRange
+// 排名范围出错时返回 left > right
+func (tree *btreeIndex[K, S, V]) rankRange(left, right int64) (int64, int64) {
+	if tree.size == 0 || left <= 0 || right <= 0 || right < left || left > tree.size {
+		return 1, 0
+	}
+	return left, min(right, tree.size)
+}
+
+// scoreRange
+// 分数范围内第一个和最后一个元素的排名
+func (tree *btreeIndex[K, S, V]) scoreRange(findRange *ScoreRange[S]) (int64, int64) {
+	left, _ := tree.countPrefix(func(e *listpackEntry[S, V]) bool {
+		return tree.beforeStart(e.score, findRange)
+	})
+	right, _ := tree.countPrefix(func(e *listpackEntry[S, V]) bool {
+		return !tree.afterEnd(e.score, findRange)
+	})
+	return left + 1, right
+}
+
+func (tree *btreeIndex[K, S, V]) AggregateByRank(left, right int64) ScoreAggregate {
+	left, right = tree.rankRange(left, right)
+	if left > right {
+		return ScoreAggregate{}
+	}
+	return ScoreAggregate{
+		Count: right - left + 1,
+		Sum:   tree.prefixByRank(right) - tree.prefixByRank(left-1),
+	}
+}
+
+func (tree *btreeIndex[K, S, V]) AggregateByScore(findRange *ScoreRange[S]) ScoreAggregate {
+	if findRange == nil {
+		return ScoreAggregate{}
+	}
+	beforeRank, beforeSum := tree.countPrefix(func(e *listpackEntry[S, V]) bool {
+		return tree.beforeStart(e.score, findRange)
+	})
+	endRank, endSum := tree.countPrefix(func(e *listpackEntry[S, V]) bool {
+		return !tree.afterEnd(e.score, findRange)
+	})
+	if endRank <= beforeRank {
+		return ScoreAggregate{}
+	}
+	return ScoreAggregate{
+		Count: endRank - beforeRank,
+		Sum:   endSum - beforeSum,
+	}
+}
+
+func (tree *btreeIndex[K, S, V]) GetValuesByRank(left, right int64) []V {
+	left, right = tree.rankRange(left, right)
+	return tree.values(left, right, false)
+}
+
+func (tree *btreeIndex[K, S, V]) GetRevValuesByRank(left, right int64) []V {
+	left, right = tree.rankRange(left, right)
+	//反向排名 [left,right] 对应正向排名 [size-right+1,size-left+1]
+	return tree.values(tree.size-right+1, tree.size-left+1, true)
+}
+
+func (tree *btreeIndex[K, S, V]) GetValuesByScore(findRange *ScoreRange[S]) []V {
+	if findRange == nil {
+		return nil
+	}
+	left, right := tree.scoreRange(findRange)
+	return tree.values(left, right, false)
+}
+
+func (tree *btreeIndex[K, S, V]) GetRevValuesByScore(findRange *ScoreRange[S]) []V {
+	if findRange == nil {
+		return nil
+	}
+	left, right := tree.scoreRange(findRange)
+	return tree.values(left, right, true)
+}
+
+// cloneIndex
+// 两棵树共享所有结点, 并且都换成新的编号, 之后谁修改谁复制路径上的结点, 复杂度 O(1)
+func (tree *btreeIndex[K, S, V]) cloneIndex() orderedIndex[K, S, V] {
+	tree.owner = btreeOwnerID.Add(1)
+	return &btreeIndex[K, S, V]{
+		root:       tree.root,
+		size:       tree.size,
+		owner:      btreeOwnerID.Add(1),
+		scoreOrder: tree.scoreOrder,
+	}
+}
+
+// mutable
+// 返回可以直接修改的结点: 结点属于这棵树时返回它本身, 否则复制一份, 调用者要用返回值替换原来的指针
+// 只复制这一个结点, 子结点仍然共享
+func (tree *btreeIndex[K, S, V]) mutable(n *btreeNode[S, V]) *btreeNode[S, V] {
+	if n.owner == tree.owner {
+		return n
+	}
+	return &btreeNode[S, V]{
+		owner:    tree.owner,
+		items:    slices.Clone(n.items),
+		children: slices.Clone(n.children),
+		counts:   slices.Clone(n.counts),
+		sums:     slices.Clone(n.sums),
+		groups:   slices.Clone(n.groups),
+		keys:     slices.Clone(n.keys),
+	}
+}
+
+// btreeSum
+// 子树的分数之和
+func btreeSum[S cmp.Ordered, V any](n *btreeNode[S, V]) float64 {
+	sum := float64(0)
+	if n.leaf() {
+		for i := range n.items {
+			sum += sumFloat(n.items[i].score)
+		}
+		return sum
+	}
+	for _, s := range n.sums {
+		sum += s
+	}
+	return sum
+}
+
+// btreeGroups
+// 子树中分数和前一个元素不同的元素数量, 子树的第一个元素不算
+func btreeGroups[S cmp.Ordered, V any](n *btreeNode[S, V]) int64 {
+	groups := int64(0)
+	if n.leaf() {
+		for i := 1; i < len(n.items); i++ {
+			if n.items[i].score != n.items[i-1].score {
+				groups++
+			}
+		}
+		return groups
+	}
+	for _, g := range n.groups {
+		groups += g
+	}
+	return groups
+}
+
+// fixGroups
+// 子树 from 到 to 修改后, 重新计算它们的 groups, 需要沿着边界向下找到相邻子树的第一个和最后一个元素, 复杂度 O(树高 + 结点大小)
+func fixGroups[S cmp.Ordered, V any](n *btreeNode[S, V], from, to int) {
+	for i := max(from, 0); i <= to && i < len(n.children); i++ {
+		g := btreeGroups(n.children[i])
+		if i > 0 && btreeLast(n.children[i-1]).score != btreeFirst(n.children[i]).score {
+			g++
+		}
+		n.groups[i] = g
+	}
+}
+
+// btreeFirst
+// 子树的第一个元素, 子树不能是空的
+func btreeFirst[S cmp.Ordered, V any](n *btreeNode[S, V]) *listpackEntry[S, V] {
+	for !n.leaf() {
+		n = n.children[0]
+	}
+	return &n.items[0]
+}
+
+// btreeLast
+// 子树的最后一个元素, 子树不能是空的
+func btreeLast[S cmp.Ordered, V any](n *btreeNode[S, V]) *listpackEntry[S, V] {
+	for !n.leaf() {
+		n = n.children[len(n.children)-1]
+	}
+	return &n.items[len(n.items)-1]
+}
+
+// btreeCount
+// 子树的元素数量
+func btreeCount[S cmp.Ordered, V any](n *btreeNode[S, V]) int64 {
+	if n.leaf() {
+		return int64(len(n.items))
+	}
+	count := int64(0)
+	for _, c := range n.counts {
+		count += c
+	}
+	return count
+}
+
+func insertAt[T any](s []T, i int, v T) []T {
+	var zero T
+	s = append(s, zero)
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
+
+func removeAt[T any](s []T, i int) []T {
+	copy(s[i:], s[i+1:])
+	var zero T
+	s[len(s)-1] = zero
+	return s[:len(s)-1]
+}
+
+// Validate
+// 检查B树的结构是否正确: 元素的顺序、子树的元素数量、分数之和和不同分数的数量、下界、所有叶子结点的深度相同、除了根结点都至少半满
+func (tree *btreeIndex[K, S, V]) Validate() error {
+	v := btreeValidator[K, S, V]{tree: tree, leafDepth: -1}
+	count, _, _, err := v.node(tree.root, 0)
+	if err != nil {
+		return err
+	}
+	if count != tree.size {
+		return fmt.Errorf("Validate btree size is %d, tree has %d items", tree.size, count)
+	}
+	return nil
+}
+
+// btreeValidator
+// 按顺序遍历B树时记录的状态
+type btreeValidator[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
+	tree *btreeIndex[K, S, V]
+	//上一个遍历到的元素
+	pre *listpackEntry[S, V]
+	//叶子结点的深度, -1 表示还没有遍历到叶子结点
+	leafDepth int
+	//遍历过的元素中分数和前一个元素不同的元素数量, 第一个元素不算
+	groups int64
+}
+
+// node
+// 检查子树, 返回子树的元素数量、分数之和、分数绝对值之和
+func (v *btreeValidator[K, S, V]) node(n *btreeNode[S, V], depth int) (int64, float64, float64, error) {
+	tree := v.tree
+	if n.leaf() {
+		if v.leafDepth >= 0 && depth != v.leafDepth {
+			return 0, 0, 0, fmt.Errorf("Validate btree leaf depth %d, want %d", depth, v.leafDepth)
+		}
+		v.leafDepth = depth
+		if depth > 0 && len(n.items) < btreeMinItems {
+			return 0, 0, 0, fmt.Errorf("Validate btree leaf at depth %d has %d items, less than %d", depth, len(n.items), btreeMinItems)
+		}
+		if len(n.items) > btreeMaxItems {
+			return 0, 0, 0, fmt.Errorf("Validate btree leaf has %d items, more than %d", len(n.items), btreeMaxItems)
+		}
+		sum, abs := float64(0), float64(0)
+		for i := range n.items {
+			e := &n.items[i]
+			if v.pre != nil && !tree.before(v.pre.score, v.pre.value, e.score, e.value) {
+				return 0, 0, 0, fmt.Errorf("Validate btree item %v is not after item %v", e.value.Key(), v.pre.value.Key())
+			}
+			if v.pre != nil && v.pre.score != e.score {
+				v.groups++
+			}
+			v.pre = e
+			f := sumFloat(e.score)
+			sum += f
+			abs += math.Abs(f)
+		}
+		return int64(len(n.items)), sum, abs, nil
+	}
+	if len(n.children) > btreeMaxItems {
+		return 0, 0, 0, fmt.Errorf("Validate btree node at depth %d has %d children, more than %d", depth, len(n.children), btreeMaxItems)
+	}
+	//根结点至少有两个子结点, 其余的结点至少半满
+	if depth == 0 && len(n.children) < 2 || depth > 0 && len(n.children) < btreeMinItems {
+		return 0, 0, 0, fmt.Errorf("Validate btree node at depth %d has only %d children", depth, len(n.children))
+	}
+	if len(n.counts) != len(n.children) || len(n.sums) != len(n.children) || len(n.groups) != len(n.children) || len(n.keys) != len(n.children)-1 {
+		return 0, 0, 0, fmt.Errorf("Validate btree node at depth %d has %d children, %d counts, %d sums, %d groups, %d keys", depth, len(n.children), len(n.counts), len(n.sums), len(n.groups), len(n.keys))
+	}
+	count, sum, abs := int64(0), float64(0), float64(0)
+	for i, child := range n.children {
+		//keys[i-1] 排在前面所有元素的后面, 不排在这个子树的任何元素的后面
+		var key *listpackEntry[S, V]
+		if i > 0 {
+			key = &n.keys[i-1]
+			if v.pre != nil && !tree.before(v.pre.score, v.pre.value, key.score, key.value) {
+				return 0, 0, 0, fmt.Errorf("Validate btree key %v at depth %d is not after item %v", key.value.Key(), depth, v.pre.value.Key())
+			}
+		}
+		pre, groups := v.pre, v.groups
+		c, s, a, err := v.node(child, depth+1)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		//第一个子树的第一个元素不和前面的元素比较
+		groups = v.groups - groups
+		if i == 0 && pre != nil && c > 0 && pre.score != btreeFirst(child).score {
+			groups--
+		}
+		if n.groups[i] != groups {
+			return 0, 0, 0, fmt.Errorf("Validate btree node at depth %d child %d groups is %d, want %d", depth, i, n.groups[i], groups)
+		}
+		if c == 0 {
+			return 0, 0, 0, fmt.Errorf("Validate btree node at depth %d has an empty child %d", depth, i)
+		}
+		if key != nil {
+			first := v.first(child)
+			if tree.before(first.score, first.value, key.score, key.value) {
+				return 0, 0, 0, fmt.Errorf("Validate btree item %v is before its lower bound %v", first.value.Key(), key.value.Key())
+			}
+		}
+		if n.counts[i] != c {
+			return 0, 0, 0, fmt.Errorf("Validate btree node at depth %d child %d count is %d, want %d", depth, i, n.counts[i], c)
+		}
+		if !sumNear(n.sums[i], s, a) {
+			return 0, 0, 0, fmt.Errorf("Validate btree node at depth %d child %d sum is %f, want %f", depth, i, n.sums[i], s)
+		}
+		count += c
+		sum += s
+		abs += a
+	}
+	return count, sum, abs, nil
+}
+
+// first
+// 子树的第一个元素, 子树不能是空的
+func (v *btreeValidator[K, S, V]) first(n *btreeNode[S, V]) *listpackEntry[S, V] {
+	for !n.leaf() {
+		n = n.children[0]
+	}
+	return &n.items[0]
+}
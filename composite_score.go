@@ -0,0 +1,263 @@
+package skiptablev2
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ScoreOrder
+// 复合分数中字段的排序方向
+type ScoreOrder int
+
+const (
+	//SCORE_ORDER_ASC 从小到大
+	SCORE_ORDER_ASC ScoreOrder = iota
+	//SCORE_ORDER_DESC 从大到小
+	SCORE_ORDER_DESC
+)
+
+// 复合分数中每个字段的类型标记, 不同类型的字段按标记排序
+// 标记不能是 0x00 和 0xFF, 反转之后也不能是 0xFF, 前缀查询时用 0xFF 作为上界
+const (
+	compositeTagInt    byte = 0x02
+	compositeTagUint   byte = 0x03
+	compositeTagFloat  byte = 0x04
+	compositeTagString byte = 0x05
+)
+
+// CompositeScore
+// 多字段复合分数的编码规则, 比如 "积分从高到低, 积分相同时完成时间从早到晚, 最后按玩家id"
+// 把多个字段编码成一个保序的 string, 直接作为 ScoredSkipList/ScoredSortSet 的分数使用,
+// string 的大小关系和按字段依次比较(考虑每个字段的方向)的结果完全相同, 所以排序不再依赖 compare 函数,
+// 按分数范围查找时也能精确到每一个字段
+// 支持的字段类型: int/int8/int16/int32/int64, uint/uint8/uint16/uint32/uint64, float32/float64, string
+type CompositeScore struct {
+	orders []ScoreOrder
+}
+
+// NewCompositeScore
+// 初始化一个复合分数的编码规则, 每个参数是对应字段的排序方向
+func NewCompositeScore(orders ...ScoreOrder) *CompositeScore {
+	return &CompositeScore{
+		orders: orders,
+	}
+}
+
+// Encode
+// 把所有字段编码成一个分数, 字段的数量必须和编码规则相同
+func (c *CompositeScore) Encode(fields ...any) (string, error) {
+	if len(fields) != len(c.orders) {
+		return "", fmt.Errorf("CompositeScore Encode need %d fields, got %d", len(c.orders), len(fields))
+	}
+	return c.encode(fields)
+}
+
+// MustEncode
+// 和 Encode 相同, 出错时直接panic
+func (c *CompositeScore) MustEncode(fields ...any) string {
+	score, err := c.Encode(fields...)
+	if err != nil {
+		panic(err)
+	}
+	return score
+}
+
+// Prefix
+// 前n个字段等于 prefix 的所有分数的范围
+func (c *CompositeScore) Prefix(prefix ...any) (*ScoreRange[string], error) {
+	return c.PrefixBetween(prefix, prefix)
+}
+
+// PrefixBetween
+// 前n个字段在 [from, to] 之间的所有分数的范围, from 和 to 的字段数量必须相同
+// from 和 to 是按排序方向的顺序, 比如字段是从大到小排序的, from 就是较大的那个值
+func (c *CompositeScore) PrefixBetween(from, to []any) (*ScoreRange[string], error) {
+	if len(from) != len(to) || len(from) > len(c.orders) {
+		return nil, fmt.Errorf("CompositeScore PrefixBetween invalid prefix length %d %d", len(from), len(to))
+	}
+	min, err := c.encode(from)
+	if err != nil {
+		return nil, err
+	}
+	max, err := c.encode(to)
+	if err != nil {
+		return nil, err
+	}
+	//后面的字段以类型标记开头, 一定小于 0xFF, 所以 max+0xFF 比所有以 max 为前缀的分数都大
+	return &ScoreRange[string]{
+		Min: min,
+		Max: max + "\xff",
+	}, nil
+}
+
+// Decode
+// 把分数解码成各个字段, 整数解码成 int64/uint64, 浮点数解码成 float64
+func (c *CompositeScore) Decode(score string) ([]any, error) {
+	buf := []byte(score)
+	fields := make([]any, 0, len(c.orders))
+	for i, order := range c.orders {
+		if len(buf) == 0 {
+			return nil, fmt.Errorf("CompositeScore Decode missing field %d", i)
+		}
+		field, n, err := decodeCompositeField(buf, order)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+		buf = buf[n:]
+	}
+	if len(buf) != 0 {
+		return nil, errors.New("CompositeScore Decode too many bytes")
+	}
+	return fields, nil
+}
+
+func (c *CompositeScore) encode(fields []any) (string, error) {
+	buf := make([]byte, 0, len(fields)*9)
+	for i, field := range fields {
+		start := len(buf)
+		var err error
+		buf, err = appendCompositeField(buf, field)
+		if err != nil {
+			return "", fmt.Errorf("CompositeScore field %d: %w", i, err)
+		}
+		//从大到小的字段, 把编码的每个字节取反
+		if c.orders[i] == SCORE_ORDER_DESC {
+			for j := start; j < len(buf); j++ {
+				buf[j] = ^buf[j]
+			}
+		}
+	}
+	return string(buf), nil
+}
+
+// 编码一个字段, 每种类型的编码都不会是另一个编码的前缀
+func appendCompositeField(buf []byte, field any) ([]byte, error) {
+	switch v := field.(type) {
+	case int:
+		return appendCompositeInt(buf, int64(v)), nil
+	case int8:
+		return appendCompositeInt(buf, int64(v)), nil
+	case int16:
+		return appendCompositeInt(buf, int64(v)), nil
+	case int32:
+		return appendCompositeInt(buf, int64(v)), nil
+	case int64:
+		return appendCompositeInt(buf, v), nil
+	case uint:
+		return appendCompositeUint(buf, uint64(v)), nil
+	case uint8:
+		return appendCompositeUint(buf, uint64(v)), nil
+	case uint16:
+		return appendCompositeUint(buf, uint64(v)), nil
+	case uint32:
+		return appendCompositeUint(buf, uint64(v)), nil
+	case uint64:
+		return appendCompositeUint(buf, v), nil
+	case float32:
+		return appendCompositeFloat(buf, float64(v))
+	case float64:
+		return appendCompositeFloat(buf, v)
+	case string:
+		buf = append(buf, compositeTagString)
+		//字符串中的 0x00 转义成 0x00 0xFF, 结尾是 0x00 0x01
+		for i := 0; i < len(v); i++ {
+			if v[i] == 0x00 {
+				buf = append(buf, 0x00, 0xFF)
+			} else {
+				buf = append(buf, v[i])
+			}
+		}
+		return append(buf, 0x00, 0x01), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", field)
+	}
+}
+
+// 有符号整数: 符号位取反, 负数就排在正数前面了
+func appendCompositeInt(buf []byte, v int64) []byte {
+	buf = append(buf, compositeTagInt)
+	return binary.BigEndian.AppendUint64(buf, uint64(v)^(1<<63))
+}
+
+func appendCompositeUint(buf []byte, v uint64) []byte {
+	buf = append(buf, compositeTagUint)
+	return binary.BigEndian.AppendUint64(buf, v)
+}
+
+// 浮点数: 正数符号位取反, 负数所有位取反, 编码后按字节比较的结果和按数值比较相同
+func appendCompositeFloat(buf []byte, v float64) ([]byte, error) {
+	if math.IsNaN(v) {
+		return nil, errors.New("NaN can not be used in score")
+	}
+	if v == 0 {
+		//-0 和 0 相等
+		v = 0
+	}
+	bits := math.Float64bits(v)
+	if bits&(1<<63) == 0 {
+		bits ^= 1 << 63
+	} else {
+		bits = ^bits
+	}
+	buf = append(buf, compositeTagFloat)
+	return binary.BigEndian.AppendUint64(buf, bits), nil
+}
+
+// 解码一个字段, 返回字段和使用的字节数
+func decodeCompositeField(buf []byte, order ScoreOrder) (any, int, error) {
+	get := func(i int) byte {
+		if order == SCORE_ORDER_DESC {
+			return ^buf[i]
+		}
+		return buf[i]
+	}
+	fixed := func() (uint64, error) {
+		if len(buf) < 9 {
+			return 0, errors.New("CompositeScore Decode truncated field")
+		}
+		var v uint64
+		for i := 1; i < 9; i++ {
+			v = v<<8 | uint64(get(i))
+		}
+		return v, nil
+	}
+	switch get(0) {
+	case compositeTagInt:
+		v, err := fixed()
+		return int64(v ^ (1 << 63)), 9, err
+	case compositeTagUint:
+		v, err := fixed()
+		return v, 9, err
+	case compositeTagFloat:
+		v, err := fixed()
+		if v&(1<<63) != 0 {
+			v ^= 1 << 63
+		} else {
+			v = ^v
+		}
+		return math.Float64frombits(v), 9, err
+	case compositeTagString:
+		s := make([]byte, 0, len(buf))
+		for i := 1; i+1 < len(buf); i++ {
+			if get(i) != 0x00 {
+				s = append(s, get(i))
+				continue
+			}
+			switch get(i + 1) {
+			case 0x01:
+				return string(s), i + 2, nil
+			case 0xFF:
+				s = append(s, 0x00)
+				i++
+			default:
+				return nil, 0, errors.New("CompositeScore Decode invalid string escape")
+			}
+		}
+		return nil, 0, errors.New("CompositeScore Decode unterminated string")
+	default:
+		return nil, 0, fmt.Errorf("CompositeScore Decode unknown tag 0x%x", get(0))
+	}
+}
@@ -0,0 +1,166 @@
+package skiptablev2
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type PlayerItem struct {
+	id     string
+	points int64
+	finish float64
+	score  string
+}
+
+func (p *PlayerItem) Key() string {
+	return p.id
+}
+
+func (p *PlayerItem) Score() string {
+	return p.score
+}
+
+// 积分从高到低, 完成时间从早到晚, 最后按玩家id
+var leaderboardScore = NewCompositeScore(SCORE_ORDER_DESC, SCORE_ORDER_ASC, SCORE_ORDER_ASC)
+
+func newPlayer(id string, points int64, finish float64) *PlayerItem {
+	return &PlayerItem{
+		id:     id,
+		points: points,
+		finish: finish,
+		score:  leaderboardScore.MustEncode(points, finish, id),
+	}
+}
+
+func lessPlayer(p1, p2 *PlayerItem) bool {
+	if p1.points != p2.points {
+		return p1.points > p2.points
+	}
+	if p1.finish != p2.finish {
+		return p1.finish < p2.finish
+	}
+	return p1.id < p2.id
+}
+
+func TestCompositeScore_Order(t *testing.T) {
+	players := make([]*PlayerItem, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		//少量的取值, 保证有很多相同的字段
+		points := int64(rand.Intn(20) - 10)
+		finish := float64(rand.Intn(10)-5) / 2
+		id := strconv.Itoa(rand.Intn(100)) + strings.Repeat("\x00", rand.Intn(2))
+		players = append(players, newPlayer(id, points, finish))
+	}
+	for i := range players {
+		for j := range players {
+			p1, p2 := players[i], players[j]
+			if lessPlayer(p1, p2) != (p1.score < p2.score) {
+				t.Fatalf("order error %+v %+v", p1, p2)
+			}
+		}
+	}
+
+	fields, err := leaderboardScore.Decode(players[0].score)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields[0] != players[0].points || fields[1] != players[0].finish || fields[2] != players[0].id {
+		t.Fatalf("decode fields:%v player:%+v", fields, players[0])
+	}
+
+	//各种类型
+	all := NewCompositeScore(SCORE_ORDER_ASC, SCORE_ORDER_DESC, SCORE_ORDER_ASC, SCORE_ORDER_DESC)
+	values := []float64{math.Inf(-1), -1e300, -1, -0.5, 0, 0.5, 1, 1e300, math.Inf(1)}
+	for i := 1; i < len(values); i++ {
+		s1 := all.MustEncode(values[i-1], values[i-1], uint64(i), int32(i))
+		s2 := all.MustEncode(values[i], values[i], uint64(i), int32(i))
+		if s1 >= s2 {
+			t.Fatalf("float order error %f %f", values[i-1], values[i])
+		}
+		decoded, err := all.Decode(s2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded[0] != values[i] || decoded[1] != values[i] || decoded[2] != uint64(i) || decoded[3] != int64(i) {
+			t.Fatalf("decode fields:%v", decoded)
+		}
+	}
+	if _, err = all.Encode(math.NaN(), 1.0, uint64(1), 1); err == nil {
+		t.Fatal("NaN should not be encoded")
+	}
+	if _, err = all.Encode(1.0); err == nil {
+		t.Fatal("wrong field count should fail")
+	}
+}
+
+func TestCompositeScore_SortSet(t *testing.T) {
+	sortSet, err := NewScoredSortSet[string, string, *PlayerItem](SortSetConfig{
+		MaxLevel:           SKIP_TABLE_DEFAULT_MAX_LEVEL,
+		ListpackMaxEntries: 0,
+	}, func(v1, v2 *PlayerItem) int {
+		return strings.Compare(v1.id, v2.id)
+	})
+	if err != nil {
+		panic(err)
+	}
+	players := make([]*PlayerItem, 0, N*10)
+	for i := 0; i < N*10; i++ {
+		player := newPlayer(strconv.Itoa(i), int64(rand.Intn(10)), float64(rand.Intn(100)))
+		players = append(players, player)
+		sortSet.Add(player)
+	}
+	sort.Slice(players, func(i, j int) bool {
+		return lessPlayer(players[i], players[j])
+	})
+	for i, r := range sortSet.Range(0, -1) {
+		if r != players[i] {
+			t.Fatalf("player:%+v rank:%d want:%+v", r, i, players[i])
+		}
+	}
+
+	//只按第一个字段查找: 积分是5的所有玩家
+	findRange, err := leaderboardScore.Prefix(int64(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []*PlayerItem
+	for _, player := range players {
+		if player.points == 5 {
+			want = append(want, player)
+		}
+	}
+	result := sortSet.RangeByScore(findRange)
+	if len(result) != len(want) {
+		t.Fatalf("prefix result len:%d want:%d", len(result), len(want))
+	}
+	for i := range result {
+		if result[i] != want[i] {
+			t.Fatalf("prefix result:%+v want:%+v", result[i], want[i])
+		}
+	}
+
+	//前两个字段的范围: 积分从8到6, 积分是6时完成时间不超过50
+	findRange, err = leaderboardScore.PrefixBetween([]any{int64(8), float64(0)}, []any{int64(6), float64(50)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = want[:0]
+	for _, player := range players {
+		if player.points <= 8 && player.points > 6 || player.points == 6 && player.finish <= 50 {
+			want = append(want, player)
+		}
+	}
+	result = sortSet.RangeByScore(findRange)
+	if len(result) != len(want) {
+		t.Fatalf("prefix between result len:%d want:%d", len(result), len(want))
+	}
+	for i := range result {
+		if result[i] != want[i] {
+			t.Fatalf("prefix between result:%+v want:%+v", result[i], want[i])
+		}
+	}
+}
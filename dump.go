@@ -0,0 +1,293 @@
+package skiptablev2
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	//SKIP_TABLE_DUMP_NODES
+	//DumpDOT、DumpText 默认显示开头和结尾各多少个结点
+	SKIP_TABLE_DUMP_NODES = 8
+)
+
+// DumpOptions
+// 输出跳表结构时的截断规则, 跳表很大时只显示一部分
+type DumpOptions struct {
+	//显示开头和结尾各多少个结点, 中间的结点合并显示, 0 表示显示所有结点
+	Nodes int
+	//显示最高的多少层, 第0层总是显示, 0 表示显示所有层
+	Levels int
+}
+
+// dumpLayout
+// 要显示的结点和层
+type dumpLayout[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
+	//要显示的结点, hidden > 0 时前 gapAt 个是开头的结点, 后面的是结尾的结点
+	nodes []*ScoredSkipListNode[K, S, V]
+	//结点所在的列(从1开始, 0 是头结点), 不在这里的结点是被省略的结点
+	column map[*ScoredSkipListNode[K, S, V]]int
+	//被省略的结点数量和它们所在的列
+	hidden int64
+	gapAt  int
+	//要显示的层, 从高到低
+	levels []int
+}
+
+// layout
+// 按第0层的顺序选出要显示的结点, 最多走 size 步, 结构被破坏(比如有环)时也能结束
+func (list *ScoredSkipList[K, S, V]) layout(opts DumpOptions) *dumpLayout[K, S, V] {
+	l := &dumpLayout[K, S, V]{column: make(map[*ScoredSkipListNode[K, S, V]]int)}
+	n := opts.Nodes
+	if n <= 0 || int64(2*n) >= list.size {
+		n = int(list.size)
+	}
+	var tailNodes []*ScoredSkipListNode[K, S, V]
+	rank := int64(0)
+	for t := list.head.Next(0); t != nil && rank < list.size; t = t.Next(0) {
+		rank++
+		switch {
+		case rank <= int64(n):
+			l.nodes = append(l.nodes, t)
+		case rank > list.size-int64(n):
+			tailNodes = append(tailNodes, t)
+		default:
+			l.hidden++
+		}
+	}
+	l.gapAt = len(l.nodes)
+	l.nodes = append(l.nodes, tailNodes...)
+	for i, t := range l.nodes {
+		col := i + 1
+		if l.hidden > 0 && i >= l.gapAt {
+			col++
+		}
+		l.column[t] = col
+	}
+
+	levels := list.level
+	if opts.Levels > 0 && opts.Levels < levels {
+		levels = opts.Levels
+	}
+	for i := list.level - 1; i >= list.level-levels; i-- {
+		l.levels = append(l.levels, i)
+	}
+	if l.levels[len(l.levels)-1] != 0 {
+		l.levels = append(l.levels, 0)
+	}
+	return l
+}
+
+// DumpText
+// 使用默认的截断规则输出跳表结构的文本图
+func (list *ScoredSkipList[K, S, V]) DumpText(w io.Writer) error {
+	return list.DumpTextWithOptions(w, DumpOptions{Nodes: SKIP_TABLE_DUMP_NODES})
+}
+
+// DumpTextWithOptions
+// 输出跳表结构的文本图, 每一列是一个结点, 每一行是一层
+// 结点有这一层时显示这一层的 span, 结点之间用 - 连接, 最后一行是每个结点后指针指向的结点
+// 被省略的结点显示成一列 ...
+func (list *ScoredSkipList[K, S, V]) DumpTextWithOptions(w io.Writer, opts DumpOptions) error {
+	l := list.layout(opts)
+	columns := len(l.nodes) + 1
+	if l.hidden > 0 {
+		columns++
+	}
+	//每一列的表头(key 和 分数)
+	keys := make([]string, columns)
+	scores := make([]string, columns)
+	back := make([]string, columns)
+	keys[0], scores[0], back[0] = "head", "", ""
+	for _, t := range l.nodes {
+		c := l.column[t]
+		keys[c] = fmt.Sprint(t.value.Key())
+		scores[c] = fmt.Sprint(t.score)
+		back[c] = list.dumpName(t.backward, l)
+	}
+	if l.hidden > 0 {
+		keys[l.gapAt+1] = "..."
+		scores[l.gapAt+1] = "(" + strconv.FormatInt(l.hidden, 10) + ")"
+		back[l.gapAt+1] = "..."
+	}
+
+	//每一层每一列显示的 span, 结点没有这一层时是空的
+	rows := make([][]string, len(l.levels))
+	for r, i := range l.levels {
+		rows[r] = make([]string, columns)
+		steps := int64(0)
+		for t := list.head; t != nil && steps <= list.size; t = t.Next(i) {
+			steps++
+			c, ok := l.column[t]
+			if t == list.head {
+				c, ok = 0, true
+			}
+			if ok && i < len(t.level) {
+				rows[r][c] = "[" + strconv.FormatInt(t.Span(i), 10) + "]"
+			}
+		}
+	}
+
+	width := make([]int, columns)
+	for c := range width {
+		width[c] = max(len(keys[c]), len(scores[c]), len(back[c]))
+		for r := range rows {
+			width[c] = max(width[c], len(rows[r][c]))
+		}
+		width[c] += 2
+	}
+	label := max(len("score"), len("L"+strconv.Itoa(list.level-1)))
+
+	bw := bufio.NewWriter(w)
+	line := func(name string, cells []string, fill byte, end string) {
+		var b strings.Builder
+		b.WriteString(name)
+		b.WriteString(strings.Repeat(" ", label-len(name)+1))
+		for c, cell := range cells {
+			pad := width[c] - len(cell)
+			if c == 0 && fill == '-' {
+				//头结点左边不画线
+				b.WriteString(strings.Repeat(" ", pad/2))
+			} else {
+				b.WriteString(strings.Repeat(string(fill), pad/2))
+			}
+			b.WriteString(cell)
+			b.WriteString(strings.Repeat(string(fill), pad-pad/2))
+		}
+		b.WriteString(end)
+		bw.WriteString(strings.TrimRight(b.String(), " "))
+		bw.WriteString("\n")
+	}
+	fmt.Fprintf(bw, "size=%d level=%d maxLevel=%d tail=%s\n", list.size, list.level, list.maxLevel, list.dumpName(list.tail, l))
+	line("key", keys, ' ', "")
+	line("score", scores, ' ', "")
+	for r, i := range l.levels {
+		if r > 0 && l.levels[r-1] != i+1 {
+			fmt.Fprintf(bw, "%s (%d levels hidden)\n", strings.Repeat(" ", label), l.levels[r-1]-i-1)
+		}
+		line("L"+strconv.Itoa(i), rows[r], '-', ">nil")
+	}
+	line("back", back, ' ', "")
+	return bw.Flush()
+}
+
+// dumpName
+// 结点在图中的名字
+func (list *ScoredSkipList[K, S, V]) dumpName(t *ScoredSkipListNode[K, S, V], l *dumpLayout[K, S, V]) string {
+	switch {
+	case t == nil:
+		return "nil"
+	case t == list.head:
+		return "head"
+	}
+	if _, ok := l.column[t]; !ok {
+		return "..."
+	}
+	return fmt.Sprint(t.value.Key())
+}
+
+// DumpDOT
+// 使用默认的截断规则输出 Graphviz 的 dot 格式的跳表结构
+func (list *ScoredSkipList[K, S, V]) DumpDOT(w io.Writer) error {
+	return list.DumpDOTWithOptions(w, DumpOptions{Nodes: SKIP_TABLE_DUMP_NODES})
+}
+
+// DumpDOTWithOptions
+// 输出 Graphviz 的 dot 格式的跳表结构, 可以用 `dot -Tsvg` 画图
+// 每个结点每一层是一个格子, forward 指针是实线, 线上的数字是 span; 后指针是虚线; 被省略的结点合并成一个结点
+func (list *ScoredSkipList[K, S, V]) DumpDOTWithOptions(w io.Writer, opts DumpOptions) error {
+	l := list.layout(opts)
+	id := func(t *ScoredSkipListNode[K, S, V]) string {
+		switch {
+		case t == nil:
+			return "nil"
+		case t == list.head:
+			return "head"
+		}
+		if c, ok := l.column[t]; ok {
+			return "n" + strconv.Itoa(c)
+		}
+		return "gap"
+	}
+	//结点的各层格子, 从高到低
+	ports := func(t *ScoredSkipListNode[K, S, V], title string) string {
+		var b strings.Builder
+		b.WriteString("{")
+		for _, i := range l.levels {
+			b.WriteString("<l" + strconv.Itoa(i) + ">")
+			if i < len(t.level) {
+				b.WriteString("L" + strconv.Itoa(i))
+			}
+			b.WriteString("|")
+		}
+		b.WriteString(title)
+		b.WriteString("}")
+		return b.String()
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph skiplist {\n")
+	bw.WriteString("\trankdir=LR;\n\tnode [shape=record];\n")
+	fmt.Fprintf(bw, "\thead [label=\"%s\"];\n", ports(list.head, "head"))
+	for _, t := range l.nodes {
+		title := dotEscape(fmt.Sprint(t.value.Key())) + "\\n" + dotEscape(fmt.Sprint(t.score))
+		fmt.Fprintf(bw, "\t%s [label=\"%s\"];\n", id(t), ports(t, title))
+	}
+	if l.hidden > 0 {
+		fmt.Fprintf(bw, "\tgap [shape=box, style=dashed, label=\"... %d nodes ...\"];\n", l.hidden)
+	}
+	bw.WriteString("\tnil [shape=plaintext];\n")
+
+	for _, i := range l.levels {
+		port := ":l" + strconv.Itoa(i)
+		//被省略的结点之间的指针不画, 从被省略的结点出来的指针每层只画一次
+		drawn := make(map[string]bool)
+		steps := int64(0)
+		for t := list.head; t != nil && i < len(t.level) && steps <= list.size; t = t.Next(i) {
+			steps++
+			from, to := id(t), id(t.Next(i))
+			if from == "gap" && (to == "gap" || drawn[to]) {
+				continue
+			}
+			src, dst := from, to
+			if from != "gap" {
+				src += port
+			}
+			if to != "gap" && to != "nil" {
+				dst += port
+			}
+			if from == "gap" {
+				drawn[to] = true
+				fmt.Fprintf(bw, "\t%s -> %s;\n", src, dst)
+				continue
+			}
+			fmt.Fprintf(bw, "\t%s -> %s [label=\"%d\"];\n", src, dst, t.Span(i))
+		}
+	}
+	for _, t := range l.nodes {
+		if t.backward != nil {
+			fmt.Fprintf(bw, "\t%s -> %s [style=dashed, constraint=false];\n", id(t), id(t.backward))
+		}
+	}
+	bw.WriteString("\ttail [shape=plaintext];\n")
+	fmt.Fprintf(bw, "\ttail -> %s [style=dotted];\n", id(list.tail))
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// dotEscape
+// 转义 dot record 标签中的特殊字符
+func dotEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`{}|<>"\ `, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
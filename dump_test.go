@@ -0,0 +1,87 @@
+package skiptablev2
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newDumpTestList(t *testing.T, n int) *SkipList[string, *S1[string]] {
+	st, err := NewDefaultSkipTable[string, *S1[string]](func(v1, v2 *S1[string]) int {
+		return strings.Compare(v1.key, v2.key)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		st.InsertByScore(float64(i%10), &S1[string]{key: "k" + strconv.Itoa(i), f: float64(i % 10)})
+	}
+	return st
+}
+
+func TestSkipList_DumpText(t *testing.T) {
+	st := newDumpTestList(t, 12)
+	var buf bytes.Buffer
+	if err := st.DumpText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for i := 0; i < 12; i++ {
+		if !strings.Contains(out, "k"+strconv.Itoa(i)) {
+			t.Fatalf("missing node k%d:\n%s", i, out)
+		}
+	}
+	//表头、分数、每一层、后指针
+	if lines := strings.Count(out, "\n"); lines != st.level+4 || strings.Count(out, ">nil") != st.level {
+		t.Fatalf("lines:%d level:%d\n%s", lines, st.level, out)
+	}
+
+	//结点很多时只显示开头和结尾
+	st = newDumpTestList(t, 1000)
+	buf.Reset()
+	if err := st.DumpTextWithOptions(&buf, DumpOptions{Nodes: 4, Levels: 2}); err != nil {
+		t.Fatal(err)
+	}
+	out = buf.String()
+	if !strings.Contains(out, "(992)") || !strings.Contains(out, "levels hidden") {
+		t.Fatalf("truncated dump:\n%s", out)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) > 120 {
+			t.Fatalf("line too long:%s", line)
+		}
+	}
+}
+
+func TestSkipList_DumpDOT(t *testing.T) {
+	st := newDumpTestList(t, 100)
+	var buf bytes.Buffer
+	if err := st.DumpDOTWithOptions(&buf, DumpOptions{Nodes: 5}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "digraph skiplist {") || !strings.HasSuffix(out, "}\n") {
+		t.Fatalf("dot:\n%s", out)
+	}
+	if !strings.Contains(out, "... 90 nodes ...") || !strings.Contains(out, "tail -> n11") {
+		t.Fatalf("dot:\n%s", out)
+	}
+	//第0层: head 和开头5个结点各1条, 省略的结点出来1条, 结尾5个结点各1条
+	if n := strings.Count(out, ":l0 ->") + strings.Count(out, "gap -> n7:l0"); n != 12 {
+		t.Fatalf("level 0 edges:%d\n%s", n, out)
+	}
+}
+
+// 结构被破坏(第0层有环)时也能输出
+func TestSkipList_DumpCorrupt(t *testing.T) {
+	st := newDumpTestList(t, 20)
+	st.tail.setNext(0, st.head.Next(0))
+	var buf bytes.Buffer
+	if err := st.DumpText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.DumpDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,237 @@
+package skiptablev2
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// sortSetHarness
+// 把一串字节解释成有序集合的操作序列, 同时在集合和 sortSetModel 上执行, 每一步之后检查结构和结果
+// 每个操作占3个字节: 操作类型、两个参数, key 和分数的取值都很少, 用来产生大量相同的分数和重复的key
+type sortSetHarness struct {
+	t     *testing.T
+	set   *SortSet[string, *StItem[string]]
+	model *sortSetModel
+	//快照和创建快照时 model 的内容, 之后的修改不能影响快照
+	snap     *SortSetSnapshot[string, *StItem[string]]
+	snapWant []*StItem[string]
+}
+
+func newSortSetHarness(t *testing.T, config SortSetConfig) *sortSetHarness {
+	set, err := NewSortSetWithConfig[string, *StItem[string]](config, compareStItemKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &sortSetHarness{
+		t:     t,
+		set:   set,
+		model: &sortSetModel{desc: config.Order == SCORE_ORDER_DESC},
+	}
+}
+
+func (h *sortSetHarness) run(ops []byte) {
+	for len(ops) >= 3 {
+		h.step(ops[0], ops[1], ops[2])
+		ops = ops[3:]
+		h.check()
+	}
+}
+
+func (h *sortSetHarness) step(op, a, b byte) {
+	t, set, model := h.t, h.set, h.model
+	key := strconv.Itoa(int(a % 64))
+	score := float64(int(b%16) - 4)
+	switch op % 10 {
+	case 0, 1, 2:
+		item := &StItem[string]{k: key, f: score}
+		set.Add(item)
+		model.add(item)
+	case 3:
+		if j := model.find(key); j >= 0 {
+			model.items = slices.Delete(model.items, j, j+1)
+		}
+		set.Remove(key)
+	case 4:
+		l, r := int64(a%32)-16, int64(b%32)-16
+		want := slices.Clone(rangeByIndex(model.items, l, r, false))
+		if n := set.RemoveRangeByRank(l, r); n != len(want) {
+			t.Fatalf("RemoveRangeByRank(%d,%d) %d != %d", l, r, n, len(want))
+		}
+		for _, item := range want {
+			j := model.find(item.k)
+			model.items = slices.Delete(model.items, j, j+1)
+		}
+	case 5:
+		min := float64(int(a%16) - 4)
+		findRange := &SkipListFindRange{Min: min, Max: score}
+		want := model.rangeByScore(findRange)
+		if n := set.RemoveRangeByScore(min, score); n != len(want) {
+			t.Fatalf("RemoveRangeByScore(%f,%f) %d != %d", min, score, n, len(want))
+		}
+		model.items = slices.DeleteFunc(model.items, func(item *StItem[string]) bool {
+			return model.inRange(item.f, findRange)
+		})
+	case 6:
+		l, r := int64(a%32)-16, int64(b%32)-16
+		equalKeys(t, "Range", set.Range(l, r), rangeByIndex(model.items, l, r, false))
+		equalKeys(t, "RevRange", set.RevRange(l, r), rangeByIndex(reversed(model.items), l, r, true))
+	case 7:
+		findRange := &SkipListFindRange{Min: float64(int(a%16) - 4), Max: score, MinInf: a >= 128, MaxInf: b >= 128}
+		want := model.rangeByScore(findRange)
+		equalKeys(t, "RangeByScore", set.RangeByScore(findRange), want)
+		var sum ScoreAggregate
+		for _, item := range want {
+			sum.Count++
+			sum.Sum += item.f
+		}
+		if got := set.AggregateByScore(findRange); got != sum {
+			t.Fatalf("AggregateByScore(%v) %v != %v", findRange, got, sum)
+		}
+	case 8:
+		want := int64(model.find(key))
+		if rank := set.Rank(key); want >= 0 && rank != want {
+			t.Fatalf("Rank(%s) %d != %d", key, rank, want)
+		}
+		if want >= 0 && set.Score(key) != model.items[want].f {
+			t.Fatalf("Score(%s) %f != %f", key, set.Score(key), model.items[want].f)
+		}
+	case 9:
+		h.snap = set.Snapshot()
+		h.snapWant = slices.Clone(model.items)
+	}
+}
+
+// check
+// 每一步之后检查结构、数量和所有元素的顺序
+func (h *sortSetHarness) check() {
+	t := h.t
+	t.Helper()
+	if err := h.set.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if h.set.Count() != int64(len(h.model.items)) {
+		t.Fatalf("count %d != %d", h.set.Count(), len(h.model.items))
+	}
+	equalKeys(t, "Range", h.set.Range(0, -1), h.model.items)
+	if h.snap != nil {
+		equalKeys(t, "Snapshot", h.snap.Range(0, -1), h.snapWant)
+	}
+}
+
+// 随机的操作序列, 所有编码和有序索引都要跑
+func TestSortSet_Differential(t *testing.T) {
+	for _, c := range sortSetIndexCases {
+		t.Run(c.name, func(t *testing.T) {
+			for n := 0; n < 20; n++ {
+				ops := make([]byte, 3*300)
+				rand.Read(ops)
+				newSortSetHarness(t, c.config).run(ops)
+			}
+		})
+	}
+}
+
+// FuzzSortSet
+// go test -fuzz=FuzzSortSet 运行, 发现的错误会保存在 testdata/fuzz/FuzzSortSet 中, 之后每次 go test 都会运行
+func FuzzSortSet(f *testing.F) {
+	f.Add(byte(0), []byte{0, 1, 2, 0, 2, 2, 3, 1, 0})
+	f.Add(byte(3), []byte{0, 1, 2, 1, 2, 2, 2, 3, 2, 4, 0, 31})
+	f.Add(byte(4), []byte{0, 1, 5, 0, 2, 5, 9, 0, 0, 5, 0, 15, 6, 0, 31})
+	f.Fuzz(func(t *testing.T, config byte, ops []byte) {
+		c := sortSetIndexCases[int(config)%len(sortSetIndexCases)]
+		newSortSetHarness(t, c.config).run(ops)
+	})
+}
+
+// FuzzSkipList
+// 直接在跳表上插入、删除、更新分数、按排名和分数范围删除, 和按顺序排列的 key 比较
+func FuzzSkipList(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 0, 2, 2, 1, 1, 5, 2, 2, 0})
+	f.Fuzz(runSkipListOps)
+}
+
+// runSkipListOps
+// 每3个字节是一个操作: 操作类型、key、分数
+func runSkipListOps(t *testing.T, ops []byte) {
+	st, err := NewDefaultSkipTable[string, *S1[string]](func(v1, v2 *S1[string]) int {
+		return strings.Compare(v1.key, v2.key)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := make(map[string]*SkipListNode[string, *S1[string]])
+	model := &sortSetModel{}
+	//范围删除时, 按顺序检查被删除的元素, 并从 nodes 中删除
+	var removed []*StItem[string]
+	onRemove := func(score float64, value *S1[string]) {
+		removed = append(removed, &StItem[string]{k: value.key, f: score})
+		delete(nodes, value.key)
+	}
+	for ; len(ops) >= 3; ops = ops[3:] {
+		key := strconv.Itoa(int(ops[1] % 32))
+		score := float64(ops[2] % 8)
+		node, ok := nodes[key]
+		switch {
+		case ops[0]%8 == 6:
+			left := int64(ops[1] % 16)
+			right := left + int64(ops[2]%8)
+			removed = removed[:0]
+			n := st.DeleteRangeByRank(left, right, onRemove)
+			var want []*StItem[string]
+			if left > 0 {
+				want = rangeByIndex(model.items, left-1, right-1, false)
+			}
+			equalKeys(t, "DeleteRangeByRank", removed, want)
+			if n != int64(len(want)) {
+				t.Fatalf("DeleteRangeByRank(%d,%d):%d want %d", left, right, n, len(want))
+			}
+			if len(want) > 0 {
+				model.items = slices.Delete(model.items, int(left-1), int(left-1)+len(want))
+			}
+		case ops[0]%8 == 7:
+			findRange := &SkipListFindRange{Min: float64(ops[1] % 8), Max: score, MinEx: ops[1]&8 != 0, MaxEx: ops[1]&16 != 0}
+			removed = removed[:0]
+			n := st.DeleteRangeByScore(findRange, onRemove)
+			if n != int64(len(removed)) {
+				t.Fatalf("DeleteRangeByScore %v:%d removed %d", findRange, n, len(removed))
+			}
+			kept := model.items[:0]
+			j := 0
+			for _, item := range model.items {
+				if (item.f > findRange.Min || (!findRange.MinEx && item.f == findRange.Min)) && (item.f < findRange.Max || (!findRange.MaxEx && item.f == findRange.Max)) {
+					if j >= len(removed) || removed[j].k != item.k {
+						t.Fatalf("DeleteRangeByScore %v removed %v, want %s at %d", findRange, removed, item.k, j)
+					}
+					j++
+					continue
+				}
+				kept = append(kept, item)
+			}
+			model.items = kept
+		case !ok:
+			nodes[key] = st.InsertByScore(score, &S1[string]{key: key, f: score})
+			model.add(&StItem[string]{k: key, f: score})
+		case ops[0]%3 == 0:
+			st.Delete(node, st.GetUpdateList(node))
+			delete(nodes, key)
+			j := model.find(key)
+			model.items = slices.Delete(model.items, j, j+1)
+		default:
+			st.UpdateScore(node, score)
+			model.add(&StItem[string]{k: key, f: score})
+		}
+		if err := st.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		j := 0
+		for x := st.head.Next(0); x != nil; x = x.Next(0) {
+			if x.value.key != model.items[j].k || x.score != model.items[j].f {
+				t.Fatalf("rank %d key:%s score:%f want:%s %f", j+1, x.value.key, x.score, model.items[j].k, model.items[j].f)
+			}
+			j++
+		}
+	}
+}
@@ -1,3 +1,3 @@
 module skip_tablev2
 
-go 1.18
+go 1.21
@@ -0,0 +1,275 @@
+package skiptablev2
+
+import (
+	"math/bits"
+	"slices"
+	"sync/atomic"
+)
+
+const (
+	//hamt 每一层使用的哈希值的位数, 每个结点最多 32 个分支
+	hamtBits = 5
+	hamtMask = 1<<hamtBits - 1
+	//哈希值的位数, 位用完后哈希值完全相同的元素放在同一个冲突结点中
+	hamtHashBits = 64
+)
+
+// hamtOwnerID
+// 分配 hamt 所有者编号的计数器
+var hamtOwnerID atomic.Uint64
+
+// hamtEntry
+// hamt 中的一个元素
+type hamtEntry[K comparable, T any] struct {
+	hash  uint64
+	key   K
+	value T
+}
+
+// hamtNode
+// hamt 的结点, entryMap 的第 i 位表示第 i 个分支是一个元素, nodeMap 的第 i 位表示第 i 个分支是一个子结点
+// 元素和子结点按分支的顺序紧凑存放, 冲突结点不使用这两个 bitmap, entries 中是哈希值完全相同的元素
+type hamtNode[K comparable, T any] struct {
+	//所属 hamt 的编号, 只有属于自己的结点可以直接修改
+	owner    uint64
+	entryMap uint32
+	nodeMap  uint32
+	entries  []hamtEntry[K, T]
+	children []*hamtNode[K, T]
+}
+
+// hamt
+// 持久化的哈希映射(hash array mapped trie), 复制是 O(1) 的, 复制后两份共享所有结点, 修改时只复制路径上的结点
+// 查找、插入、删除都是 O(log32 n)
+type hamt[K comparable, T any] struct {
+	root *hamtNode[K, T]
+	size int
+	//结点的 owner 和它相同时可以直接修改, 否则这个结点和别的 hamt 共享, 要先复制
+	owner uint64
+	hash  func(key K) uint64
+}
+
+func newHamt[K comparable, T any]() *hamt[K, T] {
+	return newHamtWithHash[K, T](defaultHash[K]())
+}
+
+// newHamtWithHash
+// 使用指定的哈希函数创建 hamt, 测试时用来制造哈希冲突
+func newHamtWithHash[K comparable, T any](hash func(key K) uint64) *hamt[K, T] {
+	owner := hamtOwnerID.Add(1)
+	return &hamt[K, T]{
+		root:  &hamtNode[K, T]{owner: owner},
+		owner: owner,
+		hash:  hash,
+	}
+}
+
+// hamtBit
+// 哈希值在第 shift 位开始的分支
+func hamtBit(hash uint64, shift uint) uint32 {
+	return 1 << ((hash >> shift) & hamtMask)
+}
+
+// hamtIndex
+// 分支 bit 前面的分支数量, 也就是它在紧凑数组中的下标
+func hamtIndex(bitmap, bit uint32) int {
+	return bits.OnesCount32(bitmap & (bit - 1))
+}
+
+func (m *hamt[K, T]) get(key K) (T, bool) {
+	return m.lookup(m.hash(key), key)
+}
+
+func (m *hamt[K, T]) lookup(hash uint64, key K) (T, bool) {
+	n := m.root
+	for shift := uint(0); ; shift += hamtBits {
+		if shift >= hamtHashBits {
+			for i := range n.entries {
+				if n.entries[i].key == key {
+					return n.entries[i].value, true
+				}
+			}
+			break
+		}
+		bit := hamtBit(hash, shift)
+		if n.entryMap&bit != 0 {
+			if e := &n.entries[hamtIndex(n.entryMap, bit)]; e.hash == hash && e.key == key {
+				return e.value, true
+			}
+			break
+		}
+		if n.nodeMap&bit == 0 {
+			break
+		}
+		n = n.children[hamtIndex(n.nodeMap, bit)]
+	}
+	var zero T
+	return zero, false
+}
+
+// mutable
+// 返回可以直接修改的结点: 结点属于自己时返回它本身, 否则复制一份, 调用者要用返回值替换原来的指针
+func (m *hamt[K, T]) mutable(n *hamtNode[K, T]) *hamtNode[K, T] {
+	if n.owner == m.owner {
+		return n
+	}
+	return &hamtNode[K, T]{
+		owner:    m.owner,
+		entryMap: n.entryMap,
+		nodeMap:  n.nodeMap,
+		entries:  slices.Clone(n.entries),
+		children: slices.Clone(n.children),
+	}
+}
+
+// set
+// 添加元素, 已经存在时更新
+func (m *hamt[K, T]) set(key K, value T) {
+	var added bool
+	m.root, added = m.insert(m.root, hamtEntry[K, T]{hash: m.hash(key), key: key, value: value}, 0)
+	if added {
+		m.size++
+	}
+}
+
+// insert
+// 在子树中添加元素, 返回修改后的子树和是否是新添加的元素
+func (m *hamt[K, T]) insert(n *hamtNode[K, T], e hamtEntry[K, T], shift uint) (*hamtNode[K, T], bool) {
+	n = m.mutable(n)
+	if shift >= hamtHashBits {
+		for i := range n.entries {
+			if n.entries[i].key == e.key {
+				n.entries[i].value = e.value
+				return n, false
+			}
+		}
+		n.entries = append(n.entries, e)
+		return n, true
+	}
+	bit := hamtBit(e.hash, shift)
+	switch {
+	case n.nodeMap&bit != 0:
+		i := hamtIndex(n.nodeMap, bit)
+		var added bool
+		n.children[i], added = m.insert(n.children[i], e, shift+hamtBits)
+		return n, added
+	case n.entryMap&bit != 0:
+		i := hamtIndex(n.entryMap, bit)
+		if old := n.entries[i]; old.hash != e.hash || old.key != e.key {
+			//同一个分支上有两个元素, 一起下移到新的子结点中
+			n.entries = removeAt(n.entries, i)
+			n.entryMap ^= bit
+			n.nodeMap |= bit
+			n.children = insertAt(n.children, hamtIndex(n.nodeMap, bit), m.pair(old, e, shift+hamtBits))
+			return n, true
+		}
+		n.entries[i].value = e.value
+		return n, false
+	default:
+		n.entryMap |= bit
+		n.entries = insertAt(n.entries, hamtIndex(n.entryMap, bit), e)
+		return n, true
+	}
+}
+
+// pair
+// 创建只包含两个元素的子树, 两个元素的哈希值在 shift 之前的位都相同
+func (m *hamt[K, T]) pair(a, b hamtEntry[K, T], shift uint) *hamtNode[K, T] {
+	n := &hamtNode[K, T]{owner: m.owner}
+	if shift >= hamtHashBits {
+		n.entries = []hamtEntry[K, T]{a, b}
+		return n
+	}
+	ba, bb := hamtBit(a.hash, shift), hamtBit(b.hash, shift)
+	if ba == bb {
+		n.nodeMap = ba
+		n.children = []*hamtNode[K, T]{m.pair(a, b, shift+hamtBits)}
+		return n
+	}
+	if ba > bb {
+		a, b = b, a
+	}
+	n.entryMap = ba | bb
+	n.entries = []hamtEntry[K, T]{a, b}
+	return n
+}
+
+// del
+// 删除元素, 元素不存在时不做任何修改, 不会复制结点
+func (m *hamt[K, T]) del(key K) {
+	hash := m.hash(key)
+	if _, ok := m.lookup(hash, key); !ok {
+		return
+	}
+	m.root = m.remove(m.root, hash, key, 0)
+	m.size--
+}
+
+// remove
+// 在子树中删除一个存在的元素, 子结点只剩一个元素时把元素提到上一层, 保持树尽量矮
+func (m *hamt[K, T]) remove(n *hamtNode[K, T], hash uint64, key K, shift uint) *hamtNode[K, T] {
+	n = m.mutable(n)
+	if shift >= hamtHashBits {
+		i := slices.IndexFunc(n.entries, func(e hamtEntry[K, T]) bool {
+			return e.key == key
+		})
+		n.entries = removeAt(n.entries, i)
+		return n
+	}
+	bit := hamtBit(hash, shift)
+	if n.entryMap&bit != 0 {
+		n.entries = removeAt(n.entries, hamtIndex(n.entryMap, bit))
+		n.entryMap ^= bit
+		return n
+	}
+	i := hamtIndex(n.nodeMap, bit)
+	child := m.remove(n.children[i], hash, key, shift+hamtBits)
+	if len(child.children) > 0 || len(child.entries) > 1 {
+		n.children[i] = child
+		return n
+	}
+	n.children = removeAt(n.children, i)
+	n.nodeMap ^= bit
+	if len(child.entries) == 1 {
+		n.entryMap |= bit
+		n.entries = insertAt(n.entries, hamtIndex(n.entryMap, bit), child.entries[0])
+	}
+	return n
+}
+
+// each
+// 遍历所有元素, 顺序是不确定的, fn 返回 false 时停止
+func (m *hamt[K, T]) each(fn func(key K, value T) bool) {
+	m.root.each(fn)
+}
+
+func (n *hamtNode[K, T]) each(fn func(key K, value T) bool) bool {
+	for i := range n.entries {
+		if !fn(n.entries[i].key, n.entries[i].value) {
+			return false
+		}
+	}
+	for _, child := range n.children {
+		if !child.each(fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// clone
+// 复制一份, 复杂度 O(1), 两份共享所有结点并且都换成新的编号, 之后谁修改谁复制路径上的结点
+func (m *hamt[K, T]) clone() *hamt[K, T] {
+	c := *m
+	m.owner = hamtOwnerID.Add(1)
+	c.owner = hamtOwnerID.Add(1)
+	return &c
+}
+
+func (m *hamt[K, T]) count() int {
+	return m.size
+}
+
+func (m *hamt[K, T]) cloneMap() memberMap[K, T] {
+	return m.clone()
+}
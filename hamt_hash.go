@@ -0,0 +1,76 @@
+package skiptablev2
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+)
+
+// reflectHash
+// 不使用 maphash.Comparable 的哈希函数, go 1.24 之前使用
+// 相等的 key 哈希值一定相同: +0 和 -0 的哈希值相同, 接口只计算动态值, 结构体跳过 "_" 字段
+func reflectHash[K comparable](seed maphash.Seed) func(key K) uint64 {
+	return func(key K) uint64 {
+		var h maphash.Hash
+		h.SetSeed(seed)
+		writeHash(&h, reflect.ValueOf(&key).Elem())
+		return h.Sum64()
+	}
+}
+
+// writeHash
+// 把 v 的值写入 h, v 必须是可以比较的类型
+func writeHash(h *maphash.Hash, v reflect.Value) {
+	var buf [8]byte
+	writeUint := func(x uint64) {
+		binary.LittleEndian.PutUint64(buf[:], x)
+		h.Write(buf[:])
+	}
+	writeFloat := func(f float64) {
+		//+0 == -0, 要有相同的哈希值
+		if f == 0 {
+			f = 0
+		}
+		writeUint(math.Float64bits(f))
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			writeUint(1)
+		} else {
+			writeUint(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		writeFloat(real(c))
+		writeFloat(imag(c))
+	case reflect.String:
+		h.WriteString(v.String())
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		writeUint(uint64(v.Pointer()))
+	case reflect.Interface:
+		if !v.IsNil() {
+			writeHash(h, v.Elem())
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			writeHash(h, v.Index(i))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).Name != "_" {
+				writeHash(h, v.Field(i))
+			}
+		}
+	default:
+		panic("writeHash: type " + v.Type().String() + " is not comparable")
+	}
+}
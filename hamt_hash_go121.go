@@ -0,0 +1,11 @@
+//go:build !go1.24
+
+package skiptablev2
+
+import "hash/maphash"
+
+// defaultHash
+// hamt 默认的哈希函数, go 1.24 之前没有 maphash.Comparable, 使用 reflectHash
+func defaultHash[K comparable]() func(key K) uint64 {
+	return reflectHash[K](maphash.MakeSeed())
+}
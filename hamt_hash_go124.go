@@ -0,0 +1,14 @@
+//go:build go1.24
+
+package skiptablev2
+
+import "hash/maphash"
+
+// defaultHash
+// hamt 默认的哈希函数, go 1.24 及以上使用 maphash.Comparable
+func defaultHash[K comparable]() func(key K) uint64 {
+	seed := maphash.MakeSeed()
+	return func(key K) uint64 {
+		return maphash.Comparable(seed, key)
+	}
+}
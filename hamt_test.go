@@ -0,0 +1,109 @@
+package skiptablev2
+
+import (
+	"hash/maphash"
+	"maps"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// 和 go map 比较, 每隔一段时间复制一份, 之后两份各自修改, 检查复制出来的那份不受影响
+// hash 是 nil 时使用默认的哈希函数
+func testHamt(t *testing.T, hash func(key int) uint64) {
+	m := newHamt[int, int]()
+	if hash != nil {
+		m = newHamtWithHash[int, int](hash)
+	}
+	want := map[int]int{}
+	type cloneCase struct {
+		m    *hamt[int, int]
+		want map[int]int
+	}
+	var clones []cloneCase
+	check := func(m *hamt[int, int], want map[int]int) {
+		t.Helper()
+		if m.count() != len(want) {
+			t.Fatalf("count:%d want:%d", m.count(), len(want))
+		}
+		got := map[int]int{}
+		m.each(func(key, value int) bool {
+			got[key] = value
+			return true
+		})
+		if !maps.Equal(got, want) {
+			t.Fatalf("each:%v want:%v", got, want)
+		}
+		for key, value := range want {
+			if v, ok := m.get(key); !ok || v != value {
+				t.Fatalf("get key:%d value:%d %v want:%d", key, v, ok, value)
+			}
+		}
+	}
+	for i := 0; i < 20000; i++ {
+		if i%1000 == 0 {
+			clones = append(clones, cloneCase{m.clone(), maps.Clone(want)})
+		}
+		key := rand.Intn(3000)
+		if rand.Intn(3) == 0 {
+			m.del(key)
+			delete(want, key)
+		} else {
+			m.set(key, i)
+			want[key] = i
+		}
+		if _, ok := m.get(-1); ok {
+			t.Fatal("get missing key")
+		}
+	}
+	check(m, want)
+	for _, c := range clones {
+		check(c.m, c.want)
+	}
+	//全部删除后回到空的根结点
+	for key := range want {
+		m.del(key)
+	}
+	if m.count() != 0 || len(m.root.entries) != 0 || len(m.root.children) != 0 {
+		t.Fatalf("count:%d entries:%d children:%d", m.count(), len(m.root.entries), len(m.root.children))
+	}
+}
+
+func TestHamt(t *testing.T) {
+	testHamt(t, nil)
+}
+
+// 哈希值只有很少几种, 大部分元素都在冲突结点中
+func TestHamt_Collision(t *testing.T) {
+	testHamt(t, func(key int) uint64 {
+		return uint64(key%7) << 58
+	})
+}
+
+// go 1.24 之前使用的哈希函数
+func TestHamt_ReflectHash(t *testing.T) {
+	testHamt(t, reflectHash[int](maphash.MakeSeed()))
+
+	type key struct {
+		s string
+		f float64
+		a [2]any
+		p *int
+		_ int
+	}
+	seed := maphash.MakeSeed()
+	hash := reflectHash[key](seed)
+	p := new(int)
+	k1 := key{s: "a", f: 0, a: [2]any{1, "b"}, p: p}
+	k2 := key{s: "a", f: math.Copysign(0, -1), a: [2]any{1, "b"}, p: p}
+	if k1 != k2 || hash(k1) != hash(k2) {
+		t.Fatalf("equal keys with different hash:%d %d", hash(k1), hash(k2))
+	}
+	k2.a[1] = "c"
+	if hash(k1) == hash(k2) {
+		t.Fatal("different keys with the same hash")
+	}
+	if h := reflectHash[any](seed); h(1) != h(1) || h(nil) != h(nil) {
+		t.Fatal("interface hash")
+	}
+}
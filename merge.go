@@ -0,0 +1,183 @@
+package skiptablev2
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// mergeStreamRatio
+// 合并时需要修改的元素数量乘以这个值不小于集合的元素数量时, 按顺序遍历两个集合重新构建整个集合(O(n+m)),
+// 否则逐个修改元素(O(m log n)), 只合并少量元素时不用遍历整个集合
+// BenchmarkSortSet_Merge 中需要修改的元素约为集合的 1/4 时两种方式耗时相同, 更少时逐个修改更快
+var mergeStreamRatio int64 = 4
+
+// MergePolicy
+// 合并时两个集合都有的元素的新分数, score 是集合中原来的分数, otherScore 是另一个集合中的分数
+// 可以使用 MergeMax、MergeMin、MergeSum、MergePreferOther, 也可以使用自定义的函数
+type MergePolicy[S cmp.Ordered] func(score, otherScore S) S
+
+// MergeMax
+// 保留较大的分数
+func MergeMax[S cmp.Ordered](score, otherScore S) S {
+	return max(score, otherScore)
+}
+
+// MergeMin
+// 保留较小的分数
+func MergeMin[S cmp.Ordered](score, otherScore S) S {
+	return min(score, otherScore)
+}
+
+// MergeSum
+// 两个分数相加, 和 redis ZUNIONSTORE 的 AGGREGATE SUM 相同; 分数是 string 时是把两个分数拼接起来
+func MergeSum[S cmp.Ordered](score, otherScore S) S {
+	return score + otherScore
+}
+
+// MergePreferOther
+// 使用另一个集合中的分数
+func MergePreferOther[S cmp.Ordered](_, otherScore S) S {
+	return otherScore
+}
+
+// MergeResult
+// 合并的结果
+type MergeResult struct {
+	//新添加的元素数量
+	Added int
+	//两个集合都有, 分数改变了的元素数量
+	Updated int
+	//两个集合都有, 分数没有改变的元素数量
+	Unchanged int
+}
+
+// Merge
+// 把 other 的所有元素合并到集合中, other 不会被修改; 集合中没有的元素直接添加, 两个集合都有的元素的分数由 policy 决定,
+// 和 Add 一样分数改变的元素保留集合中原来的 value
+// 先按顺序遍历 other 找出要添加和修改分数的元素, 要修改的元素较多时按顺序同时遍历两个集合, 线性时间重新构建集合, 不会逐个调用 Add;
+// 例外: 要修改的元素数量远少于集合的元素数量时, 会逐个修改这些元素(O(m log n)),
+// 这是有意的, 向很大的集合中合并少量元素时, 重新构建整个集合(O(n+m))反而更慢
+// policy 是 nil 或者计算出的分数是 NaN(比如 +Inf 和 -Inf 相加)时返回错误, 集合不会被修改
+func (set *ScoredSortSet[K, S, V]) Merge(other *ScoredSortSet[K, S, V], policy MergePolicy[S]) (MergeResult, error) {
+	var result MergeResult
+	if policy == nil {
+		return result, errors.New("Merge policy is nil")
+	}
+	//需要添加或者修改分数的元素, 按 other 的顺序排列, value 是 other 中的 value
+	var changes []listpackEntry[S, V]
+	//分数改变的元素的新分数
+	updated := make(map[K]S)
+	var err error
+	other.enc.scan(func(otherScore S, value V) bool {
+		score, exist := set.enc.score(value.Key())
+		if !exist {
+			changes = append(changes, listpackEntry[S, V]{score: otherScore, value: value})
+			result.Added++
+			return true
+		}
+		newScore := policy(score, otherScore)
+		if scoreIsNaN(newScore) {
+			err = fmt.Errorf("Merge new score of member %v is not a number (NaN)", value.Key())
+			return false
+		}
+		if newScore == score {
+			result.Unchanged++
+			return true
+		}
+		changes = append(changes, listpackEntry[S, V]{score: newScore, value: value})
+		updated[value.Key()] = newScore
+		result.Updated++
+		return true
+	})
+	if err != nil {
+		return MergeResult{}, err
+	}
+	if len(changes) == 0 {
+		return result, nil
+	}
+	if int64(len(changes))*mergeStreamRatio < set.enc.count() {
+		set.copyOnWrite()
+		defer set.convert()
+		for _, e := range changes {
+			set.enc.addScore(e.score, e.value)
+		}
+		return result, nil
+	}
+	set.rebuildMerged(other, changes, updated)
+	return result, nil
+}
+
+// rebuildMerged
+// 按顺序同时遍历集合和 other 中新添加的元素, 把合并后的元素依次放进紧凑编码的数组中, 元素较多时再转换成 map + 有序索引(跳表只需要线性时间)
+// 分数改变的元素不在原来的位置上, 单独排序后参与合并; 原来的数据不会被修改, 快照仍然可以使用
+func (set *ScoredSortSet[K, S, V]) rebuildMerged(other *ScoredSortSet[K, S, V], changes []listpackEntry[S, V], updated map[K]S) {
+	lp := newListpack[K, S, V](set.config.Order, set.compare)
+	before := func(e1, e2 listpackEntry[S, V]) bool {
+		return lp.before(e1.score, e1.value, e2.score, e2.value)
+	}
+	compareEntry := func(e1, e2 listpackEntry[S, V]) int {
+		if before(e1, e2) {
+			return -1
+		}
+		return 1
+	}
+	added := make([]listpackEntry[S, V], 0, len(changes)-len(updated))
+	moved := make([]listpackEntry[S, V], 0, len(updated))
+	for _, e := range changes {
+		if _, ok := updated[e.value.Key()]; !ok {
+			added = append(added, e)
+		}
+	}
+	//other 的排序方向或者比较函数和集合不同时, 按集合的顺序重新排序
+	if !slices.IsSortedFunc(added, compareEntry) {
+		slices.SortFunc(added, compareEntry)
+	}
+
+	entries := make([]listpackEntry[S, V], 0, set.enc.count()+int64(len(added)))
+	set.enc.scan(func(score S, value V) bool {
+		if newScore, ok := updated[value.Key()]; ok {
+			//保留集合中原来的 value
+			moved = append(moved, listpackEntry[S, V]{score: newScore, value: value})
+			return true
+		}
+		e := listpackEntry[S, V]{score: score, value: value}
+		for len(added) > 0 && before(added[0], e) {
+			entries = append(entries, added[0])
+			added = added[1:]
+		}
+		entries = append(entries, e)
+		return true
+	})
+	entries = append(entries, added...)
+	if len(moved) > 0 {
+		slices.SortFunc(moved, compareEntry)
+		entries = mergeEntries(entries, moved, before)
+	}
+
+	lp.entries = entries
+	set.enc = lp
+	set.shared = false
+	if lp.count() > int64(set.config.ListpackMaxEntries) {
+		set.expand(lp)
+	}
+}
+
+// mergeEntries
+// 合并两个排好序的数组
+func mergeEntries[S cmp.Ordered, V any](a, b []listpackEntry[S, V], before func(e1, e2 listpackEntry[S, V]) bool) []listpackEntry[S, V] {
+	result := make([]listpackEntry[S, V], 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if before(b[j], a[i]) {
+			result = append(result, b[j])
+			j++
+		} else {
+			result = append(result, a[i])
+			i++
+		}
+	}
+	result = append(result, a[i:]...)
+	return append(result, b[j:]...)
+}
@@ -0,0 +1,195 @@
+package skiptablev2
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestSortSet_Merge(t *testing.T) {
+	policies := []struct {
+		name   string
+		policy MergePolicy[float64]
+	}{
+		{"max", MergeMax[float64]},
+		{"min", MergeMin[float64]},
+		{"sum", MergeSum[float64]},
+		{"other", MergePreferOther[float64]},
+		{"custom", func(score, otherScore float64) float64 { return score*2 - otherScore }},
+	}
+	for _, c := range sortSetIndexCases {
+		t.Run(c.name, func(t *testing.T) {
+			for _, p := range policies {
+				for i := 0; i < 20; i++ {
+					testSortSetMerge(t, c.config, p.policy)
+				}
+			}
+		})
+	}
+}
+
+// 分别强制逐个修改和重新构建, 两种方式的结果相同
+func TestSortSet_MergeStreamRatio(t *testing.T) {
+	defer func(r int64) { mergeStreamRatio = r }(mergeStreamRatio)
+	for _, ratio := range []int64{0, 1 << 20} {
+		mergeStreamRatio = ratio
+		for _, c := range sortSetIndexCases {
+			t.Run(strconv.FormatInt(ratio, 10)+"/"+c.name, func(t *testing.T) {
+				for i := 0; i < 20; i++ {
+					testSortSetMerge(t, c.config, MergeMax[float64])
+				}
+			})
+		}
+	}
+}
+
+func testSortSetMerge(t *testing.T, config SortSetConfig, policy MergePolicy[float64]) {
+	set, _ := NewSortSetWithConfig[string, *StItem[string]](config, compareStItemKey)
+	//other 的排序方向随机, 和集合不同时也要能合并
+	otherConfig := config
+	if rand.Intn(2) == 0 {
+		otherConfig.Order = SCORE_ORDER_DESC - config.Order
+	}
+	other, _ := NewSortSetWithConfig[string, *StItem[string]](otherConfig, compareStItemKey)
+	//集合的大小差别较大时逐个修改, 否则重新构建
+	n, m := rand.Intn(200), rand.Intn(200)
+	if rand.Intn(2) == 0 {
+		m = rand.Intn(10)
+	}
+	model := &sortSetModel{desc: config.Order == SCORE_ORDER_DESC}
+	origin := make(map[string]*StItem[string])
+	for _, k := range rand.Perm(300)[:n] {
+		item := &StItem[string]{f: float64(rand.Intn(50)), k: strconv.Itoa(k)}
+		set.Add(item)
+		origin[item.k] = item
+	}
+	for _, item := range origin {
+		model.add(item)
+	}
+	var want MergeResult
+	otherItems := make(map[string]*StItem[string])
+	for i := 0; i < m; i++ {
+		item := &StItem[string]{f: float64(rand.Intn(50)), k: strconv.Itoa(rand.Intn(300))}
+		other.Add(item)
+		otherItems[item.k] = item
+	}
+	for k, item := range otherItems {
+		old, ok := origin[k]
+		if !ok {
+			model.add(item)
+			want.Added++
+			continue
+		}
+		score := policy(old.f, item.f)
+		if score == old.f {
+			want.Unchanged++
+			continue
+		}
+		model.add(&StItem[string]{f: score, k: k})
+		want.Updated++
+	}
+	snap := set.Snapshot()
+	before := set.Range(0, -1)
+
+	result, err := set.Merge(other, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != want {
+		t.Fatalf("Merge result:%+v want:%+v", result, want)
+	}
+	if err = set.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	equalKeys(t, "Merge", set.Range(0, -1), model.items)
+	for _, item := range model.items {
+		if set.Score(item.k) != item.f {
+			t.Fatalf("Merge key %s score:%v want:%v", item.k, set.Score(item.k), item.f)
+		}
+		//分数改变的元素保留集合中原来的 value
+		if old, ok := origin[item.k]; ok {
+			if v := set.Around(item.k, 0, 0)[0].Value; v != old {
+				t.Fatalf("Merge key %s value is replaced", item.k)
+			}
+		}
+	}
+	//other 和快照都不会被修改
+	if other.Count() != int64(len(otherItems)) {
+		t.Fatalf("Merge other count:%d want:%d", other.Count(), len(otherItems))
+	}
+	equalKeys(t, "Merge snapshot", snap.Range(0, -1), before)
+}
+
+func TestSortSet_MergeError(t *testing.T) {
+	set, _ := NewDefaultSortSet[string, *StItem[string]](compareStItemKey)
+	other, _ := NewDefaultSortSet[string, *StItem[string]](compareStItemKey)
+	set.Add(&StItem[string]{f: math.Inf(1), k: "a"}, &StItem[string]{f: 1, k: "b"})
+	other.Add(&StItem[string]{f: 2, k: "b"}, &StItem[string]{f: math.Inf(-1), k: "a"}, &StItem[string]{f: 3, k: "c"})
+	if _, err := set.Merge(other, nil); err == nil {
+		t.Fatal("Merge nil policy")
+	}
+	//+Inf 和 -Inf 相加是 NaN, 集合不会被修改
+	if _, err := set.Merge(other, MergeSum[float64]); err == nil {
+		t.Fatal("Merge NaN score")
+	}
+	if set.Count() != 2 || set.Score("b") != 1 {
+		t.Fatalf("Merge error modified the set")
+	}
+	result, err := set.Merge(other, MergeMax[float64])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (MergeResult{Added: 1, Updated: 1, Unchanged: 1}) {
+		t.Fatalf("Merge result:%+v", result)
+	}
+	//和自己合并
+	if result, err = set.Merge(set, MergeSum[float64]); err != nil {
+		t.Fatal(err)
+	}
+	if result != (MergeResult{Updated: 2, Unchanged: 1}) || set.Score("c") != 6 {
+		t.Fatalf("Merge itself result:%+v", result)
+	}
+	if err = set.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// BenchmarkSortSet_Merge
+// 向 100000 个元素的集合中合并 m 个元素, 比较逐个修改(add)和重新构建(rebuild)两种方式, 用来确定 mergeStreamRatio
+func BenchmarkSortSet_Merge(b *testing.B) {
+	const n = 100000
+	for _, index := range []string{SORT_SET_INDEX_SKIPLIST, SORT_SET_INDEX_BTREE} {
+		for _, m := range []int{10, 1000, n / 16, n / 8, n / 4} {
+			for _, rebuild := range []bool{false, true} {
+				name := index + "/" + strconv.Itoa(m) + "/add"
+				ratio := int64(0)
+				if rebuild {
+					name = index + "/" + strconv.Itoa(m) + "/rebuild"
+					ratio = n
+				}
+				b.Run(name, func(b *testing.B) {
+					defer func(r int64) { mergeStreamRatio = r }(mergeStreamRatio)
+					mergeStreamRatio = ratio
+					config := SortSetConfig{MaxLevel: SKIP_TABLE_DEFAULT_MAX_LEVEL, Index: index}
+					set, _ := NewSortSetWithConfig[string, *StItem[string]](config, compareStItemKey)
+					for i := 0; i < n; i++ {
+						set.Add(&StItem[string]{f: float64(i), k: strconv.Itoa(i)})
+					}
+					b.ReportAllocs()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						//other 中的元素集合里都有, 每次使用新的分数, 集合的大小不变
+						b.StopTimer()
+						other, _ := NewSortSetWithConfig[string, *StItem[string]](config, compareStItemKey)
+						for j := 0; j < m; j++ {
+							other.Add(&StItem[string]{f: float64(rand.Intn(n)), k: strconv.Itoa(rand.Intn(n))})
+						}
+						b.StartTimer()
+						set.Merge(other, MergePreferOther[float64])
+					}
+				})
+			}
+		}
+	}
+}
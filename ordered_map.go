@@ -0,0 +1,333 @@
+package skiptablev2
+
+import "cmp"
+
+// orderedMapEntry
+// 有序map中的一个键值对, key 同时作为跳表的分数
+type orderedMapEntry[K cmp.Ordered, V any] struct {
+	key   K
+	value V
+}
+
+func (e *orderedMapEntry[K, V]) Key() K {
+	return e.key
+}
+
+func (e *orderedMapEntry[K, V]) Score() K {
+	return e.key
+}
+
+// OrderedMap
+// 按 key 从小到大排序的 map, 类似 java 的 ConcurrentSkipListMap(但不是并发安全的)
+// 底层直接使用跳表, key 就是跳表的分数, 除了 floor/ceiling/higher/lower 之类的查找,
+// 还可以利用跳表的 span 在 O(log n) 内获取 key 的排名(Rank)和根据排名获取 key(Select)
+type OrderedMap[K cmp.Ordered, V any] struct {
+	list *ScoredSkipList[K, K, *orderedMapEntry[K, V]]
+}
+
+// NewDefaultOrderedMap
+// 初始化一个默认的有序map
+func NewDefaultOrderedMap[K cmp.Ordered, V any]() *OrderedMap[K, V] {
+	m, err := NewOrderedMap[K, V](SKIP_TABLE_DEFAULT_MAX_LEVEL)
+	if err != nil {
+		//默认的层数不会出错
+		panic(err)
+	}
+	return m
+}
+
+// NewOrderedMap
+// 初始化一个有序map, 可以设置底层跳表的最大层数
+func NewOrderedMap[K cmp.Ordered, V any](maxLevel int) (*OrderedMap[K, V], error) {
+	//key 不会重复, 分数相同的一定是同一个元素
+	list, err := NewScoredSkipTable[K, K, *orderedMapEntry[K, V]](maxLevel, func(v1, v2 *orderedMapEntry[K, V]) int {
+		return 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &OrderedMap[K, V]{
+		list: list,
+	}, nil
+}
+
+// seek
+// 查找最后一个 key < 给定key(inclusive 时是 key <= 给定key) 的结点和它的排名
+// 没有这样的结点时返回 head 和 0
+func (m *OrderedMap[K, V]) seek(key K, inclusive bool) (*ScoredSkipListNode[K, K, *orderedMapEntry[K, V]], int64) {
+	list := m.list
+	rank := int64(0)
+	t := list.head
+	for i := list.level - 1; i >= 0; i-- {
+		for t.Next(i) != nil && (t.Next(i).score < key || (inclusive && t.Next(i).score == key)) {
+			rank += t.Span(i)
+			t = t.Next(i)
+		}
+	}
+	return t, rank
+}
+
+// find
+// 查找 key 对应的结点, 不存在返回 nil
+func (m *OrderedMap[K, V]) find(key K) *ScoredSkipListNode[K, K, *orderedMapEntry[K, V]] {
+	t, _ := m.seek(key, false)
+	if t = t.Next(0); t != nil && t.score == key {
+		return t
+	}
+	return nil
+}
+
+// entry
+// 返回结点的键值对, 结点是 nil 或者 head 时返回 false
+func (m *OrderedMap[K, V]) entry(node *ScoredSkipListNode[K, K, *orderedMapEntry[K, V]]) (key K, value V, ok bool) {
+	if node == nil || node == m.list.head {
+		return
+	}
+	return node.value.key, node.value.value, true
+}
+
+// Len
+// 元素数量
+func (m *OrderedMap[K, V]) Len() int64 {
+	return m.list.Size()
+}
+
+// Put
+// 设置 key 对应的 value, 返回 key 是不是新添加的
+// key 是 NaN 时无法排序, 也无法再找到, 不会被添加
+func (m *OrderedMap[K, V]) Put(key K, value V) bool {
+	if scoreIsNaN(key) {
+		return false
+	}
+	if node := m.find(key); node != nil {
+		node.value.value = value
+		return false
+	}
+	m.list.InsertByScore(key, &orderedMapEntry[K, V]{key: key, value: value})
+	return true
+}
+
+// Get
+// 获取 key 对应的 value
+func (m *OrderedMap[K, V]) Get(key K) (value V, ok bool) {
+	if node := m.find(key); node != nil {
+		return node.value.value, true
+	}
+	return
+}
+
+// Contains
+// key 是否存在
+func (m *OrderedMap[K, V]) Contains(key K) bool {
+	return m.find(key) != nil
+}
+
+// Delete
+// 删除 key, 返回 key 是否存在
+func (m *OrderedMap[K, V]) Delete(key K) bool {
+	node := m.find(key)
+	if node == nil {
+		return false
+	}
+	m.list.Delete(node, m.list.updateList(node))
+	return true
+}
+
+// First
+// 最小的 key
+func (m *OrderedMap[K, V]) First() (K, V, bool) {
+	return m.entry(m.list.head.Next(0))
+}
+
+// Last
+// 最大的 key
+func (m *OrderedMap[K, V]) Last() (K, V, bool) {
+	return m.entry(m.list.tail)
+}
+
+// Floor
+// 最大的 <= key 的元素
+func (m *OrderedMap[K, V]) Floor(key K) (K, V, bool) {
+	t, _ := m.seek(key, true)
+	return m.entry(t)
+}
+
+// Lower
+// 最大的 < key 的元素
+func (m *OrderedMap[K, V]) Lower(key K) (K, V, bool) {
+	t, _ := m.seek(key, false)
+	return m.entry(t)
+}
+
+// Ceiling
+// 最小的 >= key 的元素
+func (m *OrderedMap[K, V]) Ceiling(key K) (K, V, bool) {
+	t, _ := m.seek(key, false)
+	return m.entry(t.Next(0))
+}
+
+// Higher
+// 最小的 > key 的元素
+func (m *OrderedMap[K, V]) Higher(key K) (K, V, bool) {
+	t, _ := m.seek(key, true)
+	return m.entry(t.Next(0))
+}
+
+// Rank
+// 返回 key 的排名(从0开始), 也就是比 key 小的元素数量, 复杂度 O(log n)
+// key 不存在时也会返回它插入后的排名, 第二个返回值表示 key 是否存在
+func (m *OrderedMap[K, V]) Rank(key K) (int64, bool) {
+	t, rank := m.seek(key, false)
+	next := t.Next(0)
+	return rank, next != nil && next.score == key
+}
+
+// Select
+// 返回排名是 i(从0开始) 的元素, 复杂度 O(log n)
+func (m *OrderedMap[K, V]) Select(i int64) (K, V, bool) {
+	return m.entry(m.list.getNodeByRank(i + 1))
+}
+
+// Range
+// 按 key 从小到大遍历所有元素, fn 返回 false 时停止遍历
+// 遍历时不能修改map
+func (m *OrderedMap[K, V]) Range(fn func(key K, value V) bool) {
+	for t := m.list.head.Next(0); t != nil; t = t.Next(0) {
+		if !fn(t.value.key, t.value.value) {
+			return
+		}
+	}
+}
+
+// SubMap
+// 返回 key 在 from 到 to 之间的视图, fromInclusive/toInclusive 表示是否包含边界
+// 视图不复制数据, 对原map的修改在视图中都能看到
+func (m *OrderedMap[K, V]) SubMap(from K, fromInclusive bool, to K, toInclusive bool) *OrderedSubMap[K, V] {
+	return &OrderedSubMap[K, V]{
+		m:             m,
+		from:          from,
+		to:            to,
+		fromInclusive: fromInclusive,
+		toInclusive:   toInclusive,
+	}
+}
+
+// HeadMap
+// 返回 key 小于 to(toInclusive 时小于等于) 的视图
+func (m *OrderedMap[K, V]) HeadMap(to K, toInclusive bool) *OrderedSubMap[K, V] {
+	return &OrderedSubMap[K, V]{
+		m:           m,
+		to:          to,
+		toInclusive: toInclusive,
+		fromInf:     true,
+	}
+}
+
+// TailMap
+// 返回 key 大于 from(fromInclusive 时大于等于) 的视图
+func (m *OrderedMap[K, V]) TailMap(from K, fromInclusive bool) *OrderedSubMap[K, V] {
+	return &OrderedSubMap[K, V]{
+		m:             m,
+		from:          from,
+		fromInclusive: fromInclusive,
+		toInf:         true,
+	}
+}
+
+// OrderedSubMap
+// 有序map中一段 key 范围的视图
+type OrderedSubMap[K cmp.Ordered, V any] struct {
+	m *OrderedMap[K, V]
+	//范围的开始和结束, Inf 表示这一端没有限制
+	from, to                   K
+	fromInclusive, toInclusive bool
+	fromInf, toInf             bool
+}
+
+// first
+// 范围内的第一个结点和它的排名(从1开始), 没有时返回 nil
+func (s *OrderedSubMap[K, V]) first() (*ScoredSkipListNode[K, K, *orderedMapEntry[K, V]], int64) {
+	var t *ScoredSkipListNode[K, K, *orderedMapEntry[K, V]]
+	var rank int64
+	if s.fromInf {
+		t = s.m.list.head
+	} else {
+		t, rank = s.m.seek(s.from, !s.fromInclusive)
+	}
+	t = t.Next(0)
+	if t == nil || !s.inTo(t.score) {
+		return nil, 0
+	}
+	return t, rank + 1
+}
+
+// last
+// 范围内的最后一个结点和它的排名(从1开始), 没有时返回 nil
+func (s *OrderedSubMap[K, V]) last() (*ScoredSkipListNode[K, K, *orderedMapEntry[K, V]], int64) {
+	var t *ScoredSkipListNode[K, K, *orderedMapEntry[K, V]]
+	var rank int64
+	if s.toInf {
+		t, rank = s.m.list.tail, s.m.list.Size()
+	} else {
+		t, rank = s.m.seek(s.to, s.toInclusive)
+	}
+	if t == nil || t == s.m.list.head || !s.inFrom(t.score) {
+		return nil, 0
+	}
+	return t, rank
+}
+
+// key 是否满足范围的开始
+func (s *OrderedSubMap[K, V]) inFrom(key K) bool {
+	return s.fromInf || key > s.from || (s.fromInclusive && key == s.from)
+}
+
+// key 是否满足范围的结束
+func (s *OrderedSubMap[K, V]) inTo(key K) bool {
+	return s.toInf || key < s.to || (s.toInclusive && key == s.to)
+}
+
+// Len
+// 范围内的元素数量, 使用两个端点的排名计算, 复杂度 O(log n)
+func (s *OrderedSubMap[K, V]) Len() int64 {
+	_, firstRank := s.first()
+	_, lastRank := s.last()
+	if firstRank == 0 || lastRank < firstRank {
+		return 0
+	}
+	return lastRank - firstRank + 1
+}
+
+// Get
+// 获取范围内 key 对应的 value
+func (s *OrderedSubMap[K, V]) Get(key K) (value V, ok bool) {
+	if !s.inFrom(key) || !s.inTo(key) {
+		return
+	}
+	return s.m.Get(key)
+}
+
+// First
+// 范围内最小的 key
+func (s *OrderedSubMap[K, V]) First() (K, V, bool) {
+	t, _ := s.first()
+	return s.m.entry(t)
+}
+
+// Last
+// 范围内最大的 key
+func (s *OrderedSubMap[K, V]) Last() (K, V, bool) {
+	t, _ := s.last()
+	return s.m.entry(t)
+}
+
+// Range
+// 按 key 从小到大遍历范围内的元素, fn 返回 false 时停止遍历
+func (s *OrderedSubMap[K, V]) Range(fn func(key K, value V) bool) {
+	t, _ := s.first()
+	for ; t != nil && s.inTo(t.score); t = t.Next(0) {
+		if !fn(t.value.key, t.value.value) {
+			return
+		}
+	}
+}
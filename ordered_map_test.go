@@ -0,0 +1,153 @@
+package skiptablev2
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// 和排好序的数组比较
+func TestOrderedMap(t *testing.T) {
+	const SIZE = 1000
+	m := NewDefaultOrderedMap[int, string]()
+	values := make(map[int]string)
+	for i := 0; i < SIZE*3; i++ {
+		key := rand.Intn(SIZE)
+		if rand.Intn(4) == 0 {
+			_, e := values[key]
+			if m.Delete(key) != e {
+				t.Fatalf("Delete key:%d exist:%v", key, e)
+			}
+			delete(values, key)
+			continue
+		}
+		_, e := values[key]
+		value := string(rune('a' + i%26))
+		if m.Put(key, value) == e {
+			t.Fatalf("Put key:%d exist:%v", key, e)
+		}
+		values[key] = value
+	}
+	keys := make([]int, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	if m.Len() != int64(len(keys)) {
+		t.Fatalf("len:%d want:%d", m.Len(), len(keys))
+	}
+
+	check := func(op string, key int, gotKey int, ok bool, index int) {
+		t.Helper()
+		if ok != (index >= 0 && index < len(keys)) {
+			t.Fatalf("%s(%d) ok:%v index:%d", op, key, ok, index)
+		}
+		if ok && gotKey != keys[index] {
+			t.Fatalf("%s(%d) key:%d want:%d", op, key, gotKey, keys[index])
+		}
+	}
+	for key := -1; key <= SIZE; key++ {
+		//第一个 >= key 和第一个 > key 的下标
+		ge := sort.SearchInts(keys, key)
+		gt := sort.SearchInts(keys, key+1)
+
+		k, _, ok := m.Ceiling(key)
+		check("Ceiling", key, k, ok, ge)
+		k, _, ok = m.Higher(key)
+		check("Higher", key, k, ok, gt)
+		k, _, ok = m.Floor(key)
+		check("Floor", key, k, ok, gt-1)
+		k, _, ok = m.Lower(key)
+		check("Lower", key, k, ok, ge-1)
+
+		rank, exist := m.Rank(key)
+		if rank != int64(ge) || exist != (ge != gt) {
+			t.Fatalf("Rank(%d) rank:%d exist:%v want:%d", key, rank, exist, ge)
+		}
+		if value, ok := m.Get(key); ok != exist || value != values[key] {
+			t.Fatalf("Get(%d) value:%s ok:%v", key, value, ok)
+		}
+	}
+	for i := range keys {
+		k, v, ok := m.Select(int64(i))
+		check("Select", i, k, ok, i)
+		if v != values[k] {
+			t.Fatalf("Select(%d) value:%s want:%s", i, v, values[k])
+		}
+	}
+	if _, _, ok := m.Select(int64(len(keys))); ok {
+		t.Fatal("Select out of range")
+	}
+	k, _, ok := m.First()
+	check("First", 0, k, ok, 0)
+	k, _, ok = m.Last()
+	check("Last", 0, k, ok, len(keys)-1)
+
+	var all []int
+	m.Range(func(key int, value string) bool {
+		all = append(all, key)
+		return true
+	})
+	if !slices.Equal(all, keys) {
+		t.Fatalf("Range keys:%v want:%v", all, keys)
+	}
+}
+
+func TestOrderedMap_SubMap(t *testing.T) {
+	const SIZE = 200
+	m := NewDefaultOrderedMap[int, int]()
+	keys := make([]int, 0, SIZE)
+	for i := 0; i < SIZE; i++ {
+		//只有偶数, 方便测试边界不存在的情况
+		m.Put(i*2, i)
+		keys = append(keys, i*2)
+	}
+	for i := 0; i < 200; i++ {
+		from, to := rand.Intn(SIZE*2+10)-5, rand.Intn(SIZE*2+10)-5
+		fromInclusive, toInclusive := rand.Intn(2) == 0, rand.Intn(2) == 0
+		sub := m.SubMap(from, fromInclusive, to, toInclusive)
+		switch rand.Intn(3) {
+		case 1:
+			sub = m.HeadMap(to, toInclusive)
+			from, fromInclusive = -1<<31, true
+		case 2:
+			sub = m.TailMap(from, fromInclusive)
+			to, toInclusive = 1<<31, true
+		}
+
+		var want []int
+		for _, key := range keys {
+			if (key > from || (fromInclusive && key == from)) && (key < to || (toInclusive && key == to)) {
+				want = append(want, key)
+			}
+		}
+		var got []int
+		sub.Range(func(key int, value int) bool {
+			got = append(got, key)
+			return true
+		})
+		if !slices.Equal(got, want) {
+			t.Fatalf("SubMap(%d,%v,%d,%v) keys:%v want:%v", from, fromInclusive, to, toInclusive, got, want)
+		}
+		if sub.Len() != int64(len(want)) {
+			t.Fatalf("SubMap(%d,%v,%d,%v) len:%d want:%d", from, fromInclusive, to, toInclusive, sub.Len(), len(want))
+		}
+		first, _, ok := sub.First()
+		last, _, _ := sub.Last()
+		if ok != (len(want) > 0) || (ok && (first != want[0] || last != want[len(want)-1])) {
+			t.Fatalf("SubMap(%d,%v,%d,%v) first:%d last:%d want:%v", from, fromInclusive, to, toInclusive, first, last, want)
+		}
+	}
+
+	//视图能看到原map的修改
+	sub := m.SubMap(10, true, 20, true)
+	m.Put(11, 0)
+	m.Delete(10)
+	if _, ok := sub.Get(11); !ok || sub.Len() != 6 {
+		t.Fatalf("SubMap view len:%d", sub.Len())
+	}
+	if _, ok := sub.Get(22); ok {
+		t.Fatal("SubMap Get out of range")
+	}
+}
@@ -0,0 +1,174 @@
+package skiptablev2
+
+import (
+	"cmp"
+	"errors"
+	"math"
+)
+
+// QuantileMode
+// 分位数落在两个元素之间时的插值方式, 和 numpy.quantile 的 method 相同
+type QuantileMode int
+
+const (
+	//QUANTILE_LINEAR 线性插值
+	QUANTILE_LINEAR QuantileMode = iota
+	//QUANTILE_LOWER 取较小的那个元素
+	QUANTILE_LOWER
+	//QUANTILE_HIGHER 取较大的那个元素
+	QUANTILE_HIGHER
+	//QUANTILE_NEAREST 取较近的那个元素, 距离相同时取下标是偶数的那个
+	QUANTILE_NEAREST
+	//QUANTILE_MIDPOINT 取两个元素的平均值
+	QUANTILE_MIDPOINT
+)
+
+// Quantile
+// 返回分数的 q 分位数(q 在 [0,1] 之间, 比如 p99 是 0.99), 使用线性插值
+// 集合是空的或者 q 不在 [0,1] 之间时返回 false
+func (set *ScoredSortSet[K, S, V]) Quantile(q float64) (float64, bool) {
+	return set.QuantileWithMode(q, QUANTILE_LINEAR)
+}
+
+// QuantileWithMode
+// 返回分数的 q 分位数, 可以指定插值方式, 复杂度 O(log n)
+// 分数按从小到大计算, 和集合的排序方向无关, 分数不是数字时结果没有意义
+func (set *ScoredSortSet[K, S, V]) QuantileWithMode(q float64, mode QuantileMode) (float64, bool) {
+	n := set.enc.count()
+	if n == 0 || !(q >= 0 && q <= 1) {
+		return 0, false
+	}
+	//分位数在从小到大排序后的位置
+	h := q * float64(n-1)
+	lo := int64(math.Floor(h))
+	hi := int64(math.Ceil(h))
+	switch mode {
+	case QUANTILE_LOWER:
+		return set.scoreAt(lo), true
+	case QUANTILE_HIGHER:
+		return set.scoreAt(hi), true
+	case QUANTILE_NEAREST:
+		return set.scoreAt(int64(math.RoundToEven(h))), true
+	case QUANTILE_MIDPOINT:
+		return (set.scoreAt(lo) + set.scoreAt(hi)) / 2, true
+	default:
+		low := set.scoreAt(lo)
+		if lo == hi {
+			return low, true
+		}
+		return low + (h-float64(lo))*(set.scoreAt(hi)-low), true
+	}
+}
+
+// scoreAt
+// 分数从小到大排序后, 下标是 i(从0开始) 的元素的分数
+func (set *ScoredSortSet[K, S, V]) scoreAt(i int64) float64 {
+	//从大到小的集合, 下标要反过来
+	if set.config.Order == SCORE_ORDER_DESC {
+		i = set.enc.count() - 1 - i
+	}
+	values := set.enc.valuesByRank(i+1, i+1)
+	if len(values) == 0 {
+		return 0
+	}
+	//value 中的分数可能是旧的, 使用集合中记录的分数
+	score, _ := set.enc.score(values[0].Key())
+	return scoreFloat(score)
+}
+
+// PercentileOf
+// 返回分数 <= score 的元素占所有元素的百分比(0到100), 复杂度 O(log n)
+// 集合是空的时候返回0
+func (set *ScoredSortSet[K, S, V]) PercentileOf(score S) float64 {
+	n := set.enc.count()
+	if n == 0 {
+		return 0
+	}
+	a := set.enc.aggregateByScore(&ScoreRange[S]{
+		Max:    score,
+		MinInf: true,
+	})
+	return float64(a.Count) * 100 / float64(n)
+}
+
+// quantileSample
+// 滑动窗口中的一个样本, seq 是样本的序号, 作为集合的key
+type quantileSample[S cmp.Ordered] struct {
+	seq   uint64
+	score S
+}
+
+func (s quantileSample[S]) Key() uint64 {
+	return s.seq
+}
+
+func (s quantileSample[S]) Score() S {
+	return s.score
+}
+
+// QuantileWindow
+// 只保留最近 N 个样本的分位数统计, 比如最近10000次请求的 p99 延迟
+// 样本可以重复, 超过 N 个样本后最早的样本会被删除
+type QuantileWindow[S cmp.Ordered] struct {
+	set *ScoredSortSet[uint64, S, quantileSample[S]]
+	//最多保留的样本数量
+	size uint64
+	//下一个样本的序号, 窗口中的样本序号是 [seq-size, seq)
+	seq uint64
+}
+
+// NewQuantileWindow
+// 初始化一个保留最近 size 个样本的分位数统计
+func NewQuantileWindow[S cmp.Ordered](size int) (*QuantileWindow[S], error) {
+	if size <= 0 {
+		return nil, errors.New("NewQuantileWindow size must be positive")
+	}
+	set, err := NewScoredSortSet[uint64, S, quantileSample[S]](SortSetConfig{
+		MaxLevel:           SKIP_TABLE_DEFAULT_MAX_LEVEL,
+		ListpackMaxEntries: SORT_SET_DEFAULT_LISTPACK_ENTRIES,
+	}, func(v1, v2 quantileSample[S]) int {
+		//分数相同时按序号排序
+		return cmp.Compare(v1.seq, v2.seq)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &QuantileWindow[S]{
+		set:  set,
+		size: uint64(size),
+	}, nil
+}
+
+// Add
+// 添加一个样本, 窗口满了时删除最早的样本
+func (w *QuantileWindow[S]) Add(score S) {
+	if w.seq >= w.size {
+		w.set.Remove(w.seq - w.size)
+	}
+	w.set.Add(quantileSample[S]{seq: w.seq, score: score})
+	w.seq++
+}
+
+// Count
+// 窗口中的样本数量
+func (w *QuantileWindow[S]) Count() int64 {
+	return w.set.Count()
+}
+
+// Quantile
+// 窗口中样本的 q 分位数, 使用线性插值
+func (w *QuantileWindow[S]) Quantile(q float64) (float64, bool) {
+	return w.set.Quantile(q)
+}
+
+// QuantileWithMode
+// 窗口中样本的 q 分位数, 可以指定插值方式
+func (w *QuantileWindow[S]) QuantileWithMode(q float64, mode QuantileMode) (float64, bool) {
+	return w.set.QuantileWithMode(q, mode)
+}
+
+// PercentileOf
+// 窗口中分数 <= score 的样本占所有样本的百分比
+func (w *QuantileWindow[S]) PercentileOf(score S) float64 {
+	return w.set.PercentileOf(score)
+}
@@ -0,0 +1,109 @@
+package skiptablev2
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSortSet_Quantile(t *testing.T) {
+	for _, order := range []ScoreOrder{SCORE_ORDER_ASC, SCORE_ORDER_DESC} {
+		set := newOrderTestSortSet(0, order)
+		scores := make([]float64, 0, 1000)
+		for i := 0; i < 1000; i++ {
+			score := float64(rand.Intn(500))
+			set.Add(&StItem[string]{k: strconv.Itoa(i), f: score})
+			scores = append(scores, score)
+		}
+		sort.Float64s(scores)
+
+		for _, q := range []float64{0, 0.1, 0.5, 0.95, 0.99, 0.999, 1} {
+			h := q * float64(len(scores)-1)
+			lo, hi := scores[int(math.Floor(h))], scores[int(math.Ceil(h))]
+			want := map[QuantileMode]float64{
+				QUANTILE_LINEAR:   lo + (h-math.Floor(h))*(hi-lo),
+				QUANTILE_LOWER:    lo,
+				QUANTILE_HIGHER:   hi,
+				QUANTILE_NEAREST:  scores[int(math.RoundToEven(h))],
+				QUANTILE_MIDPOINT: (lo + hi) / 2,
+			}
+			for mode, w := range want {
+				if got, ok := set.QuantileWithMode(q, mode); !ok || math.Abs(got-w) > 1e-9 {
+					t.Fatalf("order:%d Quantile(%f, %d) %f != %f", order, q, mode, got, w)
+				}
+			}
+		}
+		if _, ok := set.Quantile(1.5); ok {
+			t.Fatal("Quantile(1.5) should fail")
+		}
+
+		for i := 0; i < 100; i++ {
+			score := float64(rand.Intn(520) - 10)
+			n := sort.Search(len(scores), func(i int) bool {
+				return scores[i] > score
+			})
+			if got, want := set.PercentileOf(score), float64(n)*100/float64(len(scores)); got != want {
+				t.Fatalf("order:%d PercentileOf(%f) %f != %f", order, score, got, want)
+			}
+		}
+	}
+}
+
+func TestQuantileWindow(t *testing.T) {
+	const SIZE = 300
+	w, err := NewQuantileWindow[int64](SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := make([]int64, 0, SIZE*3)
+	for i := 0; i < SIZE*3; i++ {
+		sample := rand.Int63n(100)
+		w.Add(sample)
+		samples = append(samples, sample)
+
+		window := append([]int64(nil), samples[max(0, len(samples)-SIZE):]...)
+		if w.Count() != int64(len(window)) {
+			t.Fatalf("count:%d want:%d", w.Count(), len(window))
+		}
+		if i%37 != 0 {
+			continue
+		}
+		sort.Slice(window, func(i, j int) bool {
+			return window[i] < window[j]
+		})
+		idx := int(math.RoundToEven(0.99 * float64(len(window)-1)))
+		if got, _ := w.QuantileWithMode(0.99, QUANTILE_NEAREST); got != float64(window[idx]) {
+			t.Fatalf("p99 %f != %d", got, window[idx])
+		}
+		if got, _ := w.Quantile(0); got != float64(window[0]) {
+			t.Fatalf("p0 %f != %d", got, window[0])
+		}
+	}
+	if _, err = NewQuantileWindow[int64](0); err == nil {
+		t.Fatal("NewQuantileWindow(0) should fail")
+	}
+}
+
+// TestQuantileWindow_Duration
+// 延迟窗口的分数是 time.Duration, 插值要按底层的 int64 计算
+func TestQuantileWindow_Duration(t *testing.T) {
+	w, err := NewQuantileWindow[time.Duration](100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 10; i++ {
+		w.Add(time.Duration(i) * time.Millisecond)
+	}
+	if got, _ := w.Quantile(0.5); got != float64(5500*time.Microsecond) {
+		t.Fatalf("p50 %f", got)
+	}
+	if got, _ := w.Quantile(1); got != float64(10*time.Millisecond) {
+		t.Fatalf("p100 %f", got)
+	}
+	if got := w.PercentileOf(5 * time.Millisecond); got != 50 {
+		t.Fatalf("PercentileOf %f", got)
+	}
+}
@@ -0,0 +1,167 @@
+package skiptablev2
+
+import "cmp"
+
+// RankMode
+// 分数相同(并列)的元素的排名方式, 排名都是从1开始的
+// 比如分数是 10,20,20,30 的四个元素, 各种方式的排名是:
+//
+//	RANK_ORDINAL              1,2,3,4
+//	RANK_COMPETITION          1,2,2,4
+//	RANK_DENSE                1,2,2,3
+//	RANK_MODIFIED_COMPETITION 1,3,3,4
+//	RANK_FRACTIONAL           1,2.5,2.5,4
+type RankMode int
+
+const (
+	//RANK_ORDINAL 不考虑并列, 就是元素在集合中的位置, 和 Rank+1 相同
+	RANK_ORDINAL RankMode = iota
+	//RANK_COMPETITION 标准竞赛排名, 并列的元素取最靠前的排名, 后面的排名会空出来
+	RANK_COMPETITION
+	//RANK_DENSE 密集排名, 并列的元素排名相同, 后面的排名不空位
+	RANK_DENSE
+	//RANK_MODIFIED_COMPETITION 修正竞赛排名, 并列的元素取最靠后的排名, 前面的排名会空出来
+	RANK_MODIFIED_COMPETITION
+	//RANK_FRACTIONAL 分数排名, 并列的元素取它们位置的平均值
+	RANK_FRACTIONAL
+)
+
+// TieRankedValue
+// 带有指定排名方式的排名的元素
+type TieRankedValue[S cmp.Ordered, V any] struct {
+	Value V
+	//排名(从1开始), 只有 RANK_FRACTIONAL 会出现小数
+	Rank  float64
+	Score S
+}
+
+// tieRank
+// 根据排名方式计算排名, position 是元素的位置(从1开始)
+// before 是排在这个分数前面的元素数量, equal 是这个分数的元素数量, groups 是排在前面的不同分数的数量
+func tieRank(mode RankMode, position, before, equal, groups int64) float64 {
+	switch mode {
+	case RANK_COMPETITION:
+		return float64(before + 1)
+	case RANK_DENSE:
+		return float64(groups + 1)
+	case RANK_MODIFIED_COMPETITION:
+		return float64(before + equal)
+	case RANK_FRACTIONAL:
+		return float64(before) + float64(equal+1)/2
+	default:
+		return float64(position)
+	}
+}
+
+// tieCounts
+// 排在分数 score 前面的结点数量, 分数等于 score 的结点数量, 排在前面的不同分数的数量
+// 都是通过各层的 span 和 groups 计算的, 复杂度 O(log n)
+func (list *ScoredSkipList[K, S, V]) tieCounts(score S) (before, equal, groups int64) {
+	before, _, groups = list.prefixWhile(func(s S) bool {
+		return list.less(s, score)
+	})
+	end, _, _ := list.prefixWhile(func(s S) bool {
+		return !list.less(score, s)
+	})
+	return before, end - before, groups
+}
+
+// tieCounts
+// 和跳表一样, 通过每个子树的元素数量和不同分数的数量计算, 复杂度 O(log n)
+func (tree *btreeIndex[K, S, V]) tieCounts(score S) (before, equal, groups int64) {
+	before, _ = tree.countPrefix(func(e *listpackEntry[S, V]) bool {
+		return tree.less(e.score, score)
+	})
+	end, _ := tree.countPrefix(func(e *listpackEntry[S, V]) bool {
+		return !tree.less(score, e.score)
+	})
+	if before > 0 {
+		//第一个元素也是一个不同的分数
+		groups = tree.prefixGroups(before) + 1
+	}
+	return before, end - before, groups
+}
+
+// RankWithMode
+// 按指定的排名方式返回元素的排名(从1开始), 元素不存在返回 false
+// 底层是跳表时复杂度 O(log n)
+func (set *ScoredSortSet[K, S, V]) RankWithMode(key K, mode RankMode) (float64, bool) {
+	rank := set.enc.rank(key)
+	if rank == 0 {
+		return 0, false
+	}
+	if mode == RANK_ORDINAL {
+		return float64(rank), true
+	}
+	score, _ := set.enc.score(key)
+	before, equal, groups := set.enc.tieCounts(score)
+	return tieRank(mode, rank, before, equal, groups), true
+}
+
+// RangeWithRank
+// 和 Range 相同, 同时返回每个元素按指定排名方式的排名(从1开始)
+// 只在范围的开头和结尾查询并列信息, 复杂度 O(log n + k)
+func (set *ScoredSortSet[K, S, V]) RangeWithRank(min, max int64, mode RankMode) []TieRankedValue[S, V] {
+	return set.withTieRank(set.Range(min, max), mode)
+}
+
+// RangeByScoreWithRank
+// 和 RangeByScore 相同, 同时返回每个元素按指定排名方式的排名(从1开始)
+func (set *ScoredSortSet[K, S, V]) RangeByScoreWithRank(findRange *ScoreRange[S], mode RankMode) []TieRankedValue[S, V] {
+	return set.withTieRank(set.RangeByScore(findRange), mode)
+}
+
+// withTieRank
+// 给按集合顺序连续排列的一段元素加上排名
+// 只在第一个元素处查询一次并列信息, 之后的每一组相同分数都接在前一组后面, 并列信息可以递推出来;
+// 只有最后一组可能延伸到范围外面, 需要再查询一次相同分数的元素数量
+func (set *ScoredSortSet[K, S, V]) withTieRank(values []V, mode RankMode) []TieRankedValue[S, V] {
+	if len(values) == 0 {
+		return nil
+	}
+	first := set.enc.rank(values[0].Key())
+	result := make([]TieRankedValue[S, V], len(values))
+	for i, value := range values {
+		//value 中的分数可能是旧的, 使用集合中记录的分数
+		score, _ := set.enc.score(value.Key())
+		result[i] = TieRankedValue[S, V]{Value: value, Score: score}
+	}
+	var before, equal, groups int64
+	for i := 0; i < len(result); {
+		//[i,j) 是一组相同的分数
+		j := i + 1
+		for j < len(result) && result[j].Score == result[i].Score {
+			j++
+		}
+		switch {
+		case mode == RANK_ORDINAL:
+		case i == 0 || j == len(result):
+			//第一组可能从范围前面开始, 最后一组可能延伸到范围后面
+			before, equal, groups = set.enc.tieCounts(result[i].Score)
+		default:
+			before, equal, groups = before+equal, int64(j-i), groups+1
+		}
+		for ; i < j; i++ {
+			result[i].Rank = tieRank(mode, first+int64(i), before, equal, groups)
+		}
+	}
+	return result
+}
+
+// RankWithMode
+// 按指定的排名方式返回快照中元素的排名(从1开始)
+func (snap *ScoredSortSetSnapshot[K, S, V]) RankWithMode(key K, mode RankMode) (float64, bool) {
+	return snap.set.RankWithMode(key, mode)
+}
+
+// RangeWithRank
+// 通过索引区间返回快照中的元素和它们按指定排名方式的排名
+func (snap *ScoredSortSetSnapshot[K, S, V]) RangeWithRank(min, max int64, mode RankMode) []TieRankedValue[S, V] {
+	return snap.set.RangeWithRank(min, max, mode)
+}
+
+// RangeByScoreWithRank
+// 返回快照中分数区间内的元素和它们按指定排名方式的排名
+func (snap *ScoredSortSetSnapshot[K, S, V]) RangeByScoreWithRank(findRange *ScoreRange[S], mode RankMode) []TieRankedValue[S, V] {
+	return snap.set.RangeByScoreWithRank(findRange, mode)
+}
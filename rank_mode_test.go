@@ -0,0 +1,41 @@
+package skiptablev2
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSortSet_RankWithMode(t *testing.T) {
+	for _, c := range sortSetIndexCases {
+		t.Run(c.name, func(t *testing.T) {
+			set, err := NewSortSetWithConfig[string, *StItem[string]](c.config, compareStItemKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			set.Add(&StItem[string]{k: "a", f: 10}, &StItem[string]{k: "b", f: 20}, &StItem[string]{k: "c", f: 20}, &StItem[string]{k: "d", f: 30})
+			want := map[RankMode][]float64{
+				RANK_ORDINAL:              {1, 2, 3, 4},
+				RANK_COMPETITION:          {1, 2, 2, 4},
+				RANK_DENSE:                {1, 2, 2, 3},
+				RANK_MODIFIED_COMPETITION: {1, 3, 3, 4},
+				RANK_FRACTIONAL:           {1, 2.5, 2.5, 4},
+			}
+			//分数是对称的, 从大到小排序时每个位置的排名也一样
+			for mode, ranks := range want {
+				var got []float64
+				for _, r := range set.RangeWithRank(0, -1, mode) {
+					got = append(got, r.Rank)
+				}
+				if !slices.Equal(got, ranks) {
+					t.Fatalf("RangeWithRank mode:%d %v != %v", mode, got, ranks)
+				}
+			}
+			if rank, ok := set.RankWithMode("c", RANK_DENSE); !ok || rank != 2 {
+				t.Fatalf("RankWithMode dense %v %v", rank, ok)
+			}
+			if _, ok := set.RankWithMode("x", RANK_DENSE); ok {
+				t.Fatal("RankWithMode missing key")
+			}
+		})
+	}
+}
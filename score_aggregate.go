@@ -0,0 +1,131 @@
+package skiptablev2
+
+// ScoreAggregate
+// 一段排名或者分数范围内元素的数量和分数之和
+// 分数是浮点数时, 分数之和是插入、删除、更新时增量维护的, 可能有很小的舍入误差
+type ScoreAggregate struct {
+	Count int64
+	Sum   float64
+}
+
+// Avg
+// 平均分数, 没有元素时返回0
+func (a ScoreAggregate) Avg() float64 {
+	if a.Count == 0 {
+		return 0
+	}
+	return a.Sum / float64(a.Count)
+}
+
+// prefixByRank
+// 排名 <= rank 的所有结点的分数之和
+func (list *ScoredSkipList[K, S, V]) prefixByRank(rank int64) float64 {
+	tRank := int64(0)
+	sum := float64(0)
+	t := list.head
+	for i := list.level - 1; i >= 0 && tRank < rank; i-- {
+		for t.Next(i) != nil && tRank+t.Span(i) <= rank {
+			tRank += t.Span(i)
+			sum += t.Sum(i)
+			t = t.Next(i)
+		}
+	}
+	return sum
+}
+
+// prefixWhile
+// 从头开始连续满足 pred 的结点的数量、分数之和和不同分数的数量, pred 必须满足 从头开始连续为true, 之后都为false
+func (list *ScoredSkipList[K, S, V]) prefixWhile(pred func(score S) bool) (int64, float64, int64) {
+	rank := int64(0)
+	sum := float64(0)
+	groups := int64(0)
+	t := list.head
+	for i := list.level - 1; i >= 0; i-- {
+		for t.Next(i) != nil && pred(t.Next(i).score) {
+			rank += t.Span(i)
+			sum += t.Sum(i)
+			groups += t.level[i].groups
+			t = t.Next(i)
+		}
+	}
+	return rank, sum, groups
+}
+
+// AggregateByRank
+// 排名在 [left,right] 内的元素的数量和分数之和, 复杂度 O(log n), 范围的规则和 GetValuesByRank 相同
+func (list *ScoredSkipList[K, S, V]) AggregateByRank(left, right int64) ScoreAggregate {
+	if list.Size() == 0 || left <= 0 || right <= 0 || right < left || left > list.Size() {
+		return ScoreAggregate{}
+	}
+	if right > list.Size() {
+		right = list.Size()
+	}
+	return ScoreAggregate{
+		Count: right - left + 1,
+		Sum:   list.prefixByRank(right) - list.prefixByRank(left-1),
+	}
+}
+
+// AggregateByScore
+// 分数范围内的元素的数量和分数之和, 复杂度 O(log n)
+func (list *ScoredSkipList[K, S, V]) AggregateByScore(findRange *ScoreRange[S]) ScoreAggregate {
+	if findRange == nil || list.Size() == 0 {
+		return ScoreAggregate{}
+	}
+	//范围开始之前的结点和范围结束之前(含)的结点, 两者的差就是范围内的结点
+	beforeRank, beforeSum, _ := list.prefixWhile(func(score S) bool {
+		return list.beforeStart(score, findRange)
+	})
+	endRank, endSum, _ := list.prefixWhile(func(score S) bool {
+		return !list.afterEnd(score, findRange)
+	})
+	if endRank <= beforeRank {
+		return ScoreAggregate{}
+	}
+	return ScoreAggregate{
+		Count: endRank - beforeRank,
+		Sum:   endSum - beforeSum,
+	}
+}
+
+// AggregateByRank
+// 通过索引区间(和 Range 的规则相同)返回区间内元素的数量和分数之和, 比如前100名的总分
+func (set *ScoredSortSet[K, S, V]) AggregateByRank(min, max int64) ScoreAggregate {
+	if set.enc.count() == 0 {
+		return ScoreAggregate{}
+	}
+	//处理范围时负数的情况
+	if min < 0 {
+		min = set.enc.count() + min
+	}
+	if max < 0 {
+		max = set.enc.count() + max
+	}
+	//给定的范围出错了
+	if min > max {
+		return ScoreAggregate{}
+	}
+	//索引是从0开始的, 跳表中的rank是从1开始,所以这里要 +1
+	return set.enc.aggregateByRank(min+1, max+1)
+}
+
+// AggregateByScore
+// 返回分数区间内元素的数量和分数之和
+func (set *ScoredSortSet[K, S, V]) AggregateByScore(findRange *ScoreRange[S]) ScoreAggregate {
+	if findRange == nil || set.enc.count() == 0 {
+		return ScoreAggregate{}
+	}
+	return set.enc.aggregateByScore(findRange)
+}
+
+// AggregateByRank
+// 返回快照中索引区间内元素的数量和分数之和
+func (snap *ScoredSortSetSnapshot[K, S, V]) AggregateByRank(min, max int64) ScoreAggregate {
+	return snap.set.AggregateByRank(min, max)
+}
+
+// AggregateByScore
+// 返回快照中分数区间内元素的数量和分数之和
+func (snap *ScoredSortSetSnapshot[K, S, V]) AggregateByScore(findRange *ScoreRange[S]) ScoreAggregate {
+	return snap.set.AggregateByScore(findRange)
+}
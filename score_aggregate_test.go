@@ -0,0 +1,164 @@
+package skiptablev2
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 检查每个结点每一层的分数之和
+func checkSkipListSums(t *testing.T, st *ScoredSkipList[string, float64, *S1[string]]) {
+	t.Helper()
+	for x := st.head; x != nil; x = x.Next(0) {
+		for i := 0; i < len(x.level) && i < st.level; i++ {
+			want := float64(0)
+			groups := int64(0)
+			for y := x.Next(0); y != nil; y = y.Next(0) {
+				want += y.score
+				if y.Pre() == nil || y.Pre().score != y.score {
+					groups++
+				}
+				if y == x.Next(i) {
+					break
+				}
+			}
+			if x.Sum(i) != want {
+				t.Fatalf("node:%v level:%d sum:%f want:%f", x.value, i, x.Sum(i), want)
+			}
+			if x.level[i].groups != groups {
+				t.Fatalf("node:%v level:%d groups:%d want:%d", x.value, i, x.level[i].groups, groups)
+			}
+		}
+	}
+	groups := int64(0)
+	for x := st.head.Next(0); x != nil; x = x.Next(0) {
+		if x.Pre() == nil || x.Pre().score != x.score {
+			groups++
+		}
+	}
+	if st.groups != groups {
+		t.Fatalf("groups:%d want:%d", st.groups, groups)
+	}
+}
+
+func TestSkipList_Aggregate(t *testing.T) {
+	st, err := NewDefaultSkipTable[string, *S1[string]](func(v1, v2 *S1[string]) int {
+		if v1.key < v2.key {
+			return -1
+		} else if v1.key > v2.key {
+			return 1
+		}
+		return 0
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	const N = 500
+	nodes := make(map[string]*SkipListNode[string, *S1[string]])
+	for i := 0; i < N*4; i++ {
+		key := string(rune('a' + rand.Intn(N)))
+		//分数都是整数, 分数之和没有误差
+		score := float64(rand.Intn(100))
+		node, ok := nodes[key]
+		switch {
+		case !ok:
+			nodes[key] = st.InsertByScore(score, &S1[string]{key: key, f: score})
+		case rand.Intn(3) == 0:
+			st.Delete(node, st.GetUpdateList(node))
+			delete(nodes, key)
+		default:
+			st.UpdateScore(node, score)
+		}
+	}
+	checkSkipListSums(t, st.ScoredSkipList)
+	checkSkipListSums(t, st.clone())
+
+	all := st.GetNodesByRank(1, st.Size())
+	for i := 0; i < 100; i++ {
+		l := rand.Int63n(st.Size()+2) - 1
+		r := l + rand.Int63n(st.Size())
+		want := ScoreAggregate{}
+		for rank := max(l, 1); rank <= min(r, st.Size()) && l > 0; rank++ {
+			want.Count++
+			want.Sum += all[rank-1].score
+		}
+		if got := st.AggregateByRank(l, r); got != want {
+			t.Fatalf("AggregateByRank(%d,%d) %v != %v", l, r, got, want)
+		}
+
+		findRange := &SkipListFindRange{
+			Min:    float64(rand.Intn(110) - 5),
+			Max:    float64(rand.Intn(110) - 5),
+			MinInf: rand.Intn(4) == 0,
+			MaxInf: rand.Intn(4) == 0,
+		}
+		want = ScoreAggregate{}
+		for _, node := range all {
+			if (findRange.MinInf || node.score >= findRange.Min) && (findRange.MaxInf || node.score <= findRange.Max) {
+				want.Count++
+				want.Sum += node.score
+			}
+		}
+		if got := st.AggregateByScore(findRange); got != want {
+			t.Fatalf("AggregateByScore(%v) %v != %v", findRange, got, want)
+		}
+		if want.Count > 0 && st.AggregateByScore(findRange).Avg() != want.Sum/float64(want.Count) {
+			t.Fatalf("Avg error")
+		}
+	}
+
+	//线性构建的跳表
+	c := st.clone()
+	checkSkipListSums(t, c)
+	if got, want := c.AggregateByRank(1, c.Size()), st.AggregateByRank(1, st.Size()); got != want {
+		t.Fatalf("clone sum %v != %v", got, want)
+	}
+}
+
+// Millis 自定义的分数类型, 分数之和按底层类型计算
+type Millis int64
+
+type millisItem struct {
+	key   string
+	score Millis
+}
+
+func (m *millisItem) Key() string {
+	return m.key
+}
+
+func (m *millisItem) Score() Millis {
+	return m.score
+}
+
+func TestSkipList_AggregateNamedScore(t *testing.T) {
+	st, err := NewScoredSkipTableWithOrder[string, Millis, *millisItem](SKIP_TABLE_DEFAULT_MAX_LEVEL, SCORE_ORDER_ASC, func(v1, v2 *millisItem) int {
+		return strings.Compare(v1.key, v2.key)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 100; i++ {
+		st.InsertByScore(Millis(i), &millisItem{key: strconv.Itoa(i), score: Millis(i)})
+	}
+	if a := st.AggregateByRank(1, 100); a.Count != 100 || a.Sum != 5050 {
+		t.Fatalf("AggregateByRank:%+v", a)
+	}
+	if a := st.AggregateByScore(&ScoreRange[Millis]{Min: 11, Max: 20}); a.Count != 10 || a.Sum != 155 {
+		t.Fatalf("AggregateByScore:%+v", a)
+	}
+	if err = st.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if f := scoreFloat(2 * time.Millisecond); f != float64(2*time.Millisecond) {
+		t.Fatalf("scoreFloat(time.Duration):%f", f)
+	}
+	type level uint8
+	type ratio float32
+	if scoreFloat(level(3)) != 3 || scoreFloat(ratio(0.5)) != 0.5 || scoreFloat("a") != 0 {
+		t.Fatal("scoreFloat named types")
+	}
+}
@@ -0,0 +1,117 @@
+package skiptablev2
+
+import (
+	"cmp"
+	"fmt"
+)
+
+// ScoredSkipListHandle
+// 跳表中一个元素的句柄, 用来代替直接持有 *ScoredSkipListNode
+// 句柄记录了结点和创建句柄时结点的代数, 元素被删除(包括结点被内存池复用)后句柄就过期了,
+// 通过过期的句柄修改跳表会返回错误, 不会破坏跳表; 句柄中还记录了 key, 过期后可以用 Refind 重新找到这个元素
+// 句柄是值类型, 可以直接复制和比较, 零值是无效的句柄; 句柄只能在元素当前所在的跳表上使用,
+// SplitAtRank、SplitAtScore、Concat 把元素移到另一个跳表后, 句柄在原来的跳表上就无效了, 要在新的跳表上使用
+type ScoredSkipListHandle[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
+	node *ScoredSkipListNode[K, S, V]
+	gen  uint64
+	key  K
+}
+
+// Key
+// 句柄对应元素的 key, 句柄过期后仍然可以使用
+func (h ScoredSkipListHandle[K, S, V]) Key() K {
+	return h.key
+}
+
+// HandleOf
+// 获取结点的句柄, 结点是 nil 或者不在这个跳表中时返回无效的句柄
+func (list *ScoredSkipList[K, S, V]) HandleOf(node *ScoredSkipListNode[K, S, V]) ScoredSkipListHandle[K, S, V] {
+	if node == nil || node == list.head || node.owner != list.id {
+		return ScoredSkipListHandle[K, S, V]{}
+	}
+	node.checkFreed()
+	return ScoredSkipListHandle[K, S, V]{node: node, gen: node.gen, key: node.value.Key()}
+}
+
+// InsertHandle
+// 和 Insert 相同, 返回插入的元素的句柄
+func (list *ScoredSkipList[K, S, V]) InsertHandle(score S, value V) (ScoredSkipListHandle[K, S, V], error) {
+	node, err := list.Insert(score, value)
+	if err != nil {
+		return ScoredSkipListHandle[K, S, V]{}, err
+	}
+	return list.HandleOf(node), nil
+}
+
+// Valid
+// 句柄对应的元素是否还在这个跳表中, 复杂度 O(1)
+func (list *ScoredSkipList[K, S, V]) Valid(h ScoredSkipListHandle[K, S, V]) bool {
+	return h.node != nil && h.node.gen == h.gen && !h.node.freed && h.node.owner == list.id
+}
+
+// Refind
+// 句柄没有过期时直接返回, 过期时根据 key 重新查找元素(比如元素被删除后又重新插入了), 元素不存在返回 false
+// 调用过 EnableUniqueKeys 时复杂度 O(1), 否则句柄过期时需要遍历第0层
+func (list *ScoredSkipList[K, S, V]) Refind(h ScoredSkipListHandle[K, S, V]) (ScoredSkipListHandle[K, S, V], bool) {
+	if list.Valid(h) {
+		return h, true
+	}
+	node, ok := list.GetNodeByKey(h.key)
+	if !ok {
+		return ScoredSkipListHandle[K, S, V]{}, false
+	}
+	return list.HandleOf(node), true
+}
+
+// ValueByHandle
+// 句柄对应的元素和它的分数, 句柄过期时返回 false
+func (list *ScoredSkipList[K, S, V]) ValueByHandle(h ScoredSkipListHandle[K, S, V]) (V, S, bool) {
+	if !list.Valid(h) {
+		var v V
+		var score S
+		return v, score, false
+	}
+	return h.node.value, h.node.score, true
+}
+
+// RankByHandle
+// 句柄对应的元素的排名(从1开始), 句柄过期时返回错误
+func (list *ScoredSkipList[K, S, V]) RankByHandle(h ScoredSkipListHandle[K, S, V]) (int64, error) {
+	if !list.Valid(h) {
+		return 0, fmt.Errorf("RankByHandle handle of key %v is stale", h.key)
+	}
+	return list.GetNodeRank(h.node), nil
+}
+
+// UpdateScoreByHandle
+// 更新句柄对应的元素的分数, 句柄过期或者分数是 NaN 时返回错误, 更新后句柄仍然有效
+func (list *ScoredSkipList[K, S, V]) UpdateScoreByHandle(h ScoredSkipListHandle[K, S, V], score S) error {
+	if !list.Valid(h) {
+		return fmt.Errorf("UpdateScoreByHandle handle of key %v is stale", h.key)
+	}
+	return list.SetScore(h.node, score)
+}
+
+// DeleteByHandle
+// 删除句柄对应的元素, 句柄过期时返回错误, 删除后句柄和它的所有副本都会过期
+func (list *ScoredSkipList[K, S, V]) DeleteByHandle(h ScoredSkipListHandle[K, S, V]) error {
+	if !list.Valid(h) {
+		return fmt.Errorf("DeleteByHandle handle of key %v is stale", h.key)
+	}
+	list.Delete(h.node, list.updateList(h.node))
+	return nil
+}
+
+// GetHandlesByRank
+// 和 GetNodesByRank 相同, 返回句柄
+func (list *ScoredSkipList[K, S, V]) GetHandlesByRank(left, right int64) []ScoredSkipListHandle[K, S, V] {
+	nodes := list.GetNodesByRank(left, right)
+	if len(nodes) == 0 {
+		return nil
+	}
+	result := make([]ScoredSkipListHandle[K, S, V], len(nodes))
+	for i, node := range nodes {
+		result[i] = list.HandleOf(node)
+	}
+	return result
+}
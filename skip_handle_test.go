@@ -0,0 +1,171 @@
+package skiptablev2
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSkipList_Handle(t *testing.T) {
+	st, err := NewDefaultSkipTable[string, *S1[string]](func(v1, v2 *S1[string]) int {
+		return strings.Compare(v1.key, v2.key)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//有内存池时, 删除的结点会被复用, 过期的句柄也不能操作复用的结点
+	st.SetNodePool(NewSkipListNodePool[string, *S1[string]](16, false))
+	if err = st.EnableUniqueKeys(); err != nil {
+		t.Fatal(err)
+	}
+	handles := make([]ScoredSkipListHandle[string, float64, *S1[string]], 0, 10)
+	for i := 0; i < 10; i++ {
+		h, err := st.InsertHandle(float64(i), &S1[string]{key: strconv.Itoa(i), f: float64(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		handles = append(handles, h)
+	}
+	var zero ScoredSkipListHandle[string, float64, *S1[string]]
+	if st.Valid(zero) {
+		t.Fatal("zero handle is valid")
+	}
+
+	h := handles[3]
+	if rank, err := st.RankByHandle(h); err != nil || rank != 4 {
+		t.Fatalf("RankByHandle:%d %v", rank, err)
+	}
+	if err = st.UpdateScoreByHandle(h, 20); err != nil {
+		t.Fatal(err)
+	}
+	//更新分数后句柄仍然有效
+	if v, score, ok := st.ValueByHandle(h); !ok || score != 20 || v.key != "3" {
+		t.Fatalf("ValueByHandle:%v %f %v", v, score, ok)
+	}
+	if err = st.DeleteByHandle(h); err != nil {
+		t.Fatal(err)
+	}
+	//内存池按层数复用结点, 一直插入到被删除的结点被复用为止
+	for i := 0; ; i++ {
+		reused, err := st.InsertHandle(100+float64(i), &S1[string]{key: "new" + strconv.Itoa(i), f: 100 + float64(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reused.node == h.node {
+			break
+		}
+		if i == 10000 {
+			t.Fatal("deleted node is not reused")
+		}
+	}
+	if st.Valid(h) {
+		t.Fatal("stale handle is valid")
+	}
+	if err = st.DeleteByHandle(h); err == nil {
+		t.Fatal("DeleteByHandle with stale handle")
+	}
+	if err = st.UpdateScoreByHandle(h, 1); err == nil {
+		t.Fatal("UpdateScoreByHandle with stale handle")
+	}
+	if _, err = st.RankByHandle(h); err == nil {
+		t.Fatal("RankByHandle with stale handle")
+	}
+	if _, _, ok := st.ValueByHandle(h); ok {
+		t.Fatal("ValueByHandle with stale handle")
+	}
+	if _, ok := st.Refind(h); ok {
+		t.Fatal("Refind deleted key")
+	}
+	if err = st.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	//重新插入后, 通过 key 可以重新找到
+	if _, err = st.Insert(3, &S1[string]{key: "3", f: 3}); err != nil {
+		t.Fatal(err)
+	}
+	found, ok := st.Refind(h)
+	if !ok || !st.Valid(found) || found.Key() != "3" {
+		t.Fatalf("Refind:%v %v", found, ok)
+	}
+	if rank, err := st.RankByHandle(found); err != nil || rank != 4 {
+		t.Fatalf("RankByHandle after Refind:%d %v", rank, err)
+	}
+
+	byRank := st.GetHandlesByRank(1, 3)
+	if len(byRank) != 3 || byRank[0] != handles[0] {
+		t.Fatalf("GetHandlesByRank:%v", byRank)
+	}
+	//直接使用已经删除的结点会panic, 不会破坏跳表
+	node := (*SkipListNode[string, *S1[string]])(handles[1].node)
+	st.Delete(node, st.GetUpdateList(node))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("UpdateScore with deleted node should panic")
+			}
+		}()
+		st.UpdateScore(node, 100)
+	}()
+	if err = st.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSkipList_HandleOwner
+// 句柄只能在元素所在的跳表上使用, 切开和拼接之后也一样
+func TestSkipList_HandleOwner(t *testing.T) {
+	st, _ := newSplitTestList(t, SCORE_ORDER_ASC, 100, false)
+	handles := st.GetHandlesByRank(1, st.Size())
+	right, err := st.SplitAtRank(50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved, kept := handles[70], handles[10]
+	if st.Valid(moved) || !right.Valid(moved) || !st.Valid(kept) || right.Valid(kept) {
+		t.Fatal("handle owner after SplitAtRank")
+	}
+	//在错误的跳表上使用句柄会返回错误, 两个跳表都不会被修改
+	if err = st.DeleteByHandle(moved); err == nil {
+		t.Fatal("DeleteByHandle on the wrong list")
+	}
+	if err = st.UpdateScoreByHandle(moved, -1); err == nil {
+		t.Fatal("UpdateScoreByHandle on the wrong list")
+	}
+	if _, err = right.RankByHandle(kept); err == nil {
+		t.Fatal("RankByHandle on the wrong list")
+	}
+	if h := st.ScoredSkipList.HandleOf(right.head.Next(0)); st.Valid(h) {
+		t.Fatal("HandleOf a node of another list")
+	}
+	if st.Size() != 50 || right.Size() != 50 {
+		t.Fatalf("size:%d %d", st.Size(), right.Size())
+	}
+	if err = st.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err = right.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	//接回去之后句柄又可以在原来的跳表上使用了
+	if err = st.Concat(right); err != nil {
+		t.Fatal(err)
+	}
+	if !st.Valid(moved) || right.Valid(moved) {
+		t.Fatal("handle owner after Concat")
+	}
+	if rank, err := st.RankByHandle(moved); err != nil || rank != 71 {
+		t.Fatalf("RankByHandle after Concat:%d %v", rank, err)
+	}
+	if err = st.DeleteByHandle(moved); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	//复制的跳表中的结点是新的, 原来的句柄不能使用
+	if st.clone().Valid(kept) {
+		t.Fatal("handle is valid on a clone")
+	}
+}
@@ -1,8 +1,13 @@
 package skiptablev2
 
 import (
+	"cmp"
 	"errors"
+	"fmt"
+	"math"
 	"math/rand"
+	"reflect"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,26 +24,96 @@ const (
 // 跳表加一层索引的概率
 var SKIPLIST_P = 0.25
 
-// SkipListFindRange
+// ScoreRange
 // 根据scores查找元素的条件
-type SkipListFindRange struct {
-	Min, Max       float64 //最大值和最小值
-	MinInf, MaxInf bool    //是否是正无穷和负无穷
+// 分数是浮点数时, -Inf 排在所有分数前面, +Inf 排在所有分数后面, Min 是 -Inf 和 MinInf 的效果相同;
+// Min 或者 Max 是 NaN 时范围是空的
+type ScoreRange[S cmp.Ordered] struct {
+	Min, Max       S    //最大值和最小值
+	MinInf, MaxInf bool //是否是正无穷和负无穷
+	MinEx, MaxEx   bool //是否不包含 Min 和 Max, 和 redis 的 "(1" 一样是开区间
+}
+
+// scoreOrder
+// 元素的排序规则: 先按分数排序, 分数相同时使用 compare, 从大到小排序时整体反过来
+type scoreOrder[S cmp.Ordered, V any] struct {
+	//两个 value score 相同时的比较函数
+	//return value
+	//value < 0 v1 < v2
+	//value == 0 v1 == v2
+	//value > 0 v1 > v2
+	//只有同一个元素才能返回0, 否则删除和查找排名时可能找到另一个分数相同的元素
+	compare func(v1, v2 V) int
+	//是否从大到小排序
+	desc bool
+}
+
+// less 分数 s1 是否排在 s2 前面
+func (o *scoreOrder[S, V]) less(s1, s2 S) bool {
+	if o.desc {
+		return s1 > s2
+	}
+	return s1 < s2
+}
+
+// before (s1,v1) 是否排在 (s2,v2) 前面
+func (o *scoreOrder[S, V]) before(s1 S, v1 V, s2 S, v2 V) bool {
+	if s1 != s2 {
+		return o.less(s1, s2)
+	}
+	if o.desc {
+		return o.compare(v2, v1) < 0
+	}
+	return o.compare(v1, v2) < 0
 }
 
-// SkipListItem
-// SkipList 泛型约束
-type SkipListItem[K comparable] interface {
+// beforeStart 分数 s 是否排在查找范围的开始之前
+// 从小到大排序时开始是 Min, 从大到小排序时开始是 Max
+func (o *scoreOrder[S, V]) beforeStart(s S, findRange *ScoreRange[S]) bool {
+	if o.desc {
+		return !findRange.MaxInf && (s > findRange.Max || (findRange.MaxEx && s == findRange.Max))
+	}
+	return !findRange.MinInf && (s < findRange.Min || (findRange.MinEx && s == findRange.Min))
+}
+
+// afterEnd 分数 s 是否排在查找范围的结束之后
+// 边界是 NaN 时所有分数都在结束之后, 范围是空的
+func (o *scoreOrder[S, V]) afterEnd(s S, findRange *ScoreRange[S]) bool {
+	if scoreIsNaN(findRange.Min) || scoreIsNaN(findRange.Max) {
+		return true
+	}
+	if o.desc {
+		return !findRange.MinInf && (s < findRange.Min || (findRange.MinEx && s == findRange.Min))
+	}
+	return !findRange.MaxInf && (s > findRange.Max || (findRange.MaxEx && s == findRange.Max))
+}
+
+// Order 排序方向
+func (o *scoreOrder[S, V]) Order() ScoreOrder {
+	if o.desc {
+		return SCORE_ORDER_DESC
+	}
+	return SCORE_ORDER_ASC
+}
+
+// SkipListFindRange
+// 分数是 float64 时的查找条件
+type SkipListFindRange = ScoreRange[float64]
+
+// ScoredItem
+// ScoredSkipList 泛型约束, S 是分数的类型
+type ScoredItem[K comparable, S cmp.Ordered] interface {
 	Key() K
-	Score() float64
+	Score() S
 }
 
-// SkipList
-// 跳表的定义
-type SkipList[K comparable, V SkipListItem[K]] struct {
+// ScoredSkipList
+// 跳表的定义, S 是分数的类型
+// int64 的毫秒时间戳或者超过 2^53 的id 用 float64 会丢失精度, 这时可以直接用 int64 作为分数
+type ScoredSkipList[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
 	//头结点和尾结点
 	//重点,头结点是一个真实存在的,尾结点只是一个指针
-	head, tail *SkipListNode[K, V]
+	head, tail *ScoredSkipListNode[K, S, V]
 
 	size int64 //node总数
 
@@ -46,14 +121,37 @@ type SkipList[K comparable, V SkipListItem[K]] struct {
 
 	maxLevel int //当前最大层数
 
-	//两个 value score 相同时的比较函数
-	//return value
-	//value < 0 v1 < v2
-	//value == 0 v1 == v2
-	//value > 0 v1 > v2
-	compare func(v1, v2 V) int
+	//排序规则, 默认从小到大, 也可以从大到小
+	scoreOrder[S, V]
+
+	//插入和删除时使用的临时缓冲区,每次操作复用,避免每次都分配内存
+	rankBuf   []int64
+	updateBuf []*ScoredSkipListNode[K, S, V]
+
+	//结点的内存池,为nil时不回收结点
+	pool *ScoredSkipListNodePool[K, S, V]
+
+	//所有结点分数的和
+	sum float64
+	//插入时各层到 update 结点的分数之和, 和 rankBuf 一样复用
+	sumBuf []float64
+
+	//不同分数的数量
+	groups int64
+	//插入时各层到 update 结点的不同分数的数量, 和 rankBuf 一样复用
+	groupBuf []int64
+
+	//key 到结点的索引, 调用 EnableUniqueKeys 后才有, 用来保证同一个 key 只出现一次
+	keys map[K]*ScoredSkipListNode[K, S, V]
+
+	//跳表的编号, 每个跳表都不同, 结点记录所属跳表的编号, 用来检查句柄是不是在创建它的跳表上使用
+	id uint64
 }
 
+// skipListID
+// 分配跳表编号的计数器
+var skipListID atomic.Uint64
+
 // NewDefaultSkipTable
 // 初始化一个默认的跳表
 func NewDefaultSkipTable[K comparable, V SkipListItem[K]](compare func(v1, v2 V) int) (*SkipList[K, V], error) {
@@ -63,6 +161,30 @@ func NewDefaultSkipTable[K comparable, V SkipListItem[K]](compare func(v1, v2 V)
 // NewSkipTable
 // 初始化一个自定义最大层数的跳表
 func NewSkipTable[K comparable, V SkipListItem[K]](maxLevel int, compare func(v1, v2 V) int) (*SkipList[K, V], error) {
+	return NewSkipTableWithOrder[K, V](maxLevel, SCORE_ORDER_ASC, compare)
+}
+
+// NewSkipTableWithOrder
+// 初始化一个自定义最大层数和排序方向的跳表
+func NewSkipTableWithOrder[K comparable, V SkipListItem[K]](maxLevel int, order ScoreOrder, compare func(v1, v2 V) int) (*SkipList[K, V], error) {
+	list, err := NewScoredSkipTableWithOrder[K, float64, V](maxLevel, order, compare)
+	if err != nil {
+		return nil, err
+	}
+	return &SkipList[K, V]{list}, nil
+}
+
+// NewScoredSkipTable
+// 初始化一个自定义分数类型和最大层数的跳表
+func NewScoredSkipTable[K comparable, S cmp.Ordered, V ScoredItem[K, S]](maxLevel int, compare func(v1, v2 V) int) (*ScoredSkipList[K, S, V], error) {
+	return NewScoredSkipTableWithOrder[K, S, V](maxLevel, SCORE_ORDER_ASC, compare)
+}
+
+// NewScoredSkipTableWithOrder
+// 初始化一个自定义分数类型、最大层数和排序方向的跳表
+// 排序方向是 SCORE_ORDER_DESC 时, 跳表按分数从大到小排列, 排名第1的是分数最大的元素,
+// 取前N名只需要从头向后遍历一次
+func NewScoredSkipTableWithOrder[K comparable, S cmp.Ordered, V ScoredItem[K, S]](maxLevel int, order ScoreOrder, compare func(v1, v2 V) int) (*ScoredSkipList[K, S, V], error) {
 	if compare == nil {
 		return nil, errors.New("NewSkipTable compare function is nil")
 	}
@@ -71,17 +193,90 @@ func NewSkipTable[K comparable, V SkipListItem[K]](maxLevel int, compare func(v1
 	}
 	rand.Seed(time.Now().UnixNano())
 	var v V
-	return &SkipList[K, V]{
-		head:     NewSkipListNode[K, V](maxLevel, 0, v),
+	var score S
+	return &ScoredSkipList[K, S, V]{
+		head:     NewScoredSkipListNode[K, S, V](maxLevel, score, v),
 		size:     0,
 		level:    1,
 		maxLevel: maxLevel,
-		compare:  compare,
+		scoreOrder: scoreOrder[S, V]{
+			compare: compare,
+			desc:    order == SCORE_ORDER_DESC,
+		},
+		rankBuf:   make([]int64, maxLevel),
+		sumBuf:    make([]float64, maxLevel),
+		groupBuf:  make([]int64, maxLevel),
+		updateBuf: make([]*ScoredSkipListNode[K, S, V], maxLevel),
+		id:        skipListID.Add(1),
 	}, nil
 }
 
+// scoreFloat
+// 把分数转换成 float64, 用来计算分数的和, 分数不是数字(比如 string)时返回0
+// 内置类型直接转换, 自定义的类型(比如 time.Duration、type Millis int64)按底层类型转换
+func scoreFloat[S cmp.Ordered](score S) float64 {
+	switch s := any(score).(type) {
+	case float64:
+		return s
+	case float32:
+		return float64(s)
+	case int:
+		return float64(s)
+	case int8:
+		return float64(s)
+	case int16:
+		return float64(s)
+	case int32:
+		return float64(s)
+	case int64:
+		return float64(s)
+	case uint:
+		return float64(s)
+	case uint8:
+		return float64(s)
+	case uint16:
+		return float64(s)
+	case uint32:
+		return float64(s)
+	case uint64:
+		return float64(s)
+	case uintptr:
+		return float64(s)
+	case string:
+		return 0
+	}
+	v := reflect.ValueOf(score)
+	switch {
+	case v.CanInt():
+		return float64(v.Int())
+	case v.CanUint():
+		return float64(v.Uint())
+	case v.CanFloat():
+		return v.Float()
+	default:
+		return 0
+	}
+}
+
+// scoreIsNaN
+// 分数是不是 NaN, NaN 和任何分数比较都是 false, 会破坏跳表的顺序, 不能插入跳表
+func scoreIsNaN[S cmp.Ordered](score S) bool {
+	return score != score
+}
+
+// sumFloat
+// 计算分数之和时使用的值, ±Inf 不计入分数之和
+// 分数之和是增量维护的, 如果计入 ±Inf, 删除时 Inf-Inf 会得到 NaN, 之后所有的和都是 NaN
+func sumFloat[S cmp.Ordered](score S) float64 {
+	f := scoreFloat(score)
+	if math.IsInf(f, 0) {
+		return 0
+	}
+	return f
+}
+
 // 随机索引的层数
-func (list *SkipList[K, V]) randLevel() int {
+func (list *ScoredSkipList[K, S, V]) randLevel() int {
 	level := 1
 	for (rand.Uint32()&0xFFFF) < uint32(0xFFFF*SKIPLIST_P) && level < list.maxLevel {
 		level++
@@ -89,56 +284,166 @@ func (list *SkipList[K, V]) randLevel() int {
 	return level
 }
 
+// SetNodePool
+// 设置结点的内存池, 删除的结点会回收到内存池中, 插入时优先从内存池中获取结点
+// 设置为 nil 时不回收结点
+func (list *ScoredSkipList[K, S, V]) SetNodePool(pool *ScoredSkipListNodePool[K, S, V]) {
+	list.pool = pool
+}
+
+// 分配一个新结点, 有内存池时从内存池中获取
+func (list *ScoredSkipList[K, S, V]) newNode(level int, score S, value V) *ScoredSkipListNode[K, S, V] {
+	var node *ScoredSkipListNode[K, S, V]
+	if list.pool != nil {
+		node = list.pool.get(level, score, value)
+	} else {
+		node = NewScoredSkipListNode[K, S, V](level, score, value)
+	}
+	node.owner = list.id
+	return node
+}
+
 // Size
 // 条表中的结点数量
-func (list *SkipList[K, V]) Size() int64 {
+func (list *ScoredSkipList[K, S, V]) Size() int64 {
 	return list.size
 }
 
 // InsertByScore
-// 插入一个结点
-func (list *SkipList[K, V]) InsertByScore(score float64, value V) *SkipListNode[K, V] {
-	rank := make([]int64, list.maxLevel)
-	update := make([]*SkipListNode[K, V], list.maxLevel)
+// 插入一个结点, 不检查分数和 key, 调用者需要保证分数不是 NaN, 需要检查时使用 Insert
+// 返回的结点被删除后不能再使用, 需要长期持有时使用 InsertHandle
+func (list *ScoredSkipList[K, S, V]) InsertByScore(score S, value V) *ScoredSkipListNode[K, S, V] {
+	newNode := list.newNode(list.randLevel(), score, value)
+	list.insertNode(newNode)
+	if list.keys != nil {
+		list.keys[value.Key()] = newNode
+	}
+	return newNode
+}
+
+// Insert
+// 插入一个结点, 分数是 NaN 时返回错误; 调用过 EnableUniqueKeys 时, key 已经存在也返回错误
+// 出错时跳表不会被修改
+func (list *ScoredSkipList[K, S, V]) Insert(score S, value V) (*ScoredSkipListNode[K, S, V], error) {
+	if scoreIsNaN(score) {
+		return nil, fmt.Errorf("Insert score of key %v is NaN", value.Key())
+	}
+	if list.keys != nil {
+		if _, ok := list.keys[value.Key()]; ok {
+			return nil, fmt.Errorf("Insert key %v already exists", value.Key())
+		}
+	}
+	return list.InsertByScore(score, value), nil
+}
+
+// EnableUniqueKeys
+// 给跳表加上 key 到结点的索引, 之后 Insert 和 BulkLoad 会拒绝已经存在的 key, GetNodeByKey 的复杂度变成 O(1)
+// 跳表中已经有重复的 key 时返回错误, 此时不会加上索引
+func (list *ScoredSkipList[K, S, V]) EnableUniqueKeys() error {
+	if list.keys != nil {
+		return nil
+	}
+	keys := make(map[K]*ScoredSkipListNode[K, S, V], list.size)
+	for t := list.head.Next(0); t != nil; t = t.Next(0) {
+		if _, ok := keys[t.value.Key()]; ok {
+			return fmt.Errorf("EnableUniqueKeys key %v is duplicated", t.value.Key())
+		}
+		keys[t.value.Key()] = t
+	}
+	list.keys = keys
+	return nil
+}
+
+// GetNodeByKey
+// 根据 key 查找结点, 调用过 EnableUniqueKeys 时复杂度 O(1), 否则需要遍历第0层, 复杂度 O(n)
+func (list *ScoredSkipList[K, S, V]) GetNodeByKey(key K) (*ScoredSkipListNode[K, S, V], bool) {
+	if list.keys != nil {
+		node, ok := list.keys[key]
+		return node, ok
+	}
+	for t := list.head.Next(0); t != nil; t = t.Next(0) {
+		if t.value.Key() == key {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// insertNode
+// 把一个已经分配好层数的结点插入跳表, 使用跳表的临时缓冲区, 不会分配内存
+func (list *ScoredSkipList[K, S, V]) insertNode(newNode *ScoredSkipListNode[K, S, V]) {
+	rank := list.rankBuf
+	sum := list.sumBuf
+	groups := list.groupBuf
+	update := list.updateBuf
+	score, value := newNode.score, newNode.value
 	t := list.head
 	for i := list.level - 1; i >= 0; i-- {
 		if i == list.level-1 {
 			rank[i] = 0
+			sum[i] = 0
+			groups[i] = 0
 		} else {
 			rank[i] = rank[i+1]
+			sum[i] = sum[i+1]
+			groups[i] = groups[i+1]
 		}
-		//当前层的下一个结点存在 && (下一个结点score<score || 当score相同时,比较这两个结点,下一个结点<新插入的结点)
-		for t.Next(i) != nil && (t.Next(i).score < score || (t.Next(i).score == value.Score() && list.compare(t.Next(i).value, value) < 0)) {
+		//当前层的下一个结点存在 && 下一个结点排在新插入的结点前面(先比较score, score相同时用compare比较)
+		for t.Next(i) != nil && list.before(t.Next(i).score, t.Next(i).value, score, value) {
 			rank[i] += t.level[i].span
+			sum[i] += t.level[i].sum
+			groups[i] += t.level[i].groups
 			t = t.Next(i)
 		}
 		update[i] = t
 	}
+	f := sumFloat(score)
+	g := list.groupStart(update[0], score)
 
-	level := list.randLevel()
+	level := len(newNode.level)
 
 	if level > list.level {
 		//处理rand level后, level>当前level后的情况
 		for i := list.level; i < level; i++ {
 			rank[i] = 0
+			sum[i] = 0
+			groups[i] = 0
 			update[i] = list.head
-			update[i].SetSpan(i, list.size)
+			update[i].setSpan(i, list.size)
+			update[i].level[i].sum = list.sum
+			update[i].level[i].groups = list.groups
 		}
 		list.level = level
 	}
-	newNode := NewSkipListNode[K, V](level, score, value)
 
 	for i := 0; i < level; i++ {
-		newNode.SetNext(i, update[i].Next(i))
-		update[i].SetNext(i, newNode)
+		newNode.setNext(i, update[i].Next(i))
+		update[i].setNext(i, newNode)
+
+		newNode.setSpan(i, update[i].Span(i)-(rank[0]-rank[i]))
+		update[i].setSpan(i, rank[0]-rank[i]+1)
+
+		newNode.level[i].sum = update[i].level[i].sum - (sum[0] - sum[i])
+		update[i].level[i].sum = sum[0] - sum[i] + f
 
-		newNode.SetSpan(i, update[i].Span(i)-(rank[0]-rank[i]))
-		update[i].SetSpan(i, rank[0]-rank[i]+1)
+		newNode.level[i].groups = update[i].level[i].groups - (groups[0] - groups[i])
+		update[i].level[i].groups = groups[0] - groups[i] + g
 	}
 
 	//处理新增结点的span
 	for i := level; i < list.level; i++ {
 		update[i].level[i].span++
+		update[i].level[i].sum += f
+		update[i].level[i].groups += g
+	}
+	list.sum += f
+	list.groups += g
+
+	//新结点插在 update[0] 和 next 中间, next 是不是一组相同分数的第一个结点可能会变
+	if next := newNode.Next(0); next != nil {
+		delta := list.groupStart(newNode, next.score) - list.groupStart(update[0], next.score)
+		//next 在 level 以下的层属于新结点的span, 以上的层属于 update[i] 的span
+		list.addGroups(update, newNode, delta)
 	}
 	//处理新节点的后退指针
 	if update[0] == list.head {
@@ -155,44 +460,234 @@ func (list *SkipList[K, V]) InsertByScore(score float64, value V) *SkipListNode[
 		list.tail = newNode
 	}
 	list.size++
-	return newNode
 }
 
-// UpdateScore
-// 更新结点的score
-func (list *SkipList[K, V]) UpdateScore(node *SkipListNode[K, V], score float64) {
-	if score == node.score {
+// groupStart
+// 分数是 score 的结点排在 pre 后面时, 是不是一组相同分数的第一个结点, 是返回1, 不是返回0
+func (list *ScoredSkipList[K, S, V]) groupStart(pre *ScoredSkipListNode[K, S, V], score S) int64 {
+	if pre == nil || pre == list.head || pre.score != score {
+		return 1
+	}
+	return 0
+}
+
+// addGroups
+// 结点 pre 的下一个结点(第0层)是不是一组相同分数的第一个结点变了, 修改包含它的各层span的不同分数的数量
+// update 是 pre 的各层前驱(pre 自己在第0层), pre 有的层属于 pre 的span, 更高的层属于 update[i] 的span
+func (list *ScoredSkipList[K, S, V]) addGroups(update []*ScoredSkipListNode[K, S, V], pre *ScoredSkipListNode[K, S, V], delta int64) {
+	if delta == 0 {
 		return
 	}
-	//更新后,分数还是 < next node的位置不用变
-	if score > node.score {
-		if node.Next(0) != nil && score < node.Next(0).score {
-			node.score = score
-			return
+	for i := 0; i < list.level; i++ {
+		if i < len(pre.level) {
+			pre.level[i].groups += delta
+		} else {
+			update[i].level[i].groups += delta
 		}
 	}
+	list.groups += delta
+}
 
-	//更新后,分数还是 > per node的位置不用变
-	if score < node.score {
-		if node.Pre() != nil && score > node.Pre().score {
-			node.score = score
-			return
+// BulkLoad
+// 使用已经按跳表的排序规则排好序(score 从小到大, score 相同时按 compare 从小到大; 从大到小的跳表反过来)的数据一次性构建跳表
+// 只需要一次线性遍历, 复杂度 O(n), 比逐个 InsertByScore 快得多
+// 只能在空跳表上使用, 数据没有排好序时返回错误, 此时跳表不会被修改
+func (list *ScoredSkipList[K, S, V]) BulkLoad(items []V) error {
+	if list.size != 0 {
+		return errors.New("BulkLoad skip list is not empty")
+	}
+	for i, item := range items {
+		if scoreIsNaN(item.Score()) {
+			return fmt.Errorf("BulkLoad score of key %v at index %d is NaN", item.Key(), i)
+		}
+		if i > 0 && list.before(item.Score(), item, items[i-1].Score(), items[i-1]) {
+			return fmt.Errorf("BulkLoad items are not sorted at index %d", i)
+		}
+	}
+	if list.keys != nil {
+		keys := make(map[K]struct{}, len(items))
+		for i, item := range items {
+			if _, ok := keys[item.Key()]; ok {
+				return fmt.Errorf("BulkLoad key %v at index %d is duplicated", item.Key(), i)
+			}
+			keys[item.Key()] = struct{}{}
+		}
+	}
+
+	b := list.newBuilder()
+	for _, item := range items {
+		b.append(list.randLevel(), item.Score(), item)
+	}
+	b.finish()
+	return nil
+}
+
+// skipListBuilder
+// 按顺序在空跳表的末尾追加结点, 用于线性时间构建跳表
+type skipListBuilder[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
+	list *ScoredSkipList[K, S, V]
+	//每一层当前的最后一个结点和它的排名,新结点直接接在它后面
+	last     []*ScoredSkipListNode[K, S, V]
+	lastRank []int64
+	//每一层当前的最后一个结点之前(含)的分数之和
+	lastSum []float64
+	//每一层当前的最后一个结点之前(含)的不同分数的数量
+	lastGroups []int64
+	//最后一个结点
+	pre *ScoredSkipListNode[K, S, V]
+}
+
+// newBuilder
+// 在空跳表上创建一个构建器
+func (list *ScoredSkipList[K, S, V]) newBuilder() *skipListBuilder[K, S, V] {
+	b := &skipListBuilder[K, S, V]{
+		list:       list,
+		last:       make([]*ScoredSkipListNode[K, S, V], list.maxLevel),
+		lastRank:   make([]int64, list.maxLevel),
+		lastSum:    make([]float64, list.maxLevel),
+		lastGroups: make([]int64, list.maxLevel),
+	}
+	for i := 0; i < list.maxLevel; i++ {
+		b.last[i] = list.head
+	}
+	return b
+}
+
+// append
+// 在跳表末尾追加一个结点, 调用者需要保证追加的顺序是有序的
+func (b *skipListBuilder[K, S, V]) append(level int, score S, value V) *ScoredSkipListNode[K, S, V] {
+	node := b.list.newNode(level, score, value)
+	b.appendNode(node)
+	return node
+}
+
+// appendNode
+// 在跳表末尾追加一个已经分配好层数的结点
+func (b *skipListBuilder[K, S, V]) appendNode(node *ScoredSkipListNode[K, S, V]) {
+	list := b.list
+	level := len(node.level)
+	list.size++
+	if level > list.level {
+		list.level = level
+	}
+	list.sum += sumFloat(node.score)
+	list.groups += list.groupStart(b.pre, node.score)
+	if list.keys != nil {
+		list.keys[node.value.Key()] = node
+	}
+	for i := 0; i < level; i++ {
+		b.last[i].setNext(i, node)
+		b.last[i].setSpan(i, list.size-b.lastRank[i])
+		b.last[i].level[i].sum = list.sum - b.lastSum[i]
+		b.last[i].level[i].groups = list.groups - b.lastGroups[i]
+		b.last[i] = node
+		b.lastRank[i] = list.size
+		b.lastSum[i] = list.sum
+		b.lastGroups[i] = list.groups
+	}
+	node.backward = b.pre
+	b.pre = node
+}
+
+// finish
+// 结束构建, 处理每一层最后一个结点的span(到结尾的距离)和tail指针
+func (b *skipListBuilder[K, S, V]) finish() {
+	list := b.list
+	for i := 0; i < list.maxLevel; i++ {
+		b.last[i].setSpan(i, list.size-b.lastRank[i])
+		b.last[i].level[i].sum = list.sum - b.lastSum[i]
+		b.last[i].level[i].groups = list.groups - b.lastGroups[i]
+	}
+	list.tail = b.pre
+}
+
+// reset
+// 清空跳表, 原来的结点都标记成已经删除
+func (list *ScoredSkipList[K, S, V]) reset() {
+	for t := list.head.Next(0); t != nil; t = t.Next(0) {
+		t.gen++
+	}
+	list.detach()
+}
+
+// detach
+// 清空跳表, 原来的结点不做任何处理, 用于结点已经被移到另一个跳表的情况
+func (list *ScoredSkipList[K, S, V]) detach() {
+	var v V
+	var score S
+	list.head = NewScoredSkipListNode[K, S, V](list.maxLevel, score, v)
+	list.tail = nil
+	list.size = 0
+	list.level = 1
+	list.sum = 0
+	list.groups = 0
+	if list.keys != nil {
+		list.keys = make(map[K]*ScoredSkipListNode[K, S, V])
+	}
+}
+
+// UpdateScore
+// 更新结点的score, 不检查分数, 调用者需要保证分数不是 NaN, 需要检查时使用 SetScore
+func (list *ScoredSkipList[K, S, V]) UpdateScore(node *ScoredSkipListNode[K, S, V], score S) {
+	node.checkFreed()
+	if score == node.score {
+		return
+	}
+	//更新后,分数还是排在 pre node 和 next node 中间, 位置不用变, 只需要更新路径上各层的分数之和和不同分数的数量
+	if (node.Pre() == nil || list.less(node.Pre().score, score)) && (node.Next(0) == nil || list.less(score, node.Next(0).score)) {
+		delta := sumFloat(score) - sumFloat(node.score)
+		update := list.updateList(node)
+		for i := 0; i < list.level; i++ {
+			update[i].level[i].sum += delta
 		}
+		list.sum += delta
+		//新的分数和前后结点都不同, node 和 next 都变成了一组相同分数的第一个结点
+		list.addGroups(update, update[0], 1-list.groupStart(node.Pre(), node.score))
+		if next := node.Next(0); next != nil {
+			list.addGroups(update, node, 1-list.groupStart(node, next.score))
+		}
+		node.score = score
+		return
 	}
 	//删掉node,重新插入
-	updateList := list.GetUpdateList(node)
-	list.Delete(node, updateList)
-	//重新插入
-	list.InsertByScore(score, node.value)
+	//重新插入时复用原来的结点,这样持有这个结点的地方(比如sortSet的map)不需要更新,也不需要分配内存
+	list.unlink(node, list.updateList(node))
+	node.score = score
+	list.insertNode(node)
+}
+
+// SetScore
+// 更新结点的score, 分数是 NaN 时返回错误, 此时跳表不会被修改
+func (list *ScoredSkipList[K, S, V]) SetScore(node *ScoredSkipListNode[K, S, V], score S) error {
+	if scoreIsNaN(score) {
+		return fmt.Errorf("SetScore score of key %v is NaN", node.value.Key())
+	}
+	list.UpdateScore(node, score)
+	return nil
 }
 
 // GetUpdateList
 // 获取找到该结点的各层结点(路径)
-func (list *SkipList[K, V]) GetUpdateList(node *SkipListNode[K, V]) (update []*SkipListNode[K, V]) {
-	update = make([]*SkipListNode[K, V], list.maxLevel)
+func (list *ScoredSkipList[K, S, V]) GetUpdateList(node *ScoredSkipListNode[K, S, V]) []*ScoredSkipListNode[K, S, V] {
+	node.checkFreed()
+	update := make([]*ScoredSkipListNode[K, S, V], list.maxLevel)
+	copy(update, list.updateList(node))
+	return update
+}
+
+// updateList
+// 获取找到该结点的各层结点(路径), 结果存放在跳表的临时缓冲区中, 下一次插入或者查找路径时会被覆盖
+func (list *ScoredSkipList[K, S, V]) updateList(node *ScoredSkipListNode[K, S, V]) []*ScoredSkipListNode[K, S, V] {
+	return list.pathTo(node.score, node.value)
+}
+
+// pathTo
+// 查找 (score, value) 时每一层最后一个排在它前面的结点, 结果存放在跳表的临时缓冲区中, 下一次插入或者查找路径时会被覆盖
+func (list *ScoredSkipList[K, S, V]) pathTo(score S, value V) (update []*ScoredSkipListNode[K, S, V]) {
+	update = list.updateBuf
 	t := list.head
 	for i := list.level - 1; i >= 0; i-- {
-		for t.Next(i) != nil && (t.Next(i).score < node.score || (t.Next(i).score == node.score && list.compare(t.Next(i).value, node.value) < 0)) {
+		for t.Next(i) != nil && list.before(t.Next(i).score, t.Next(i).value, score, value) {
 			t = t.Next(i)
 		}
 		update[i] = t
@@ -201,8 +696,8 @@ func (list *SkipList[K, V]) GetUpdateList(node *SkipListNode[K, V]) (update []*S
 }
 
 // Delete
-// 删除对应的结点
-func (list *SkipList[K, V]) Delete(node *SkipListNode[K, V], update []*SkipListNode[K, V]) {
+// 删除对应的结点, 有内存池时结点会被回收, 删除后不能再使用这个结点, 再次使用会panic, 需要检查时使用 DeleteByHandle
+func (list *ScoredSkipList[K, S, V]) Delete(node *ScoredSkipListNode[K, S, V], update []*ScoredSkipListNode[K, S, V]) {
 	if node == nil {
 		return
 	}
@@ -210,23 +705,52 @@ func (list *SkipList[K, V]) Delete(node *SkipListNode[K, V], update []*SkipListN
 	if node == list.head {
 		return
 	}
+	node.checkFreed()
+	list.unlink(node, update)
+	node.gen++
+	if list.keys != nil && list.keys[node.value.Key()] == node {
+		delete(list.keys, node.value.Key())
+	}
+	if list.pool != nil {
+		list.pool.put(node)
+	}
+}
 
+// unlink
+// 把结点从跳表中摘下来, 结点本身不做任何处理
+func (list *ScoredSkipList[K, S, V]) unlink(node *ScoredSkipListNode[K, S, V], update []*ScoredSkipListNode[K, S, V]) {
+	f := sumFloat(node.score)
+	g := list.groupStart(update[0], node.score)
 	for i := 0; i < list.level; i++ {
 		if update[i].Next(i) == node {
-			//修改span
-			update[i].SetSpan(i, update[i].Span(i)+node.Span(i)-1)
+			//修改span和分数之和
+			update[i].setSpan(i, update[i].Span(i)+node.Span(i)-1)
+			update[i].level[i].sum += node.level[i].sum - f
+			update[i].level[i].groups += node.level[i].groups - g
 			//删除对应的结点
-			update[i].SetNext(i, node.Next(i))
+			update[i].setNext(i, node.Next(i))
 		} else {
 			update[i].level[i].span--
+			update[i].level[i].sum -= f
+			update[i].level[i].groups -= g
 		}
 	}
+	list.sum -= f
+	list.groups -= g
+	//删除后 next 排在 update[0] 后面, next 是不是一组相同分数的第一个结点可能会变
+	if next := node.Next(0); next != nil {
+		list.addGroups(update, update[0], list.groupStart(update[0], next.score)-list.groupStart(node, next.score))
+	}
 
-	//处理node的后指针
+	//处理node的后指针, 和插入时一样, 第一个结点的后指针是nil, 不会指向head
+	pre := update[0]
+	if pre == list.head {
+		pre = nil
+	}
 	if node.Next(0) == nil { //node是最后一个,把tail指针指向node的上一个(update[0])
-		list.tail = update[0]
+		list.tail = pre
 	} else { //node不是最后一个,node的下一个指向node的上一个(update[0])
-		node.Next(0).backward = update[0]
+		node.Next(0).backward = pre
 	}
 
 	//处理删掉的是最高level的情况,当前的level要对应的--
@@ -237,9 +761,138 @@ func (list *SkipList[K, V]) Delete(node *SkipListNode[K, V], update []*SkipListN
 	list.size--
 }
 
+// rangeBound
+// 范围删除时, 一层中最后一个满足条件的结点, 以及到它(含)为止的排名、分数之和和不同分数的数量
+type rangeBound[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
+	node   *ScoredSkipListNode[K, S, V]
+	rank   int64
+	sum    float64
+	groups int64
+}
+
+// descend
+// 从最高层向下, 找到每一层最后一个满足 pred 的结点, 结果放在 bounds[i] 中
+// pred 的参数是结点和它的排名, 必须满足 从头开始连续为true, 之后都为false
+func (list *ScoredSkipList[K, S, V]) descend(bounds []rangeBound[K, S, V], pred func(node *ScoredSkipListNode[K, S, V], rank int64) bool) {
+	b := rangeBound[K, S, V]{node: list.head}
+	for i := list.level - 1; i >= 0; i-- {
+		for t := b.node; t.Next(i) != nil && pred(t.Next(i), b.rank+t.Span(i)); t = b.node {
+			b.rank += t.Span(i)
+			b.sum += t.level[i].sum
+			b.groups += t.level[i].groups
+			b.node = t.Next(i)
+		}
+		bounds[i] = b
+	}
+}
+
+// deleteRange
+// 删除 first(不含) 和 last(含) 之间的一段连续的结点, first 和 last 是 descend 的结果
+// 和 redis 的 zslDeleteRangeByRank 不同, 每一层只修改一次指针和 span, 不需要对每个被删除的结点修改每一层,
+// 复杂度 O(level + k), k 是删除的结点数量; fn 不是 nil 时, 在结点被回收之前按顺序用被删除的元素调用 fn
+func (list *ScoredSkipList[K, S, V]) deleteRange(first, last []rangeBound[K, S, V], fn func(score S, value V)) int64 {
+	k := last[0].rank - first[0].rank
+	if k <= 0 {
+		return 0
+	}
+	sum := last[0].sum - first[0].sum
+	groups := last[0].groups - first[0].groups
+	pre := first[0].node
+	x := pre.Next(0)
+	next := last[0].node.Next(0)
+	//next 的前一个结点从 last 变成 pre, next 是不是一组相同分数的第一个结点可能会变
+	var delta int64
+	if next != nil {
+		delta = list.groupStart(pre, next.score) - list.groupStart(last[0].node, next.score)
+	}
+
+	update := list.updateBuf
+	for i := 0; i < list.level; i++ {
+		u, e := first[i], last[i]
+		//u 到 e 的下一个结点的距离, 减去被删除的结点
+		span := e.rank - u.rank + e.node.Span(i) - k
+		levelSum := e.sum - u.sum + e.node.level[i].sum - sum
+		levelGroups := e.groups - u.groups + e.node.level[i].groups - groups
+		u.node.setNext(i, e.node.Next(i))
+		u.node.setSpan(i, span)
+		u.node.level[i].sum = levelSum
+		u.node.level[i].groups = levelGroups
+		update[i] = u.node
+	}
+	list.size -= k
+	list.sum -= sum
+	list.groups -= groups
+	list.addGroups(update, pre, delta)
+
+	if pre == list.head {
+		pre = nil
+	}
+	if next == nil {
+		list.tail = pre
+	} else {
+		next.backward = pre
+	}
+	for list.level > 1 && list.head.Next(list.level-1) == nil {
+		list.level--
+	}
+
+	for n := k; n > 0; n-- {
+		t := x
+		x = x.Next(0)
+		if fn != nil {
+			fn(t.score, t.value)
+		}
+		if list.keys != nil && list.keys[t.value.Key()] == t {
+			delete(list.keys, t.value.Key())
+		}
+		t.gen++
+		if list.pool != nil {
+			list.pool.put(t)
+		}
+	}
+	return k
+}
+
+// DeleteRangeByRank
+// 删除排名在 [left,right] 内的所有结点, 返回删除的数量, 范围的规则和 GetValuesByRank 相同
+// 只查找两次范围的边界, 然后一次性把整段结点摘下来, 复杂度 O(log n + k)
+// fn 可以是 nil, 不是 nil 时按顺序返回被删除的元素, fn 中不能修改跳表
+func (list *ScoredSkipList[K, S, V]) DeleteRangeByRank(left, right int64, fn func(score S, value V)) int64 {
+	if list.Size() == 0 || left <= 0 || right <= 0 || right < left || left > list.Size() {
+		return 0
+	}
+	bounds := make([]rangeBound[K, S, V], 2*list.level)
+	first, last := bounds[:list.level], bounds[list.level:]
+	list.descend(first, func(_ *ScoredSkipListNode[K, S, V], rank int64) bool {
+		return rank < left
+	})
+	list.descend(last, func(_ *ScoredSkipListNode[K, S, V], rank int64) bool {
+		return rank <= right
+	})
+	return list.deleteRange(first, last, fn)
+}
+
+// DeleteRangeByScore
+// 删除分数范围内的所有结点, 返回删除的数量, 复杂度 O(log n + k)
+// fn 可以是 nil, 不是 nil 时按顺序返回被删除的元素, fn 中不能修改跳表
+func (list *ScoredSkipList[K, S, V]) DeleteRangeByScore(findRange *ScoreRange[S], fn func(score S, value V)) int64 {
+	if findRange == nil || list.Size() == 0 {
+		return 0
+	}
+	bounds := make([]rangeBound[K, S, V], 2*list.level)
+	first, last := bounds[:list.level], bounds[list.level:]
+	list.descend(first, func(node *ScoredSkipListNode[K, S, V], _ int64) bool {
+		return list.beforeStart(node.score, findRange)
+	})
+	list.descend(last, func(node *ScoredSkipListNode[K, S, V], _ int64) bool {
+		return !list.afterEnd(node.score, findRange)
+	})
+	return list.deleteRange(first, last, fn)
+}
+
 // GetValuesByScore
 // 根据 score 范围 查找 node
-func (list *SkipList[K, V]) GetValuesByScore(findRange *SkipListFindRange) (result []V) {
+func (list *ScoredSkipList[K, S, V]) GetValuesByScore(findRange *ScoreRange[S]) (result []V) {
 	if findRange == nil || list.Size() == 0 {
 		return
 	}
@@ -247,37 +900,86 @@ func (list *SkipList[K, V]) GetValuesByScore(findRange *SkipListFindRange) (resu
 	if !list.ScoreInRange(findRange) {
 		return
 	}
+	//找到第一个不在范围开始之前的结点
 	t := list.head
-	if findRange.MinInf {
-		//从头开始查找
-		t = list.head.Next(0)
-	} else {
-		//不是从头,找到最小的那个元素
-		for i := list.level - 1; i >= 0; i-- {
-			for t.Next(i) != nil && t.Next(i).score < findRange.Min {
-				t = t.Next(i)
-			}
+	for i := list.level - 1; i >= 0; i-- {
+		for t.Next(i) != nil && list.beforeStart(t.Next(i).score, findRange) {
+			t = t.Next(i)
 		}
 	}
-	for {
-		//符合范围的条件 (从负无穷 || 当前的score >= 查找的最小值) && (到正无穷 || 当前元素 <= 查找的最大值)
-		if (findRange.MinInf || t.score >= findRange.Min) && (findRange.MaxInf || t.score <= findRange.Max) {
-			result = append(result, t.value)
+	//向右遍历,直到超出范围
+	for t = t.Next(0); t != nil && !list.afterEnd(t.score, findRange); t = t.Next(0) {
+		result = append(result, t.value)
+	}
+	return
+}
+
+// GetRevValuesByScore
+// 根据 score 范围 反向查找 node, 从范围的结尾开始沿着后指针向前遍历
+// 返回的顺序和 GetValuesByScore 相反, 复杂度 O(log n + k)
+func (list *ScoredSkipList[K, S, V]) GetRevValuesByScore(findRange *ScoreRange[S]) (result []V) {
+	if findRange == nil || list.Size() == 0 {
+		return
+	}
+	//查找范围不在这跳表中,直接return
+	if !list.ScoreInRange(findRange) {
+		return
+	}
+	last, lastRank := list.lastInRange(findRange)
+	if last == nil {
+		return
+	}
+	_, firstRank := list.firstInRange(findRange)
+	if firstRank > lastRank {
+		return
+	}
+	//两次查找就能知道结果的数量, 只需要分配一次内存
+	result = make([]V, 0, lastRank-firstRank+1)
+	for t := last; t != nil && len(result) < cap(result); t = t.Pre() {
+		result = append(result, t.value)
+	}
+	return
+}
+
+// firstInRange
+// 范围内的第一个结点和它的排名, 没有时返回 nil
+func (list *ScoredSkipList[K, S, V]) firstInRange(findRange *ScoreRange[S]) (*ScoredSkipListNode[K, S, V], int64) {
+	rank := int64(0)
+	t := list.head
+	for i := list.level - 1; i >= 0; i-- {
+		for t.Next(i) != nil && list.beforeStart(t.Next(i).score, findRange) {
+			rank += t.Span(i)
+			t = t.Next(i)
 		}
-		if t.Next(0) == nil || (!findRange.MaxInf && t.Next(0).score > findRange.Max) {
-			//下一个元素是空(到尾了) || (不是查找到正无穷 && 下一个元素的 score > 要查找的最大值)
-			break
-		} else {
-			//向右移动
-			t = t.Next(0)
+	}
+	t = t.Next(0)
+	if t == nil || list.afterEnd(t.score, findRange) {
+		return nil, 0
+	}
+	return t, rank + 1
+}
+
+// lastInRange
+// 范围内的最后一个结点和它的排名, 没有时返回 nil
+// 比如从小到大的跳表中, 就是最后一个 score <= Max 的结点
+func (list *ScoredSkipList[K, S, V]) lastInRange(findRange *ScoreRange[S]) (*ScoredSkipListNode[K, S, V], int64) {
+	rank := int64(0)
+	t := list.head
+	for i := list.level - 1; i >= 0; i-- {
+		for t.Next(i) != nil && !list.afterEnd(t.Next(i).score, findRange) {
+			rank += t.Span(i)
+			t = t.Next(i)
 		}
 	}
-	return
+	if t == list.head || list.beforeStart(t.score, findRange) {
+		return nil, 0
+	}
+	return t, rank
 }
 
 // GetValuesByRank
 // 根据排名 范围 查找 node
-func (list *SkipList[K, V]) GetValuesByRank(left, right int64) (result []V) {
+func (list *ScoredSkipList[K, S, V]) GetValuesByRank(left, right int64) (result []V) {
 	//范围出错
 	if list.Size() == 0 || left == 0 || right == 0 || right < left || left > list.Size() {
 		return
@@ -302,16 +1004,54 @@ func (list *SkipList[K, V]) GetValuesByRank(left, right int64) (result []V) {
 	return
 }
 
+// GetRevValuesByRank
+// 根据反向排名范围查找 node, 反向排名1是最后一个结点
+// 先找到反向排名最小的结点, 然后沿着后指针向前遍历, 复杂度 O(log n + k)
+func (list *ScoredSkipList[K, S, V]) GetRevValuesByRank(left, right int64) (result []V) {
+	//范围出错
+	if list.Size() == 0 || left <= 0 || right <= 0 || right < left || left > list.Size() {
+		return
+	}
+	if right > list.Size() {
+		right = list.Size()
+	}
+	result = make([]V, 0, right-left+1)
+	for t := list.getNodeByRank(list.Size() - left + 1); t != nil && len(result) < cap(result); t = t.Pre() {
+		result = append(result, t.value)
+	}
+	return
+}
+
+// getNodeByRank
+// 根据排名查找结点, 排名超出范围时返回 nil
+func (list *ScoredSkipList[K, S, V]) getNodeByRank(rank int64) *ScoredSkipListNode[K, S, V] {
+	if rank <= 0 || rank > list.Size() {
+		return nil
+	}
+	tRank := int64(0)
+	t := list.head
+	for i := list.level - 1; i >= 0; i-- {
+		for t.Next(i) != nil && tRank+t.Span(i) <= rank {
+			tRank += t.Span(i)
+			t = t.Next(i)
+		}
+		if tRank == rank {
+			return t
+		}
+	}
+	return nil
+}
+
 // GetNodesByRank
-// 根据排名 范围 查找 node
-func (list *SkipList[K, V]) GetNodesByRank(left, right int64) (result []*SkipListNode[K, V]) {
+// 根据排名 范围 查找 node, 结点被删除后不能再使用, 需要长期持有时使用 GetHandlesByRank
+func (list *ScoredSkipList[K, S, V]) GetNodesByRank(left, right int64) (result []*ScoredSkipListNode[K, S, V]) {
 	//范围出错
 	if list.Size() == 0 || left == 0 || right == 0 || right < left || left > list.Size() {
 		return
 	}
 	tRank := int64(0)
 	t := list.head
-	result = make([]*SkipListNode[K, V], 0, right-left+1)
+	result = make([]*ScoredSkipListNode[K, S, V], 0, right-left+1)
 	//先找到排名最小的元素,然后向右一点点查找,直到找到排名最大的元素
 	for i := list.level - 1; i >= 0; i-- {
 		for t.Next(i) != nil && tRank+t.level[i].span <= left {
@@ -331,28 +1071,66 @@ func (list *SkipList[K, V]) GetNodesByRank(left, right int64) (result []*SkipLis
 
 // GetNodeRank
 // 获取这个node的排名(排名从1开始)
-func (list *SkipList[K, V]) GetNodeRank(node *SkipListNode[K, V]) int64 {
+func (list *ScoredSkipList[K, S, V]) GetNodeRank(node *ScoredSkipListNode[K, S, V]) int64 {
+	node.checkFreed()
 	t := list.head
 	rank := int64(0)
 	for i := list.level - 1; i >= 0; i-- {
-		for t.Next(i) != nil && t.Next(i).score <= node.score {
+		//score相同时也要用compare比较, 否则会越过score相同但是排在node后面的结点
+		for t.Next(i) != nil && (t.Next(i) == node || list.before(t.Next(i).score, t.Next(i).value, node.score, node.value)) {
 			rank += t.level[i].span
-			if t.Next(i).score == node.score && list.compare(t.Next(i).value, node.value) == 0 {
-				return rank
-			}
 			t = t.Next(i)
 		}
+		if t == node {
+			return rank
+		}
 	}
 	return rank
 }
 
+// clone
+// 复制一个结构完全相同的跳表(每个结点的层数、span都不变), 复杂度 O(n)
+func (list *ScoredSkipList[K, S, V]) clone() *ScoredSkipList[K, S, V] {
+	c := list.newEmpty()
+	b := c.newBuilder()
+	for t := list.head.Next(0); t != nil; t = t.Next(0) {
+		b.append(len(t.level), t.score, t.value)
+	}
+	b.finish()
+	return c
+}
+
+// newEmpty
+// 创建一个配置(最大层数、排序规则、内存池、是否有 key 的索引)相同的空跳表
+func (list *ScoredSkipList[K, S, V]) newEmpty() *ScoredSkipList[K, S, V] {
+	var v V
+	var score S
+	c := &ScoredSkipList[K, S, V]{
+		head:       NewScoredSkipListNode[K, S, V](list.maxLevel, score, v),
+		size:       0,
+		level:      1,
+		maxLevel:   list.maxLevel,
+		scoreOrder: list.scoreOrder,
+		rankBuf:    make([]int64, list.maxLevel),
+		sumBuf:     make([]float64, list.maxLevel),
+		groupBuf:   make([]int64, list.maxLevel),
+		updateBuf:  make([]*ScoredSkipListNode[K, S, V], list.maxLevel),
+		pool:       list.pool,
+		id:         skipListID.Add(1),
+	}
+	if list.keys != nil {
+		c.keys = make(map[K]*ScoredSkipListNode[K, S, V], list.size)
+	}
+	return c
+}
+
 // ScoreInRange
 // 判断 这个跳表 的最大值和最小值 是否包含 要查询的score范围
-func (list *SkipList[K, V]) ScoreInRange(findRange *SkipListFindRange) bool {
-	if !findRange.MaxInf && list.head.Next(0).score > findRange.Max {
+func (list *ScoredSkipList[K, S, V]) ScoreInRange(findRange *ScoreRange[S]) bool {
+	if list.afterEnd(list.head.Next(0).score, findRange) {
 		return false
 	}
-	if !findRange.MinInf && list.tail.score < findRange.Min {
+	if list.beforeStart(list.tail.score, findRange) {
 		return false
 	}
 	return true
@@ -0,0 +1,181 @@
+package skiptablev2
+
+// 分数是 float64 的跳表类型, 和分数类型泛型化之前的名字、方法相同, 原来的代码不需要修改
+// 它们都是定义的新类型而不是泛型类型别名(go 1.24 才支持), 所以只需要和 ScoredSkipList 相同的 go 版本
+// 参数或者返回值是结点、跳表的方法在这里重新定义, 使用 SkipListNode、SkipList, 其余的方法直接使用 ScoredSkipList 的方法
+
+// SkipListItem
+// SkipList 泛型约束, 分数是 float64, 满足它的类型也满足 ScoredItem[K, float64]
+type SkipListItem[K comparable] interface {
+	Key() K
+	Score() float64
+}
+
+// SkipListLevel
+// 分数是 float64 的跳表层
+type SkipListLevel[K comparable, V SkipListItem[K]] ScoredSkipListLevel[K, float64, V]
+
+// SkipListNode
+// 分数是 float64 的跳表结点, 和 ScoredSkipListNode[K, float64, V] 的结构相同, 两种指针可以直接转换
+type SkipListNode[K comparable, V SkipListItem[K]] ScoredSkipListNode[K, float64, V]
+
+func NewSkipListNode[K comparable, V SkipListItem[K]](level int, score float64, value V) *SkipListNode[K, V] {
+	return (*SkipListNode[K, V])(NewScoredSkipListNode[K, float64, V](level, score, value))
+}
+
+// scored
+// 转换成 ScoredSkipList 使用的结点, 不需要复制
+func (node *SkipListNode[K, V]) scored() *ScoredSkipListNode[K, float64, V] {
+	return (*ScoredSkipListNode[K, float64, V])(node)
+}
+
+// Next 第i层的下一个元素
+func (node *SkipListNode[K, V]) Next(i int) *SkipListNode[K, V] {
+	return (*SkipListNode[K, V])(node.scored().Next(i))
+}
+
+// Span 第i层的span值
+func (node *SkipListNode[K, V]) Span(i int) int64 {
+	return node.scored().Span(i)
+}
+
+// Sum 第i层到下一个元素之间所有元素的分数之和
+func (node *SkipListNode[K, V]) Sum(i int) float64 {
+	return node.scored().Sum(i)
+}
+
+// Pre 上一个元素
+func (node *SkipListNode[K, V]) Pre() *SkipListNode[K, V] {
+	return (*SkipListNode[K, V])(node.scored().Pre())
+}
+
+// skipListNodes
+// 把 ScoredSkipList 返回的结点数组转换成 SkipListNode 数组
+func skipListNodes[K comparable, V SkipListItem[K]](nodes []*ScoredSkipListNode[K, float64, V]) []*SkipListNode[K, V] {
+	if nodes == nil {
+		return nil
+	}
+	result := make([]*SkipListNode[K, V], len(nodes))
+	for i, node := range nodes {
+		result[i] = (*SkipListNode[K, V])(node)
+	}
+	return result
+}
+
+// SkipListNodePool
+// 分数是 float64 的跳表结点内存池
+type SkipListNodePool[K comparable, V SkipListItem[K]] struct {
+	*ScoredSkipListNodePool[K, float64, V]
+}
+
+// scored
+// 内存池为 nil 时返回 nil, 表示不回收结点
+func (pool *SkipListNodePool[K, V]) scored() *ScoredSkipListNodePool[K, float64, V] {
+	if pool == nil {
+		return nil
+	}
+	return pool.ScoredSkipListNodePool
+}
+
+// SkipList
+// 分数是 float64 的跳表
+type SkipList[K comparable, V SkipListItem[K]] struct {
+	*ScoredSkipList[K, float64, V]
+}
+
+// SetNodePool
+// 设置结点的内存池, 和 ScoredSkipList.SetNodePool 相同
+func (list *SkipList[K, V]) SetNodePool(pool *SkipListNodePool[K, V]) {
+	list.ScoredSkipList.SetNodePool(pool.scored())
+}
+
+// InsertByScore
+// 插入一个结点, 和 ScoredSkipList.InsertByScore 相同
+func (list *SkipList[K, V]) InsertByScore(score float64, value V) *SkipListNode[K, V] {
+	return (*SkipListNode[K, V])(list.ScoredSkipList.InsertByScore(score, value))
+}
+
+// Insert
+// 插入一个结点, 和 ScoredSkipList.Insert 相同
+func (list *SkipList[K, V]) Insert(score float64, value V) (*SkipListNode[K, V], error) {
+	node, err := list.ScoredSkipList.Insert(score, value)
+	return (*SkipListNode[K, V])(node), err
+}
+
+// GetNodeByKey
+// 根据 key 查找结点, 和 ScoredSkipList.GetNodeByKey 相同
+func (list *SkipList[K, V]) GetNodeByKey(key K) (*SkipListNode[K, V], bool) {
+	node, ok := list.ScoredSkipList.GetNodeByKey(key)
+	return (*SkipListNode[K, V])(node), ok
+}
+
+// UpdateScore
+// 更新结点的score, 和 ScoredSkipList.UpdateScore 相同
+func (list *SkipList[K, V]) UpdateScore(node *SkipListNode[K, V], score float64) {
+	list.ScoredSkipList.UpdateScore(node.scored(), score)
+}
+
+// SetScore
+// 更新结点的score, 和 ScoredSkipList.SetScore 相同
+func (list *SkipList[K, V]) SetScore(node *SkipListNode[K, V], score float64) error {
+	return list.ScoredSkipList.SetScore(node.scored(), score)
+}
+
+// GetUpdateList
+// 获取找到该结点的各层结点(路径), 和 ScoredSkipList.GetUpdateList 相同
+func (list *SkipList[K, V]) GetUpdateList(node *SkipListNode[K, V]) []*SkipListNode[K, V] {
+	return skipListNodes(list.ScoredSkipList.GetUpdateList(node.scored()))
+}
+
+// Delete
+// 删除对应的结点, 和 ScoredSkipList.Delete 相同, update 是 GetUpdateList 返回的路径
+func (list *SkipList[K, V]) Delete(node *SkipListNode[K, V], update []*SkipListNode[K, V]) {
+	path := make([]*ScoredSkipListNode[K, float64, V], len(update))
+	for i, n := range update {
+		path[i] = n.scored()
+	}
+	list.ScoredSkipList.Delete(node.scored(), path)
+}
+
+// GetNodesByRank
+// 根据排名范围查找结点, 和 ScoredSkipList.GetNodesByRank 相同
+func (list *SkipList[K, V]) GetNodesByRank(left, right int64) []*SkipListNode[K, V] {
+	return skipListNodes(list.ScoredSkipList.GetNodesByRank(left, right))
+}
+
+// GetNodeRank
+// 获取这个node的排名(排名从1开始), 和 ScoredSkipList.GetNodeRank 相同
+func (list *SkipList[K, V]) GetNodeRank(node *SkipListNode[K, V]) int64 {
+	return list.ScoredSkipList.GetNodeRank(node.scored())
+}
+
+// HandleOf
+// 获取结点的句柄, 和 ScoredSkipList.HandleOf 相同
+func (list *SkipList[K, V]) HandleOf(node *SkipListNode[K, V]) ScoredSkipListHandle[K, float64, V] {
+	return list.ScoredSkipList.HandleOf(node.scored())
+}
+
+// SplitAtRank
+// 在排名 rank 处切开, 和 ScoredSkipList.SplitAtRank 相同
+func (list *SkipList[K, V]) SplitAtRank(rank int64) (*SkipList[K, V], error) {
+	return skipListOf(list.ScoredSkipList.SplitAtRank(rank))
+}
+
+// SplitAtScore
+// 在分数 score 处切开, 和 ScoredSkipList.SplitAtScore 相同
+func (list *SkipList[K, V]) SplitAtScore(score float64) (*SkipList[K, V], error) {
+	return skipListOf(list.ScoredSkipList.SplitAtScore(score))
+}
+
+// Concat
+// 把 other 的所有结点接到跳表的后面, 和 ScoredSkipList.Concat 相同
+func (list *SkipList[K, V]) Concat(other *SkipList[K, V]) error {
+	return list.ScoredSkipList.Concat(other.ScoredSkipList)
+}
+
+func skipListOf[K comparable, V SkipListItem[K]](list *ScoredSkipList[K, float64, V], err error) (*SkipList[K, V], error) {
+	if err != nil {
+		return nil, err
+	}
+	return &SkipList[K, V]{list}, nil
+}
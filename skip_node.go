@@ -1,57 +1,134 @@
 package skiptablev2
 
-type SkipListLevel[K comparable, V SkipListItem[K]] struct {
+import "cmp"
+
+type ScoredSkipListLevel[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
 	//指向下一个结点
-	forward *SkipListNode[K, V]
+	forward *ScoredSkipListNode[K, S, V]
 
 	/*
 	 * 到下一个node的距离;
 	 * 思考,为啥是记录到下一个node, 而不是记录上一个node到这的距离
 	 */
 	span int64
+
+	//从当前结点(不含)到下一个node(含)之间所有结点的分数之和, 和 span 一样, 没有下一个node时是到结尾的和
+	//用来在 O(log n) 内计算一段排名或者分数范围内分数的和, 分数不是数字时一直是0
+	sum float64
+
+	//从当前结点(不含)到下一个node(含)之间, 分数和前一个结点不同的结点数量(也就是不同分数的数量), 和 span 一样, 没有下一个node时是到结尾
+	//用来在 O(log n) 内计算 dense 排名(并列的元素排名相同, 排名之间不空位)
+	groups int64
 }
 
-type SkipListNode[K comparable, V SkipListItem[K]] struct {
+type ScoredSkipListNode[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
 	//指向上一个结点
-	backward *SkipListNode[K, V]
+	backward *ScoredSkipListNode[K, S, V]
 	//索引用的层
-	level []SkipListLevel[K, V]
+	level []ScoredSkipListLevel[K, S, V]
 	//存储的值
 	value V
 	//排名用的分数
-	score float64
+	score S
+	//结点是否已经被回收到内存池中
+	freed bool
+	//结点的代数, 从跳表中删除时加1, 从内存池中复用时再加1, 奇数表示结点已经被删除
+	//句柄记录了创建时的代数, 代数不同说明句柄已经过期
+	gen uint64
+	//结点所属跳表的编号, 切开和拼接跳表移动结点时会修改
+	owner uint64
+}
+
+// 层数较少的结点,结点和层数组放在同一块内存中,只需要一次内存分配
+// SKIPLIST_P=0.25 时, 超过4层的结点不到 0.5%
+type skipListNode1[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
+	node  ScoredSkipListNode[K, S, V]
+	level [1]ScoredSkipListLevel[K, S, V]
 }
 
-func NewSkipListNode[K comparable, V SkipListItem[K]](level int, score float64, value V) *SkipListNode[K, V] {
-	return &SkipListNode[K, V]{
-		backward: nil,
-		level:    make([]SkipListLevel[K, V], level),
-		value:    value,
-		score:    score,
+type skipListNode2[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
+	node  ScoredSkipListNode[K, S, V]
+	level [2]ScoredSkipListLevel[K, S, V]
+}
+
+type skipListNode3[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
+	node  ScoredSkipListNode[K, S, V]
+	level [3]ScoredSkipListLevel[K, S, V]
+}
+
+type skipListNode4[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
+	node  ScoredSkipListNode[K, S, V]
+	level [4]ScoredSkipListLevel[K, S, V]
+}
+
+func NewScoredSkipListNode[K comparable, S cmp.Ordered, V ScoredItem[K, S]](level int, score S, value V) *ScoredSkipListNode[K, S, V] {
+	var node *ScoredSkipListNode[K, S, V]
+	switch level {
+	case 1:
+		b := &skipListNode1[K, S, V]{}
+		b.node.level = b.level[:]
+		node = &b.node
+	case 2:
+		b := &skipListNode2[K, S, V]{}
+		b.node.level = b.level[:]
+		node = &b.node
+	case 3:
+		b := &skipListNode3[K, S, V]{}
+		b.node.level = b.level[:]
+		node = &b.node
+	case 4:
+		b := &skipListNode4[K, S, V]{}
+		b.node.level = b.level[:]
+		node = &b.node
+	default:
+		node = &ScoredSkipListNode[K, S, V]{level: make([]ScoredSkipListLevel[K, S, V], level)}
 	}
+	node.value = value
+	node.score = score
+	return node
 }
 
 // Next 第i层的下一个元素
-func (node *SkipListNode[K, V]) Next(i int) *SkipListNode[K, V] {
+func (node *ScoredSkipListNode[K, S, V]) Next(i int) *ScoredSkipListNode[K, S, V] {
 	return node.level[i].forward
 }
 
-// SetNext 设置第i层的下一个元素
-func (node *SkipListNode[K, V]) SetNext(i int, next *SkipListNode[K, V]) {
+// setNext 设置第i层的下一个元素, 只能在跳表内部使用, 直接修改会破坏 span 和分数之和
+func (node *ScoredSkipListNode[K, S, V]) setNext(i int, next *ScoredSkipListNode[K, S, V]) {
 	node.level[i].forward = next
 }
 
 // Span 第i层的span值
-func (node *SkipListNode[K, V]) Span(i int) int64 {
+func (node *ScoredSkipListNode[K, S, V]) Span(i int) int64 {
 	return node.level[i].span
 }
 
-// SetSpan 设置第i层的span值
-func (node *SkipListNode[K, V]) SetSpan(i int, span int64) {
+// setSpan 设置第i层的span值, 只能在跳表内部使用
+func (node *ScoredSkipListNode[K, S, V]) setSpan(i int, span int64) {
 	node.level[i].span = span
 }
 
+// Sum 第i层到下一个结点的分数之和
+func (node *ScoredSkipListNode[K, S, V]) Sum(i int) float64 {
+	return node.level[i].sum
+}
+
 // Pre 上一个元素    想一下,为啥指向上一个的元素不需要i呢???
-func (node *SkipListNode[K, V]) Pre() *SkipListNode[K, V] {
+func (node *ScoredSkipListNode[K, S, V]) Pre() *ScoredSkipListNode[K, S, V] {
 	return node.backward
 }
+
+// checkFreed 检查结点是否已经被回收或者删除, 使用已经回收或者删除的结点会直接panic
+func (node *ScoredSkipListNode[K, S, V]) checkFreed() {
+	if node.freed {
+		panic("skiplist: use of freed node")
+	}
+	if node.deleted() {
+		panic("skiplist: use of deleted node")
+	}
+}
+
+// deleted 结点是否已经从跳表中删除
+func (node *ScoredSkipListNode[K, S, V]) deleted() bool {
+	return node.gen&1 == 1
+}
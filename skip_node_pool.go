@@ -0,0 +1,90 @@
+package skiptablev2
+
+import (
+	"cmp"
+	"math"
+)
+
+// ScoredSkipListNodePool
+// 跳表结点的内存池, 按结点的层数分类缓存被删除的结点, 插入时复用相同层数的结点, 减少GC压力
+// 内存池不是并发安全的, 可以被同一个goroutine里的多个跳表共用
+type ScoredSkipListNodePool[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
+	//free[i] 缓存层数为 i+1 的结点
+	free [][]*ScoredSkipListNode[K, S, V]
+	//每种层数最多缓存的结点数量
+	maxFree int
+	//调试模式, 回收的结点会被"下毒"并且永远不会被复用,
+	//通过过期的 *SkipListNode 使用已经删除的结点时会直接panic
+	debug bool
+}
+
+// NewSkipListNodePool
+// 初始化一个结点内存池, maxFree 是每种层数最多缓存的结点数量
+func NewSkipListNodePool[K comparable, V SkipListItem[K]](maxFree int, debug bool) *SkipListNodePool[K, V] {
+	return &SkipListNodePool[K, V]{NewScoredSkipListNodePool[K, float64, V](maxFree, debug)}
+}
+
+// NewScoredSkipListNodePool
+// 初始化一个自定义分数类型的结点内存池
+func NewScoredSkipListNodePool[K comparable, S cmp.Ordered, V ScoredItem[K, S]](maxFree int, debug bool) *ScoredSkipListNodePool[K, S, V] {
+	return &ScoredSkipListNodePool[K, S, V]{
+		free:    make([][]*ScoredSkipListNode[K, S, V], SKIP_TABLE_DEFAULT_MAX_LEVEL),
+		maxFree: maxFree,
+		debug:   debug,
+	}
+}
+
+// FreeCount
+// 内存池中缓存的结点数量
+func (pool *ScoredSkipListNodePool[K, S, V]) FreeCount() int {
+	count := 0
+	for _, free := range pool.free {
+		count += len(free)
+	}
+	return count
+}
+
+// 获取一个结点, 内存池中没有对应层数的结点时重新分配
+func (pool *ScoredSkipListNodePool[K, S, V]) get(level int, score S, value V) *ScoredSkipListNode[K, S, V] {
+	if level <= len(pool.free) {
+		if free := pool.free[level-1]; len(free) > 0 {
+			node := free[len(free)-1]
+			free[len(free)-1] = nil
+			pool.free[level-1] = free[:len(free)-1]
+			node.freed = false
+			//结点重新开始使用, 代数变回偶数, 原来的句柄仍然是过期的
+			node.gen++
+			node.score = score
+			node.value = value
+			return node
+		}
+	}
+	return NewScoredSkipListNode[K, S, V](level, score, value)
+}
+
+// 回收一个结点
+func (pool *ScoredSkipListNodePool[K, S, V]) put(node *ScoredSkipListNode[K, S, V]) {
+	var v V
+	node.freed = true
+	//不再引用 value, 让 value 可以被GC回收
+	node.value = v
+	node.backward = nil
+	for i := range node.level {
+		node.level[i].forward = nil
+	}
+	if pool.debug {
+		//下毒: 分数和span都改成非法值, 调试模式下的结点不会被复用
+		if score, ok := any(&node.score).(*float64); ok {
+			*score = math.NaN()
+		}
+		for i := range node.level {
+			node.level[i].span = -1
+		}
+		return
+	}
+	level := len(node.level)
+	if level > len(pool.free) || len(pool.free[level-1]) >= pool.maxFree {
+		return
+	}
+	pool.free[level-1] = append(pool.free[level-1], node)
+}
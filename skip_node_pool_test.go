@@ -0,0 +1,120 @@
+package skiptablev2
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func newPoolTestSkipList(pool *SkipListNodePool[string, *S1[string]]) *SkipList[string, *S1[string]] {
+	st, err := NewDefaultSkipTable[string, *S1[string]](func(v1, v2 *S1[string]) int {
+		if v1.f == v2.f {
+			return 0
+		} else if v1.f < v2.f {
+			return -1
+		} else {
+			return 1
+		}
+	})
+	if err != nil {
+		panic(err)
+	}
+	st.SetNodePool(pool)
+	return st
+}
+
+func TestSkipListNodePool(t *testing.T) {
+	pool := NewSkipListNodePool[string, *S1[string]](1024, false)
+	st := newPoolTestSkipList(pool)
+
+	const N = 10000
+	arrS := make(SortS1[string], 0, N)
+	for i := 0; i < N; i++ {
+		s := &S1[string]{f: rand.Float64()}
+		st.InsertByScore(s.f, s)
+		arrS = append(arrS, s)
+	}
+
+	//反复删除和插入, 结点会被回收复用
+	for round := 0; round < 10; round++ {
+		cached := pool.FreeCount()
+		nodes := st.GetNodesByRank(1, 100)
+		for _, node := range nodes {
+			st.Delete(node, st.GetUpdateList(node))
+		}
+		freed := pool.FreeCount()
+		if freed != cached+len(nodes) {
+			t.Fatalf("pool free count:%d want:%d", freed, cached+len(nodes))
+		}
+		arrS = arrS[:0]
+		for _, v := range st.GetValuesByRank(1, st.Size()) {
+			arrS = append(arrS, v)
+		}
+		for i := 0; i < 100; i++ {
+			s := &S1[string]{f: rand.Float64()}
+			st.InsertByScore(s.f, s)
+			arrS = append(arrS, s)
+		}
+		//新结点的层数是随机的,只有层数相同的结点才会被复用
+		if pool.FreeCount() >= freed {
+			t.Fatalf("pool free count:%d should be less than:%d", pool.FreeCount(), freed)
+		}
+	}
+	sort.Sort(arrS)
+	if err := compare(st.GetValuesByRank(1, st.Size()), arrS); err != nil {
+		t.Fatal(err)
+	}
+	for i, node := range st.GetNodesByRank(1, st.Size()) {
+		if rank := st.GetNodeRank(node); rank != int64(i+1) {
+			t.Fatalf("node rank:%d want:%d", rank, i+1)
+		}
+	}
+}
+
+func TestSkipListNodePool_Debug(t *testing.T) {
+	pool := NewSkipListNodePool[string, *S1[string]](1024, true)
+	st := newPoolTestSkipList(pool)
+	for i := 0; i < 100; i++ {
+		s := &S1[string]{f: rand.Float64()}
+		st.InsertByScore(s.f, s)
+	}
+	node := st.GetNodesByRank(1, 1)[0]
+	st.Delete(node, st.GetUpdateList(node))
+	if pool.FreeCount() != 0 {
+		t.Fatal("debug pool should not reuse nodes")
+	}
+
+	//通过过期的结点再次操作跳表,应该panic
+	defer func() {
+		if recover() == nil {
+			t.Fatal("use of freed node should panic")
+		}
+	}()
+	st.UpdateScore(node, 1)
+}
+
+func TestSortSet_NodePool(t *testing.T) {
+	sortSet := NewTestSortSet()
+	sortSet.SetNodePool(NewSkipListNodePool[string, *StItem[string]](1024, false))
+	items := make([]*StItem[string], 0, N)
+	for i := 0; i < N; i++ {
+		item := CreateStItem()
+		sortSet.Add(item)
+		items = append(items, item)
+	}
+	for _, item := range items[:N/2] {
+		sortSet.Remove(item.k)
+	}
+	for i := 0; i < N/2; i++ {
+		item := CreateStItem()
+		sortSet.Add(item)
+		items = append(items, item)
+	}
+	items = items[N/2:]
+	sort.Sort(SortStItem[string](items))
+	for i, r := range sortSet.Range(0, -1) {
+		if !compareItem(items[i], r) {
+			t.Fatalf("item:%v rank:%d arrItem:%v error", r, i, items[i])
+		}
+	}
+}
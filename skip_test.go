@@ -3,8 +3,11 @@ package skiptablev2
 import (
 	"errors"
 	"fmt"
+	"math"
 	"math/rand"
+	"slices"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -371,3 +374,328 @@ func TestSkipList_Delete(t *testing.T) {
 
 	fmt.Println(st.Size())
 }
+
+func TestSkipList_BulkLoad(t *testing.T) {
+	st, err := NewDefaultSkipTable[string, *S1[string]](func(v1, v2 *S1[string]) int {
+		if v1.f == v2.f {
+			return 0
+		} else if v1.f < v2.f {
+			return -1
+		} else {
+			return 1
+		}
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	const N = 10000
+	arrS := make(SortS1[string], 0, N)
+	for i := 0; i < N; i++ {
+		arrS = append(arrS, &S1[string]{f: rand.Float64()})
+	}
+
+	//没有排序的数据
+	if err = st.BulkLoad(arrS); err == nil || st.Size() != 0 {
+		t.Fatalf("BulkLoad unsorted items err:%v size:%d", err, st.Size())
+	}
+
+	sort.Sort(arrS)
+	if err = st.BulkLoad(arrS); err != nil {
+		t.Fatal(err)
+	}
+	if st.Size() != N {
+		t.Fatalf("st size:%d want:%d", st.Size(), N)
+	}
+	if err = compare(st.GetValuesByRank(1, N), arrS); err != nil {
+		t.Fatal(err)
+	}
+	nodes := st.GetNodesByRank(1, N)
+	for i, node := range nodes {
+		if rank := st.GetNodeRank(node); rank != int64(i+1) {
+			t.Fatalf("node rank:%d want:%d", rank, i+1)
+		}
+	}
+
+	//非空跳表不能 BulkLoad
+	if err = st.BulkLoad(arrS); err == nil {
+		t.Fatal("BulkLoad on non empty skip list should fail")
+	}
+
+	//BulkLoad 之后的插入和删除
+	for i := 0; i < N; i++ {
+		s := &S1[string]{f: rand.Float64()}
+		st.InsertByScore(s.f, s)
+		arrS = append(arrS, s)
+	}
+	sort.Sort(arrS)
+	if err = compare(st.GetValuesByRank(1, st.Size()), arrS); err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range st.GetNodesByRank(1, st.Size()) {
+		st.Delete(node, st.GetUpdateList(node))
+	}
+	if st.Size() != 0 {
+		t.Fatalf("st size:%d want:0", st.Size())
+	}
+}
+
+func TestSkipList_Reverse(t *testing.T) {
+	st, err := NewDefaultSkipTable[string, *S1[string]](func(v1, v2 *S1[string]) int {
+		if v1.f == v2.f {
+			return 0
+		} else if v1.f < v2.f {
+			return -1
+		} else {
+			return 1
+		}
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	const N = 1000
+	arrS := make(SortS1[string], 0, N)
+	for i := 0; i < N; i++ {
+		s := &S1[string]{f: rand.Float64()}
+		st.InsertByScore(s.f, s)
+		arrS = append(arrS, s)
+	}
+	//删除第一个结点后, 新的第一个结点的后指针也必须是 nil
+	first := st.GetNodesByRank(1, 1)[0]
+	st.Delete(first, st.GetUpdateList(first))
+	if pre := st.GetNodesByRank(1, 1)[0].Pre(); pre != nil {
+		t.Fatalf("first node backward:%v want nil", pre)
+	}
+	sort.Sort(sort.Reverse(arrS))
+	arrS = arrS[:N-1]
+
+	if err = compare(st.GetRevValuesByRank(1, N), arrS); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		l := rand.Int63n(N) + 1
+		r := l + rand.Int63n(N)
+		result := st.GetRevValuesByRank(l, r)
+		if want := min(r, N-1) - l + 1; int64(len(result)) != max(want, 0) {
+			t.Fatalf("GetRevValuesByRank(%d,%d) len:%d want:%d", l, r, len(result), want)
+		}
+		if err = compare(result, arrS[min(l-1, N-1):]); err != nil {
+			t.Fatal(err)
+		}
+
+		findRange := &SkipListFindRange{
+			Min:    rand.Float64(),
+			Max:    rand.Float64(),
+			MinInf: rand.Intn(4) == 0,
+			MaxInf: rand.Intn(4) == 0,
+		}
+		want := st.GetValuesByScore(findRange)
+		slices.Reverse(want)
+		result = st.GetRevValuesByScore(findRange)
+		if len(result) != len(want) || cap(result) != len(result) {
+			t.Fatalf("GetRevValuesByScore len:%d cap:%d want:%d", len(result), cap(result), len(want))
+		}
+		for j := range want {
+			if want[j] != result[j] {
+				t.Fatalf("GetRevValuesByScore item:%v want:%v at:%d", result[j], want[j], j)
+			}
+		}
+	}
+
+	//反向取前10名只需要分配一次内存
+	allocs := testing.AllocsPerRun(100, func() {
+		st.GetRevValuesByRank(1, 10)
+	})
+	if allocs > 1 {
+		t.Fatalf("GetRevValuesByRank allocs:%f want <= 1", allocs)
+	}
+}
+
+func TestSkipList_Insert(t *testing.T) {
+	st, err := NewDefaultSkipTable[string, *S1[string]](func(v1, v2 *S1[string]) int {
+		return strings.Compare(v1.key, v2.key)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = st.Insert(math.NaN(), &S1[string]{key: "nan"}); err == nil || st.Size() != 0 {
+		t.Fatalf("Insert NaN err:%v size:%d", err, st.Size())
+	}
+	a, err := st.Insert(1, &S1[string]{key: "a", f: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = st.SetScore(a, math.NaN()); err == nil || a.score != 1 {
+		t.Fatalf("SetScore NaN err:%v score:%f", err, a.score)
+	}
+
+	//没有 key 的索引时, 同一个 key 可以插入多次
+	st.InsertByScore(2, &S1[string]{key: "a", f: 2})
+	if err = st.EnableUniqueKeys(); err == nil {
+		t.Fatal("EnableUniqueKeys should fail with duplicated key")
+	}
+	st.Delete(a, st.GetUpdateList(a))
+	if err = st.EnableUniqueKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = st.Insert(3, &S1[string]{key: "a", f: 3}); err == nil {
+		t.Fatal("Insert duplicated key should fail")
+	}
+	b, err := st.Insert(3, &S1[string]{key: "b", f: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node, ok := st.GetNodeByKey("b"); !ok || node != b {
+		t.Fatalf("GetNodeByKey b:%v %v", node, ok)
+	}
+	st.Delete(b, st.GetUpdateList(b))
+	if _, ok := st.GetNodeByKey("b"); ok {
+		t.Fatal("GetNodeByKey deleted key")
+	}
+	if _, err = st.Insert(4, &S1[string]{key: "b", f: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	c := st.clone()
+	if node, ok := c.GetNodeByKey("b"); !ok || node.score != 4 {
+		t.Fatalf("clone GetNodeByKey b:%v %v", node, ok)
+	}
+	if err = c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	//BulkLoad 拒绝 NaN 和重复的 key, 跳表不会被修改
+	st.reset()
+	if err = st.BulkLoad([]*S1[string]{{key: "a", f: 1}, {key: "b", f: math.NaN()}, {key: "c", f: 3}}); err == nil {
+		t.Fatal("BulkLoad NaN should fail")
+	}
+	if err = st.BulkLoad([]*S1[string]{{key: "a", f: 1}, {key: "a", f: 2}}); err == nil || st.Size() != 0 {
+		t.Fatalf("BulkLoad duplicated key err:%v size:%d", err, st.Size())
+	}
+	if err = st.BulkLoad([]*S1[string]{{key: "a", f: 1}, {key: "b", f: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if node, ok := st.GetNodeByKey("a"); !ok || node.score != 1 {
+		t.Fatalf("BulkLoad GetNodeByKey a:%v %v", node, ok)
+	}
+	if err = st.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSkipList_Inf(t *testing.T) {
+	st, err := NewDefaultSkipTable[string, *S1[string]](func(v1, v2 *S1[string]) int {
+		return strings.Compare(v1.key, v2.key)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores := map[string]float64{"max": math.Inf(1), "min": math.Inf(-1), "zero": 0, "neg": -1e300, "pos": 1e300, "max2": math.Inf(1)}
+	for key, score := range scores {
+		if _, err = st.Insert(score, &S1[string]{key: key, f: score}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = compare(st.GetValuesByRank(1, 6), []*S1[string]{{f: math.Inf(-1)}, {f: -1e300}, {f: 0}, {f: 1e300}, {f: math.Inf(1)}, {f: math.Inf(1)}}); err != nil {
+		t.Fatal(err)
+	}
+	//Min 是 -Inf 和 MinInf 相同
+	if n := len(st.GetValuesByScore(&SkipListFindRange{Min: math.Inf(-1), Max: 0})); n != 3 {
+		t.Fatalf("GetValuesByScore [-Inf,0] len:%d want 3", n)
+	}
+	if n := len(st.GetValuesByScore(&SkipListFindRange{Min: 0, Max: math.Inf(1), MaxEx: true})); n != 2 {
+		t.Fatalf("GetValuesByScore [0,+Inf) len:%d want 2", n)
+	}
+	//边界是 NaN 时范围是空的
+	nan := &SkipListFindRange{Min: math.NaN(), MaxInf: true}
+	if n := len(st.GetValuesByScore(nan)) + len(st.GetRevValuesByScore(nan)); n != 0 {
+		t.Fatalf("GetValuesByScore NaN range len:%d", n)
+	}
+	if a := st.AggregateByScore(nan); a.Count != 0 {
+		t.Fatalf("AggregateByScore NaN range:%v", a)
+	}
+
+	//±Inf 不计入分数之和, 删除后分数之和也不会变成 NaN
+	if a := st.AggregateByRank(1, 6); a.Count != 6 || a.Sum != 0 {
+		t.Fatalf("AggregateByRank all:%v", a)
+	}
+	for _, rank := range []int64{6, 1} {
+		node := st.GetNodesByRank(rank, rank)[0]
+		st.Delete(node, st.GetUpdateList(node))
+	}
+	node, _ := st.GetNodeByKey("zero")
+	st.UpdateScore(node, math.Inf(-1))
+	if a := st.AggregateByScore(&SkipListFindRange{MinInf: true, MaxInf: true}); a.Count != 4 || a.Sum != 0 {
+		t.Fatalf("AggregateByScore after delete:%v", a)
+	}
+	if err = st.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSkipList_DeleteRange(t *testing.T) {
+	for _, order := range []ScoreOrder{SCORE_ORDER_ASC, SCORE_ORDER_DESC} {
+		st, err := NewSkipTableWithOrder[string, *S1[string]](SKIP_TABLE_DEFAULT_MAX_LEVEL, order, func(v1, v2 *S1[string]) int {
+			return strings.Compare(v1.key, v2.key)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		st.SetNodePool(NewSkipListNodePool[string, *S1[string]](64, false))
+		if err = st.EnableUniqueKeys(); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2000; i++ {
+			score := float64(rand.Intn(200))
+			st.InsertByScore(score, &S1[string]{key: fmt.Sprint(i), f: score})
+		}
+		first := st.GetHandlesByRank(1, 1)[0]
+		for st.Size() > 0 {
+			var removed []*S1[string]
+			fn := func(score float64, value *S1[string]) {
+				removed = append(removed, &S1[string]{key: value.key, f: score})
+			}
+			var want []*S1[string]
+			var n int64
+			if rand.Intn(2) == 0 {
+				left := rand.Int63n(st.Size()) + 1
+				right := left + rand.Int63n(100)
+				want = st.GetValuesByRank(left, min(right, st.Size()))
+				n = st.DeleteRangeByRank(left, right, fn)
+			} else {
+				findRange := &SkipListFindRange{Min: float64(rand.Intn(200)), MinEx: rand.Intn(2) == 0, MaxEx: rand.Intn(2) == 0}
+				findRange.Max = findRange.Min + float64(rand.Intn(10))
+				if order == SCORE_ORDER_DESC {
+					findRange.MinInf = rand.Intn(10) == 0
+				} else {
+					findRange.MaxInf = rand.Intn(10) == 0
+				}
+				want = st.GetValuesByScore(findRange)
+				n = st.DeleteRangeByScore(findRange, fn)
+			}
+			if n != int64(len(want)) || len(removed) != len(want) {
+				t.Fatalf("DeleteRange removed:%d %d want:%d", n, len(removed), len(want))
+			}
+			for i := range want {
+				if removed[i].key != want[i].key || removed[i].f != want[i].f {
+					t.Fatalf("DeleteRange item:%v want:%v at:%d", removed[i], want[i], i)
+				}
+				if _, ok := st.GetNodeByKey(want[i].key); ok {
+					t.Fatalf("DeleteRange key %s is still in the key index", want[i].key)
+				}
+			}
+			if err = st.Validate(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if st.Valid(first) || st.tail != nil || st.level != 1 {
+			t.Fatalf("DeleteRange empty list valid:%v tail:%v level:%d", st.Valid(first), st.tail, st.level)
+		}
+		if n := st.DeleteRangeByRank(1, 1, nil) + st.DeleteRangeByScore(&SkipListFindRange{MinInf: true, MaxInf: true}, nil); n != 0 {
+			t.Fatalf("DeleteRange on empty list:%d", n)
+		}
+	}
+}
@@ -1,5 +1,35 @@
 package skiptablev2
 
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	//SORT_SET_DEFAULT_LISTPACK_ENTRIES
+	//默认元素数量不超过这个值时使用紧凑编码, 和redis的 zset-max-listpack-entries 默认值相同
+	SORT_SET_DEFAULT_LISTPACK_ENTRIES = 128
+)
+
+// SortSetConfig
+// 有序集合的配置
+type SortSetConfig struct {
+	//底层跳表的最大层数
+	MaxLevel int
+	//元素数量不超过这个值时使用紧凑编码(有序数组), 超过后自动转换成 map + 跳表
+	//0 表示不使用紧凑编码, 一开始就使用跳表
+	ListpackMaxEntries int
+	//排序方向, 默认 SCORE_ORDER_ASC 从小到大
+	//SCORE_ORDER_DESC 时按分数从大到小排列, Range/RangeByScore 从高到低返回, 排名0是分数最大的元素,
+	//RevRange/RevRangeByScore 反过来从低到高返回
+	Order ScoreOrder
+	//元素数量超过紧凑编码的阈值后使用的有序索引, 默认 SORT_SET_INDEX_SKIPLIST 跳表
+	//SORT_SET_INDEX_BTREE 使用带子树元素数量的B树, 对外的行为和跳表完全相同
+	Index string
+}
+
 // NewDefaultSortSet
 // 初始化一个默认的有序集合
 func NewDefaultSortSet[K comparable, V SkipListItem[K]](compare func(v1, v2 V) int) (*SortSet[K, V], error) {
@@ -10,45 +40,153 @@ func NewDefaultSortSet[K comparable, V SkipListItem[K]](compare func(v1, v2 V) i
 // 初始化一个有序集合, 可以设置底层跳表的最大层数
 // 没有特殊情况,不建议自定义层数
 func NewSortSet[K comparable, V SkipListItem[K]](level int, compare func(v1, v2 V) int) (*SortSet[K, V], error) {
-	skipTable, err := NewSkipTable[K, V](level, compare)
+	return NewSortSetWithConfig[K, V](SortSetConfig{
+		MaxLevel:           level,
+		ListpackMaxEntries: SORT_SET_DEFAULT_LISTPACK_ENTRIES,
+	}, compare)
+}
+
+// NewSortSetWithConfig
+// 使用自定义的配置初始化一个有序集合
+func NewSortSetWithConfig[K comparable, V SkipListItem[K]](config SortSetConfig, compare func(v1, v2 V) int) (*SortSet[K, V], error) {
+	set, err := NewScoredSortSet[K, float64, V](config, compare)
+	if err != nil {
+		return nil, err
+	}
+	return &SortSet[K, V]{set}, nil
+}
+
+// NewScoredSortSet
+// 使用自定义的分数类型和配置初始化一个有序集合
+func NewScoredSortSet[K comparable, S cmp.Ordered, V ScoredItem[K, S]](config SortSetConfig, compare func(v1, v2 V) int) (*ScoredSortSet[K, S, V], error) {
+	switch config.Index {
+	case "", SORT_SET_INDEX_SKIPLIST, SORT_SET_INDEX_BTREE:
+	default:
+		return nil, fmt.Errorf("NewSortSet unknown index %q", config.Index)
+	}
+	set := &ScoredSortSet[K, S, V]{
+		config:  config,
+		compare: compare,
+	}
+	if config.ListpackMaxEntries > 0 && compare == nil {
+		return nil, errors.New("NewSortSet compare function is nil")
+	}
+	enc, err := set.emptyEncoding()
 	if err != nil {
 		return nil, err
 	}
-	return &SortSet[K, V]{
-		member: make(map[K]*SkipListNode[K, V]),
-		sl:     skipTable,
-	}, nil
+	set.enc = enc
+	return set, nil
 }
 
-type SortSet[K comparable, V SkipListItem[K]] struct {
-	//使用map记录当前集合所有的元素
-	member map[K]*SkipListNode[K, V]
-	//底层的跳表
-	sl *SkipList[K, V]
+// emptyEncoding
+// 根据配置创建一个空的编码, 配置了紧凑编码时先使用紧凑编码
+func (set *ScoredSortSet[K, S, V]) emptyEncoding() (sortSetEncoding[K, S, V], error) {
+	if set.config.ListpackMaxEntries > 0 {
+		return newListpack[K, S, V](set.config.Order, set.compare), nil
+	}
+	enc, err := set.newIndexEncoding()
+	if err != nil {
+		return nil, err
+	}
+	return enc, nil
 }
 
-// 获取map中的元素
-func (set *SortSet[K, V]) getMember(key K) *SkipListNode[K, V] {
-	return set.member[key]
+// newEmptyEncoding
+// 和 emptyEncoding 相同, 只能在集合创建成功后使用, 这时配置已经检查过了, 不会出错
+func (set *ScoredSortSet[K, S, V]) newEmptyEncoding() sortSetEncoding[K, S, V] {
+	enc, err := set.emptyEncoding()
+	if err != nil {
+		panic(err)
+	}
+	return enc
 }
 
-// 向map中添加元素
-func (set *SortSet[K, V]) addMember(key K, member *SkipListNode[K, V]) {
-	set.member[key] = member
+// newIndexEncoding
+// 根据配置创建一个空的 map + 有序索引 的编码, 索引是跳表时使用集合的结点内存池
+func (set *ScoredSortSet[K, S, V]) newIndexEncoding() (*indexEncoding[K, S, V], error) {
+	enc, err := newIndexEncoding[K, S, V](set.config.Index, set.config.MaxLevel, set.config.Order, set.compare)
+	if err != nil {
+		return nil, err
+	}
+	enc.setNodePool(set.pool)
+	return enc, nil
 }
 
-// 删除map中的元素
-func (set *SortSet[K, V]) delMember(key K) {
-	delete(set.member, key)
+// ScoredSortSet
+// 有序集合, S 是分数的类型
+type ScoredSortSet[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
+	//底层数据的存储方式
+	enc sortSetEncoding[K, S, V]
+	//配置
+	config SortSetConfig
+	//两个 value score 相同时的比较函数
+	compare func(v1, v2 V) int
+	//跳表结点的内存池
+	pool *ScoredSkipListNodePool[K, S, V]
+	//当前的数据是否和快照共享, 共享时修改前要先复制一份(写时复制)
+	shared bool
+}
+
+// copyOnWrite
+// 如果当前数据和快照共享, 先复制一份再修改, 快照持有的数据永远不会被改动
+// B树索引的复制是 O(1) 的, 之后的修改只复制路径上的结点; 跳表索引和紧凑编码要复制整个集合
+func (set *ScoredSortSet[K, S, V]) copyOnWrite() {
+	if !set.shared {
+		return
+	}
+	set.enc = set.enc.clone()
+	set.shared = false
+}
+
+// convert
+// 紧凑编码的元素数量超过配置的阈值后, 转换成 map + 跳表
+func (set *ScoredSortSet[K, S, V]) convert() {
+	lp, ok := set.enc.(*listpack[K, S, V])
+	if !ok || lp.count() <= int64(set.config.ListpackMaxEntries) {
+		return
+	}
+	set.expand(lp)
+}
+
+// expand
+// 把紧凑编码转换成 map + 有序索引, 数组已经有序, 线性时间就可以构建索引
+func (set *ScoredSortSet[K, S, V]) expand(lp *listpack[K, S, V]) sortSetEncoding[K, S, V] {
+	enc, err := set.newIndexEncoding()
+	if err != nil {
+		//配置在创建时已经检查过了, 不会出错
+		panic(err)
+	}
+	enc.load(lp.entries)
+	set.enc = enc
+	return enc
+}
+
+// Encoding
+// 当前底层数据的存储方式, listpack 或者 skiplist
+func (set *ScoredSortSet[K, S, V]) Encoding() string {
+	return set.enc.name()
 }
 
 // Add
-// 向sortSet中添加元素
-func (set *SortSet[K, V]) Add(items ...V) int {
+// 向sortSet中添加元素, 元素已经存在时更新分数, 同一个key出现多次时使用最后一个
+// 返回添加和更新分数的元素数量(不同 key 的数量), 只需要新添加的元素数量时使用 ZAdd
+// 分数是 NaN 的元素会被忽略, 需要返回错误时使用 ZAdd
+func (set *ScoredSortSet[K, S, V]) Add(items ...V) int {
 	l := len(items)
 	if l == 0 {
 		return 0
 	}
+	set.copyOnWrite()
+	defer set.convert()
+	//只添加一个元素时不需要去重,也就不需要额外分配内存
+	if l == 1 {
+		if scoreIsNaN(items[0].Score()) {
+			return 0
+		}
+		set.enc.add(items[0])
+		return 1
+	}
 	//记录添加了多少个元素
 	op := make(map[K]struct{})
 
@@ -56,139 +194,133 @@ func (set *SortSet[K, V]) Add(items ...V) int {
 	//想一下,为啥是从后向前遍历
 	for l >= 0 {
 		//如果这次已经操作过这个元素了,就不用再操作了
-		if _, e := op[items[l].Key()]; e {
+		if _, e := op[items[l].Key()]; e || scoreIsNaN(items[l].Score()) {
 			l--
 			continue
 		}
-		if member := set.getMember(items[l].Key()); member == nil {
-			//如果当前集合中没有这个元素了,就添加
-			node := set.sl.InsertByScore(items[l].Score(), items[l])
-			set.addMember(items[l].Key(), node)
-		} else {
-			//如果当前集合中已经有这个元素了,就只更新分数就好了
-			set.sl.UpdateScore(member, items[l].Score())
-		}
+		set.enc.add(items[l])
 		op[items[l].Key()] = struct{}{}
 		l--
 	}
 	return len(op)
 }
 
+// BulkLoad
+// 使用已经排好序(和集合的排序方向相同, 默认 score 从小到大, score 相同时按 compare 从小到大)的数据初始化sortSet, 复杂度 O(n)
+// 如果sortSet不是空的, 或者数据没有排好序, 或者有重复的key, 或者有 NaN 的分数, 会退化成逐个 Add
+// 返回添加的元素数量
+func (set *ScoredSortSet[K, S, V]) BulkLoad(items []V) int {
+	if len(items) == 0 {
+		return 0
+	}
+	if set.enc.count() != 0 || slices.ContainsFunc(items, func(item V) bool { return scoreIsNaN(item.Score()) }) {
+		return set.Add(items...)
+	}
+	set.copyOnWrite()
+	enc := set.enc
+	if lp, ok := enc.(*listpack[K, S, V]); ok && len(items) > set.config.ListpackMaxEntries {
+		//数据太多,直接使用有序索引
+		enc = set.expand(lp)
+	}
+	if !enc.bulkLoad(items) {
+		return set.Add(items...)
+	}
+	return len(items)
+}
+
 // Count
 // sortSet中元素数量
-func (set *SortSet[K, V]) Count() int64 {
-	return set.sl.Size()
+func (set *ScoredSortSet[K, S, V]) Count() int64 {
+	return set.enc.count()
 }
 
 // Rank
-// 返回有序集合中指定成员的索引(从0开始)不存在返回 -1
-func (set *SortSet[K, V]) Rank(key K) int64 {
-	member := set.getMember(key)
-	if member == nil {
+// 返回有序集合中指定成员的索引(从0开始)不存在返回 0, 和排名第一的元素相同, 需要区分时使用 ZRank
+func (set *ScoredSortSet[K, S, V]) Rank(key K) int64 {
+	rank := set.enc.rank(key)
+	if rank == 0 {
 		return 0
 	}
-	return set.sl.GetNodeRank(member) - 1
+	return rank - 1
 }
 
 // RevRank
 // 返回有序集合中指定成员的索引(从0开始)不存在返回 -1
-func (set *SortSet[K, V]) RevRank(key K) int64 {
-	member := set.getMember(key)
-	if member == nil {
+func (set *ScoredSortSet[K, S, V]) RevRank(key K) int64 {
+	rank := set.enc.rank(key)
+	if rank == 0 {
 		return -1
 	}
-	rank := set.sl.GetNodeRank(member)
-	return set.sl.Size() - rank
+	return set.enc.count() - rank
 }
 
 // Score
-// 获取元素分数
-func (set *SortSet[K, V]) Score(key K) float64 {
-	member := set.getMember(key)
-	if member == nil {
-		return 0
-	}
-	return member.score
+// 获取元素分数, 元素不存在时返回分数类型的零值, 需要区分时使用 ZScore
+func (set *ScoredSortSet[K, S, V]) Score(key K) S {
+	score, _ := set.enc.score(key)
+	return score
 }
 
 // Remove
-// 移除有序集合中的一个或多个成员
-func (set *SortSet[K, V]) Remove(keys ...K) int {
+// 移除有序集合中的一个或多个成员, 总是返回 0, 需要实际移除的成员数量时使用 ZRem
+func (set *ScoredSortSet[K, S, V]) Remove(keys ...K) int {
+	set.copyOnWrite()
 	for _, key := range keys {
-		if member := set.getMember(key); member != nil {
-			set.delMember(key)
-			set.sl.Delete(member, set.sl.GetUpdateList(member))
-		}
+		set.enc.remove(key)
 	}
 	return 0
 }
 
 // RemoveRangeByRank
 // 移除有序集合中给定的排名区间的所有成员
-func (set *SortSet[K, V]) RemoveRangeByRank(min, max int64) int {
-	//先根据rank范围 查找node
-	result := set.Range(min, max)
-	if len(result) == 0 {
+func (set *ScoredSortSet[K, S, V]) RemoveRangeByRank(min, max int64) int {
+	if set.enc.count() == 0 {
 		return 0
 	}
-
-	//删除数据需要的各层结点信息(路径)
-	//想一下,为啥只需要获取一次路径就行呢?????
-	var updateList []*SkipListNode[K, V]
-	for _, key := range result {
-		if member := set.getMember(key.Key()); member != nil {
-			if updateList == nil {
-				updateList = set.sl.GetUpdateList(member)
-			}
-			set.delMember(key.Key())
-			set.sl.Delete(member, updateList)
-		}
+	//处理范围时负数的情况
+	if min < 0 {
+		min = set.enc.count() + min
+	}
+	if max < 0 {
+		max = set.enc.count() + max
 	}
-	return len(result)
+	//给定的范围出错了
+	if min > max {
+		return 0
+	}
+	set.copyOnWrite()
+	//索引是从0开始的, rank是从1开始,所以这里要 +1
+	return set.enc.removeRangeByRank(min+1, max+1)
 }
 
 // RemoveRangeByScore
 // 移除有序集合中给定的分数区间的所有成员
-func (set *SortSet[K, V]) RemoveRangeByScore(min, max float64) int {
-	//先根据score范围获取node
-	result := set.RangeByScore(&SkipListFindRange{
+func (set *ScoredSortSet[K, S, V]) RemoveRangeByScore(min, max S) int {
+	if set.enc.count() == 0 {
+		return 0
+	}
+	set.copyOnWrite()
+	return set.enc.removeRangeByScore(&ScoreRange[S]{
 		Min:    min,
 		Max:    max,
 		MinInf: false,
 		MaxInf: false,
 	})
-
-	if len(result) == 0 {
-		return 0
-	}
-	//删除数据需要的各层结点信息(路径)
-	//想一下,为啥只需要获取一次路径就行呢?????
-	var updateList []*SkipListNode[K, V]
-	for _, key := range result {
-		if member := set.getMember(key.Key()); member != nil {
-			if updateList == nil {
-				updateList = set.sl.GetUpdateList(member)
-			}
-			set.delMember(key.Key())
-			set.sl.Delete(member, updateList)
-		}
-	}
-	return len(result)
 }
 
 // Range
 // 通过索引区间返回有序集合指定区间内的成员,分数从低到高
-func (set *SortSet[K, V]) Range(min, max int64) (result []V) {
-	if set.sl.Size() == 0 {
+func (set *ScoredSortSet[K, S, V]) Range(min, max int64) (result []V) {
+	if set.enc.count() == 0 {
 		return
 	}
 
 	//处理范围时负数的情况
 	if min < 0 {
-		min = set.sl.Size() + min
+		min = set.enc.count() + min
 	}
 	if max < 0 {
-		max = set.sl.Size() + max
+		max = set.enc.count() + max
 	}
 
 	//给定的范围出错了
@@ -196,71 +328,49 @@ func (set *SortSet[K, V]) Range(min, max int64) (result []V) {
 		return
 	}
 	//索引是从0开始的, 跳表中的rank是从1开始,所以这里要 +1
-	nodes := set.sl.GetValuesByRank(min+1, max+1)
-	if len(nodes) == 0 {
-		return
-	}
-	result = make([]V, len(nodes))
-	for i, node := range nodes {
-		result[i] = node
+	result = set.enc.valuesByRank(min+1, max+1)
+	if len(result) == 0 {
+		return nil
 	}
 	return
 }
 
 // RevRange
 // 返回有序集中指定区间内的成员，通过索引，分数从高到低排序
-func (set *SortSet[K, V]) RevRange(min, max int64) (result []V) {
-	if set.sl.Size() == 0 {
+func (set *ScoredSortSet[K, S, V]) RevRange(min, max int64) (result []V) {
+	count := set.enc.count()
+	if count == 0 {
 		return
 	}
-	//反向查找也是按照正向查找来做的
-	//只不过是把反向查找的范围转换成正向查找的范围
-	//最后把查找的结果反向
+	//处理范围时负数的情况
 	if min < 0 {
-		min = -min
-	} else {
-		if set.sl.Size() >= min {
-			min = set.sl.Size() - min
-		} else {
-			min = set.sl.Size()
-		}
+		min = count + min
 	}
-
 	if max < 0 {
-		max = -max
-	} else {
-		if set.sl.Size() > max {
-			max = set.sl.Size() - max
-		} else {
-			max = 1
-		}
+		max = count + max
 	}
-
-	if max > min {
-		return
+	if min < 0 {
+		min = 0
 	}
-	nodes := set.sl.GetValuesByRank(max, min)
-	l := len(nodes)
-	if l == 0 {
+	//给定的范围出错了
+	if min > max || min >= count {
 		return
 	}
-	result = make([]V, l)
-
-	l--
-	//翻转查找结果
-	for i, node := range nodes {
-		result[l-i] = node
+	//从最后一个元素开始沿着后指针向前查找, 不需要再翻转结果
+	result = set.enc.revValuesByRank(min+1, max+1)
+	if len(result) == 0 {
+		return nil
 	}
 	return
 }
 
 // RangeByScore
 // 返回有序集中指定分数区间内的成员，分数从低到高排序
-func (set *SortSet[K, V]) RangeByScore(findRange *SkipListFindRange) (result []V) {
-	if findRange == nil || set.sl.Size() == 0 {
+func (set *ScoredSortSet[K, S, V]) RangeByScore(findRange *ScoreRange[S]) (result []V) {
+	if findRange == nil || set.enc.count() == 0 {
 		return
 	}
-	result = set.sl.GetValuesByScore(findRange)
+	result = set.enc.valuesByScore(findRange)
 	if len(result) == 0 {
 		return
 	}
@@ -269,28 +379,74 @@ func (set *SortSet[K, V]) RangeByScore(findRange *SkipListFindRange) (result []V
 
 // RevRangeByScore
 // 返回有序集中指定分数区间内的成员，分数从高到低排序
-func (set *SortSet[K, V]) RevRangeByScore(findRange *SkipListFindRange) (result []V) {
-	if findRange == nil || set.sl.Size() == 0 {
+func (set *ScoredSortSet[K, S, V]) RevRangeByScore(findRange *ScoreRange[S]) (result []V) {
+	if findRange == nil || set.enc.count() == 0 {
 		return
 	}
 
-	//分数从高到低的查找, 本质上和从低到高查找是一样的
-	//不同点是,从高到低查找时,给的范围要调换
-	//最后再把查找的结果翻转一下
-	findRange.Max, findRange.Min = findRange.Min, findRange.Max
-	findRange.MaxInf, findRange.MinInf = findRange.MinInf, findRange.MaxInf
+	//从高到低查找时, Min 是开始的分数(较大的), Max 是结束的分数(较小的), 转换成正常的范围
+	//不修改调用者传入的 findRange
+	r := ScoreRange[S]{
+		Min:    findRange.Max,
+		Max:    findRange.Min,
+		MinInf: findRange.MaxInf,
+		MaxInf: findRange.MinInf,
+		MinEx:  findRange.MaxEx,
+		MaxEx:  findRange.MinEx,
+	}
+	//从范围的结尾开始沿着后指针向前查找, 不需要再翻转结果
+	result = set.enc.revValuesByScore(&r)
+	if len(result) == 0 {
+		return nil
+	}
+	return
+}
 
-	nodes := set.sl.GetValuesByScore(findRange)
-	l := len(nodes)
-	if l == 0 {
-		return
+// SetNodePool
+// 设置底层跳表结点的内存池, 删除的结点会被回收复用
+func (set *ScoredSortSet[K, S, V]) SetNodePool(pool *ScoredSkipListNodePool[K, S, V]) {
+	set.pool = pool
+	if enc, ok := set.enc.(*indexEncoding[K, S, V]); ok {
+		enc.setNodePool(pool)
 	}
-	result = make([]V, l)
+}
 
-	l--
-	//翻转查找结果
-	for i, node := range nodes {
-		result[l-i] = node
+// RankedValue
+// 带排名和分数的元素
+type RankedValue[S cmp.Ordered, V any] struct {
+	Value V
+	//排名(从0开始), 反向查询时是反向排名
+	Rank  int64
+	Score S
+}
+
+// Around
+// 返回 key 和它前面 before 个、后面 after 个元素, 以及它们的排名(从0开始)和分数, 比如排行榜上 "我和我前后5名"
+// 只需要一次查找, 到头或者到尾时返回的元素会少一些, key 不存在时返回 nil
+func (set *ScoredSortSet[K, S, V]) Around(key K, before, after int64) []RankedValue[S, V] {
+	if before < 0 || after < 0 {
+		return nil
 	}
-	return
+	result := set.enc.around(key, before, after)
+	//跳表中的rank是从1开始的, 索引是从0开始
+	for i := range result {
+		result[i].Rank--
+	}
+	return result
+}
+
+// RevAround
+// 和 Around 相同, 但是按反向顺序(分数从高到低), 排名是反向排名, before 是反向顺序中排在前面的元素
+func (set *ScoredSortSet[K, S, V]) RevAround(key K, before, after int64) []RankedValue[S, V] {
+	if before < 0 || after < 0 {
+		return nil
+	}
+	//反向顺序中前面的元素, 就是正向顺序中后面的元素
+	result := set.enc.around(key, after, before)
+	count := set.enc.count()
+	slices.Reverse(result)
+	for i := range result {
+		result[i].Rank = count - result[i].Rank
+	}
+	return result
 }
@@ -0,0 +1,69 @@
+package skiptablev2
+
+import "cmp"
+
+const (
+	//SORT_SET_ENCODING_LISTPACK
+	//紧凑编码, 使用有序数组存储元素
+	SORT_SET_ENCODING_LISTPACK = "listpack"
+	//SORT_SET_ENCODING_SKIPLIST
+	//使用 map + 跳表 存储元素
+	SORT_SET_ENCODING_SKIPLIST = "skiplist"
+)
+
+// sortSetEncoding
+// 有序集合底层数据的存储方式, 不同的存储方式对外的行为完全相同
+// 排名都是从1开始的
+type sortSetEncoding[K comparable, S cmp.Ordered, V ScoredItem[K, S]] interface {
+	//编码的名字
+	name() string
+	//元素数量
+	count() int64
+	//添加元素, 元素已经存在时只更新分数
+	add(item V)
+	//使用指定的分数(而不是 item.Score())添加元素, 其余和 add 相同
+	addScore(score S, item V)
+	//按顺序遍历所有元素和它们的分数, fn 返回 false 时停止
+	scan(fn func(score S, value V) bool)
+	//删除元素, 返回元素是否存在
+	remove(key K) bool
+	//获取元素的分数
+	score(key K) (S, bool)
+	//获取元素的排名, 不存在返回0
+	rank(key K) int64
+	//根据排名范围查找元素, 和 SkipList.GetValuesByRank 的规则相同
+	valuesByRank(left, right int64) []V
+	//根据分数范围查找元素, 和 SkipList.GetValuesByScore 的规则相同
+	valuesByScore(findRange *ScoreRange[S]) []V
+	//根据反向排名范围查找元素, 和 SkipList.GetRevValuesByRank 的规则相同
+	revValuesByRank(left, right int64) []V
+	//根据分数范围反向查找元素, 和 SkipList.GetRevValuesByScore 的规则相同
+	revValuesByScore(findRange *ScoreRange[S]) []V
+	//返回元素和它前面 before 个、后面 after 个元素, 以及它们的排名和分数, 元素不存在返回 nil
+	around(key K, before, after int64) []RankedValue[S, V]
+	//排名范围内元素的数量和分数之和
+	aggregateByRank(left, right int64) ScoreAggregate
+	//分数范围内元素的数量和分数之和
+	aggregateByScore(findRange *ScoreRange[S]) ScoreAggregate
+	//排在分数 score 前面的元素数量, 分数等于 score 的元素数量, 排在前面的不同分数的数量
+	tieCounts(score S) (before, equal, groups int64)
+	//删除排名范围内的元素, 返回删除的数量
+	removeRangeByRank(left, right int64) int
+	//删除分数范围内的元素, 返回删除的数量
+	removeRangeByScore(findRange *ScoreRange[S]) int
+	//使用排好序的数据初始化, 只能在没有元素时使用, 数据没有排好序或者有重复的key时返回false, 不做任何修改
+	bulkLoad(items []V) bool
+	//复制一份, 复制后原来的编码只能读(它和快照共享)
+	clone() sortSetEncoding[K, S, V]
+	//把排名 > rank 的元素移到一个同样编码的新编码中返回
+	split(rank int64) sortSetEncoding[K, S, V]
+	//把 other 的所有元素接到后面, other 变成空的, 调用者需要保证 other 的元素都排在后面并且 key 不重复
+	//other 和自己不是同一种编码时不做任何修改, 返回 false
+	concat(other sortSetEncoding[K, S, V]) bool
+	//检查数据结构是否正确
+	validate() error
+	//统计信息
+	stats() SortSetStats
+	//一个元素占用的内存, 元素不存在返回 false
+	memoryUsage(key K) (int64, bool)
+}
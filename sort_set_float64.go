@@ -0,0 +1,59 @@
+package skiptablev2
+
+// 分数是 float64 的有序集合类型, 和 skip_list_float64.go 中的跳表一样, 是定义的新类型而不是泛型类型别名
+// 参数或者返回值是有序集合、快照、内存池的方法在这里重新定义, 其余的方法直接使用 ScoredSortSet 的方法
+
+// SortSet
+// 分数是 float64 的有序集合
+type SortSet[K comparable, V SkipListItem[K]] struct {
+	*ScoredSortSet[K, float64, V]
+}
+
+// SortSetSnapshot
+// 分数是 float64 的有序集合快照
+type SortSetSnapshot[K comparable, V SkipListItem[K]] struct {
+	*ScoredSortSetSnapshot[K, float64, V]
+}
+
+// SetNodePool
+// 设置底层跳表结点的内存池, 和 ScoredSortSet.SetNodePool 相同
+func (set *SortSet[K, V]) SetNodePool(pool *SkipListNodePool[K, V]) {
+	set.ScoredSortSet.SetNodePool(pool.scored())
+}
+
+// Snapshot
+// 获取当前有序集合的只读快照, 和 ScoredSortSet.Snapshot 相同
+func (set *SortSet[K, V]) Snapshot() *SortSetSnapshot[K, V] {
+	return &SortSetSnapshot[K, V]{set.ScoredSortSet.Snapshot()}
+}
+
+// Merge
+// 把 other 中的元素合并到有序集合中, 和 ScoredSortSet.Merge 相同
+func (set *SortSet[K, V]) Merge(other *SortSet[K, V], policy MergePolicy[float64]) (MergeResult, error) {
+	return set.ScoredSortSet.Merge(other.ScoredSortSet, policy)
+}
+
+// SplitAtRank
+// 在排名 rank 处切开, 和 ScoredSortSet.SplitAtRank 相同
+func (set *SortSet[K, V]) SplitAtRank(rank int64) (*SortSet[K, V], error) {
+	return sortSetOf(set.ScoredSortSet.SplitAtRank(rank))
+}
+
+// SplitAtScore
+// 在分数 score 处切开, 和 ScoredSortSet.SplitAtScore 相同
+func (set *SortSet[K, V]) SplitAtScore(score float64) (*SortSet[K, V], error) {
+	return sortSetOf(set.ScoredSortSet.SplitAtScore(score))
+}
+
+// Concat
+// 把 other 的所有元素接到有序集合的后面, 和 ScoredSortSet.Concat 相同
+func (set *SortSet[K, V]) Concat(other *SortSet[K, V]) error {
+	return set.ScoredSortSet.Concat(other.ScoredSortSet)
+}
+
+func sortSetOf[K comparable, V SkipListItem[K]](set *ScoredSortSet[K, float64, V], err error) (*SortSet[K, V], error) {
+	if err != nil {
+		return nil, err
+	}
+	return &SortSet[K, V]{set}, nil
+}
@@ -0,0 +1,478 @@
+package skiptablev2
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+)
+
+const (
+	//SORT_SET_INDEX_SKIPLIST
+	//使用跳表作为有序集合的有序索引(默认)
+	SORT_SET_INDEX_SKIPLIST = "skiplist"
+	//SORT_SET_INDEX_BTREE
+	//使用带子树元素数量的B树作为有序集合的有序索引
+	SORT_SET_INDEX_BTREE = "btree"
+)
+
+// orderedIndex
+// 有序集合底层的有序索引, 元素按 (score, value) 排序, 不同的实现对外的行为完全相同
+// 排名都是从1开始的, 跳表(ScoredSkipList)和B树(btreeIndex)都实现了这个接口
+type orderedIndex[K comparable, S cmp.Ordered, V ScoredItem[K, S]] interface {
+	//元素数量
+	Size() int64
+	//插入元素
+	insert(score S, value V)
+	//删除元素, 返回元素是否存在
+	remove(score S, value V) bool
+	//更新元素的分数
+	updateValueScore(value V, oldScore, newScore S)
+	//获取元素的排名, 不存在返回0
+	valueRank(score S, value V) int64
+	//删除排名范围内的元素, 返回删除的数量, fn 按顺序返回被删除的元素
+	removeRangeByRank(left, right int64, fn func(score S, value V)) int64
+	//删除分数范围内的元素, 返回删除的数量, fn 按顺序返回被删除的元素
+	removeRangeByScore(findRange *ScoreRange[S], fn func(score S, value V)) int64
+	//按顺序遍历所有元素, fn 返回 false 时停止
+	scan(fn func(score S, value V) bool)
+	//使用排好序的元素初始化, 只能在没有元素时使用, 复杂度 O(n)
+	load(entries []listpackEntry[S, V])
+	//把排名 > rank 的元素移到一个新的索引中返回
+	splitIndex(rank int64) orderedIndex[K, S, V]
+	//把 other 的所有元素接到后面, other 变成空的, other 和自己不是同一种索引时返回 false
+	concatIndex(other orderedIndex[K, S, V]) bool
+	//根据排名范围查找元素
+	GetValuesByRank(left, right int64) []V
+	//根据反向排名范围查找元素
+	GetRevValuesByRank(left, right int64) []V
+	//根据分数范围查找元素
+	GetValuesByScore(findRange *ScoreRange[S]) []V
+	//根据分数范围反向查找元素
+	GetRevValuesByScore(findRange *ScoreRange[S]) []V
+	//排名范围内元素的数量和分数之和
+	AggregateByRank(left, right int64) ScoreAggregate
+	//分数范围内元素的数量和分数之和
+	AggregateByScore(findRange *ScoreRange[S]) ScoreAggregate
+	//排在分数 score 前面的元素数量, 分数等于 score 的元素数量, 排在前面的不同分数的数量
+	tieCounts(score S) (before, equal, groups int64)
+	//复制一份, 复制后原来的索引只能读, B树是 O(1) 的(结构共享), 跳表是 O(n) 的
+	cloneIndex() orderedIndex[K, S, V]
+	//检查数据结构是否正确
+	Validate() error
+	//所有结点占用的内存
+	indexBytes() int64
+	//一个元素在索引中占用的内存
+	memberBytes(score S, value V) int64
+}
+
+var _ orderedIndex[string, float64, SkipListItem[string]] = (*ScoredSkipList[string, float64, SkipListItem[string]])(nil)
+var _ orderedIndex[string, float64, SkipListItem[string]] = (*btreeIndex[string, float64, SkipListItem[string]])(nil)
+
+// newOrderedIndex
+// 根据名字创建一个空的有序索引
+func newOrderedIndex[K comparable, S cmp.Ordered, V ScoredItem[K, S]](name string, level int, order ScoreOrder, compare func(v1, v2 V) int) (orderedIndex[K, S, V], error) {
+	switch name {
+	case "", SORT_SET_INDEX_SKIPLIST:
+		list, err := NewScoredSkipTableWithOrder[K, S, V](level, order, compare)
+		if err != nil {
+			return nil, err
+		}
+		return list, nil
+	case SORT_SET_INDEX_BTREE:
+		return newBTreeIndex[K, S, V](order, compare)
+	default:
+		return nil, fmt.Errorf("unknown sort set index %q", name)
+	}
+}
+
+// findNode
+// 查找 (score, value) 对应的结点和它的排名, 不存在返回 nil
+func (list *ScoredSkipList[K, S, V]) findNode(score S, value V) (*ScoredSkipListNode[K, S, V], int64) {
+	rank := int64(0)
+	t := list.head
+	for i := list.level - 1; i >= 0; i-- {
+		for t.Next(i) != nil && list.before(t.Next(i).score, t.Next(i).value, score, value) {
+			rank += t.Span(i)
+			t = t.Next(i)
+		}
+	}
+	t = t.Next(0)
+	if t == nil || t.score != score || list.compare(t.value, value) != 0 {
+		return nil, 0
+	}
+	return t, rank + 1
+}
+
+func (list *ScoredSkipList[K, S, V]) insert(score S, value V) {
+	list.InsertByScore(score, value)
+}
+
+func (list *ScoredSkipList[K, S, V]) remove(score S, value V) bool {
+	//查找时记录的路径就是删除需要的 update, 只需要查找一次
+	update := list.pathTo(score, value)
+	node := update[0].Next(0)
+	if node == nil || node.score != score || list.compare(node.value, value) != 0 {
+		return false
+	}
+	list.Delete(node, update)
+	return true
+}
+
+func (list *ScoredSkipList[K, S, V]) updateValueScore(value V, oldScore, newScore S) {
+	if node, _ := list.findNode(oldScore, value); node != nil {
+		list.UpdateScore(node, newScore)
+	}
+}
+
+func (list *ScoredSkipList[K, S, V]) valueRank(score S, value V) int64 {
+	_, rank := list.findNode(score, value)
+	return rank
+}
+
+func (list *ScoredSkipList[K, S, V]) removeRangeByRank(left, right int64, fn func(score S, value V)) int64 {
+	return list.DeleteRangeByRank(left, right, fn)
+}
+
+func (list *ScoredSkipList[K, S, V]) removeRangeByScore(findRange *ScoreRange[S], fn func(score S, value V)) int64 {
+	return list.DeleteRangeByScore(findRange, fn)
+}
+
+func (list *ScoredSkipList[K, S, V]) scan(fn func(score S, value V) bool) {
+	for t := list.head.Next(0); t != nil && fn(t.score, t.value); t = t.Next(0) {
+	}
+}
+
+func (list *ScoredSkipList[K, S, V]) load(entries []listpackEntry[S, V]) {
+	b := list.newBuilder()
+	for _, e := range entries {
+		b.append(list.randLevel(), e.score, e.value)
+	}
+	b.finish()
+}
+
+func (list *ScoredSkipList[K, S, V]) splitIndex(rank int64) orderedIndex[K, S, V] {
+	nl, _ := list.SplitAtRank(rank)
+	return nl
+}
+
+func (list *ScoredSkipList[K, S, V]) concatIndex(other orderedIndex[K, S, V]) bool {
+	o, ok := other.(*ScoredSkipList[K, S, V])
+	return ok && list.Concat(o) == nil
+}
+
+func (list *ScoredSkipList[K, S, V]) cloneIndex() orderedIndex[K, S, V] {
+	return list.clone()
+}
+
+// memberMap
+// indexEncoding 中 key 到元素的映射
+type memberMap[K comparable, T any] interface {
+	get(key K) (T, bool)
+	//添加元素, 已经存在时更新
+	set(key K, value T)
+	//删除元素, 不存在时不做任何修改
+	del(key K)
+	count() int
+	//遍历所有元素, 顺序是不确定的, fn 返回 false 时停止
+	each(fn func(key K, value T) bool)
+	//复制一份, 复制后原来的映射只能读
+	cloneMap() memberMap[K, T]
+}
+
+// hashMap
+// 使用 go 的 map 实现的 memberMap, 复制是 O(n) 的
+type hashMap[K comparable, T any] map[K]T
+
+func (m hashMap[K, T]) get(key K) (T, bool) {
+	value, ok := m[key]
+	return value, ok
+}
+
+func (m hashMap[K, T]) set(key K, value T) {
+	m[key] = value
+}
+
+func (m hashMap[K, T]) del(key K) {
+	delete(m, key)
+}
+
+func (m hashMap[K, T]) count() int {
+	return len(m)
+}
+
+func (m hashMap[K, T]) each(fn func(key K, value T) bool) {
+	for key, value := range m {
+		if !fn(key, value) {
+			return
+		}
+	}
+}
+
+func (m hashMap[K, T]) cloneMap() memberMap[K, T] {
+	return maps.Clone(m)
+}
+
+// newMemberMap
+// 有序索引可以结构共享(B树)时使用同样可以结构共享的 hamt, 复制整个编码是 O(1) 的
+// 跳表的结点被多个前驱结点指向, 不能只复制路径, 使用更快的 go map
+func newMemberMap[K comparable, S cmp.Ordered, V ScoredItem[K, S]](idx orderedIndex[K, S, V]) memberMap[K, indexMember[S, V]] {
+	if _, ok := idx.(*btreeIndex[K, S, V]); ok {
+		return newHamt[K, indexMember[S, V]]()
+	}
+	return hashMap[K, indexMember[S, V]]{}
+}
+
+// indexMember
+// 集合中的一个元素和它当前的分数
+type indexMember[S cmp.Ordered, V any] struct {
+	score S
+	value V
+}
+
+// indexEncoding
+// 使用map记录所有的元素, 使用任意一种有序索引排序
+// 元素在索引中的位置由 (score, value) 决定, 所以map中要记录元素当前的分数
+type indexEncoding[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
+	//编码的名字, 和索引的名字相同
+	indexName string
+	//记录当前集合所有的元素
+	member memberMap[K, indexMember[S, V]]
+	//底层的有序索引
+	idx orderedIndex[K, S, V]
+	//排序规则, 检查 bulkLoad 的数据是否有序
+	order scoreOrder[S, V]
+}
+
+func newIndexEncoding[K comparable, S cmp.Ordered, V ScoredItem[K, S]](name string, level int, order ScoreOrder, compare func(v1, v2 V) int) (*indexEncoding[K, S, V], error) {
+	//默认使用跳表
+	if name == "" {
+		name = SORT_SET_INDEX_SKIPLIST
+	}
+	idx, err := newOrderedIndex[K, S, V](name, level, order, compare)
+	if err != nil {
+		return nil, err
+	}
+	return &indexEncoding[K, S, V]{
+		indexName: name,
+		member:    newMemberMap(idx),
+		idx:       idx,
+		order: scoreOrder[S, V]{
+			compare: compare,
+			desc:    order == SCORE_ORDER_DESC,
+		},
+	}, nil
+}
+
+// setNodePool
+// 有序索引是跳表时设置跳表结点的内存池, B树没有结点内存池
+func (enc *indexEncoding[K, S, V]) setNodePool(pool *ScoredSkipListNodePool[K, S, V]) {
+	if list, ok := enc.idx.(*ScoredSkipList[K, S, V]); ok {
+		list.SetNodePool(pool)
+	}
+}
+
+func (enc *indexEncoding[K, S, V]) name() string {
+	return enc.indexName
+}
+
+func (enc *indexEncoding[K, S, V]) count() int64 {
+	return enc.idx.Size()
+}
+
+func (enc *indexEncoding[K, S, V]) add(item V) {
+	enc.addScore(item.Score(), item)
+}
+
+func (enc *indexEncoding[K, S, V]) scan(fn func(score S, value V) bool) {
+	enc.idx.scan(fn)
+}
+
+// addScore
+// 使用指定的分数添加元素, 从紧凑编码转换时 value 中的分数可能是旧的, 要使用紧凑编码中记录的分数
+func (enc *indexEncoding[K, S, V]) addScore(score S, item V) {
+	key := item.Key()
+	m, ok := enc.member.get(key)
+	if !ok {
+		enc.idx.insert(score, item)
+		enc.member.set(key, indexMember[S, V]{score: score, value: item})
+		return
+	}
+	//已经有这个元素了,只更新分数, 和跳表一样保留原来的value
+	if m.score == score {
+		return
+	}
+	enc.idx.updateValueScore(m.value, m.score, score)
+	m.score = score
+	enc.member.set(key, m)
+}
+
+func (enc *indexEncoding[K, S, V]) remove(key K) bool {
+	m, ok := enc.member.get(key)
+	if !ok {
+		return false
+	}
+	enc.member.del(key)
+	enc.idx.remove(m.score, m.value)
+	return true
+}
+
+func (enc *indexEncoding[K, S, V]) score(key K) (S, bool) {
+	m, ok := enc.member.get(key)
+	return m.score, ok
+}
+
+func (enc *indexEncoding[K, S, V]) rank(key K) int64 {
+	m, ok := enc.member.get(key)
+	if !ok {
+		return 0
+	}
+	return enc.idx.valueRank(m.score, m.value)
+}
+
+func (enc *indexEncoding[K, S, V]) valuesByRank(left, right int64) []V {
+	return enc.idx.GetValuesByRank(left, right)
+}
+
+func (enc *indexEncoding[K, S, V]) valuesByScore(findRange *ScoreRange[S]) []V {
+	return enc.idx.GetValuesByScore(findRange)
+}
+
+func (enc *indexEncoding[K, S, V]) revValuesByRank(left, right int64) []V {
+	return enc.idx.GetRevValuesByRank(left, right)
+}
+
+func (enc *indexEncoding[K, S, V]) revValuesByScore(findRange *ScoreRange[S]) []V {
+	return enc.idx.GetRevValuesByScore(findRange)
+}
+
+func (enc *indexEncoding[K, S, V]) around(key K, before, after int64) []RankedValue[S, V] {
+	rank := enc.rank(key)
+	if rank == 0 {
+		return nil
+	}
+	//先限制前后元素的数量, before、after 很大(比如 math.MaxInt64)时直接相加会溢出
+	left := rank - min(before, rank-1)
+	values := enc.idx.GetValuesByRank(left, rank+min(after, enc.idx.Size()-rank))
+	result := make([]RankedValue[S, V], len(values))
+	for i, value := range values {
+		m, _ := enc.member.get(value.Key())
+		result[i] = RankedValue[S, V]{Value: value, Score: m.score, Rank: left + int64(i)}
+	}
+	return result
+}
+
+func (enc *indexEncoding[K, S, V]) aggregateByRank(left, right int64) ScoreAggregate {
+	return enc.idx.AggregateByRank(left, right)
+}
+
+func (enc *indexEncoding[K, S, V]) aggregateByScore(findRange *ScoreRange[S]) ScoreAggregate {
+	return enc.idx.AggregateByScore(findRange)
+}
+
+func (enc *indexEncoding[K, S, V]) tieCounts(score S) (int64, int64, int64) {
+	return enc.idx.tieCounts(score)
+}
+
+func (enc *indexEncoding[K, S, V]) removeRangeByRank(left, right int64) int {
+	return int(enc.idx.removeRangeByRank(left, right, enc.delMember))
+}
+
+func (enc *indexEncoding[K, S, V]) removeRangeByScore(findRange *ScoreRange[S]) int {
+	return int(enc.idx.removeRangeByScore(findRange, enc.delMember))
+}
+
+// delMember
+// 有序索引删除一段元素时, 从map中删除对应的元素
+func (enc *indexEncoding[K, S, V]) delMember(_ S, value V) {
+	enc.member.del(value.Key())
+}
+
+func (enc *indexEncoding[K, S, V]) bulkLoad(items []V) bool {
+	keys := make(map[K]struct{}, len(items))
+	for i, item := range items {
+		if i > 0 && enc.order.before(item.Score(), item, items[i-1].Score(), items[i-1]) {
+			return false
+		}
+		if _, e := keys[item.Key()]; e {
+			return false
+		}
+		keys[item.Key()] = struct{}{}
+	}
+	entries := make([]listpackEntry[S, V], len(items))
+	for i, item := range items {
+		entries[i] = listpackEntry[S, V]{score: item.Score(), value: item}
+	}
+	enc.load(entries)
+	return true
+}
+
+// load
+// 使用排好序并且 key 不重复的元素初始化, 只能在没有元素时使用, 复杂度 O(n)
+func (enc *indexEncoding[K, S, V]) load(entries []listpackEntry[S, V]) {
+	enc.idx.load(entries)
+	for _, e := range entries {
+		enc.member.set(e.value.Key(), indexMember[S, V]{score: e.score, value: e.value})
+	}
+}
+
+// split
+// 在有序索引上切开, 把移走的元素从map中移到新编码的map中
+func (enc *indexEncoding[K, S, V]) split(rank int64) sortSetEncoding[K, S, V] {
+	c := &indexEncoding[K, S, V]{
+		indexName: enc.indexName,
+		idx:       enc.idx.splitIndex(rank),
+		order:     enc.order,
+	}
+	c.member = newMemberMap(c.idx)
+	c.idx.scan(func(score S, value V) bool {
+		enc.member.del(value.Key())
+		c.member.set(value.Key(), indexMember[S, V]{score: score, value: value})
+		return true
+	})
+	return c
+}
+
+func (enc *indexEncoding[K, S, V]) concat(other sortSetEncoding[K, S, V]) bool {
+	o, ok := other.(*indexEncoding[K, S, V])
+	if !ok || o.indexName != enc.indexName {
+		return false
+	}
+	if !enc.idx.concatIndex(o.idx) {
+		//不会出现, 同名的索引是同一种类型
+		return false
+	}
+	o.member.each(func(key K, m indexMember[S, V]) bool {
+		enc.member.set(key, m)
+		return true
+	})
+	o.member = newMemberMap(o.idx)
+	return true
+}
+
+func (enc *indexEncoding[K, S, V]) clone() sortSetEncoding[K, S, V] {
+	return &indexEncoding[K, S, V]{
+		indexName: enc.indexName,
+		member:    enc.member.cloneMap(),
+		idx:       enc.idx.cloneIndex(),
+		order:     enc.order,
+	}
+}
+
+// validate
+// 检查有序索引的结构, 以及 map 和有序索引中的元素是否一一对应
+func (enc *indexEncoding[K, S, V]) validate() error {
+	if err := enc.idx.Validate(); err != nil {
+		return err
+	}
+	if int64(enc.member.count()) != enc.idx.Size() {
+		return fmt.Errorf("Validate map has %d members, index has %d", enc.member.count(), enc.idx.Size())
+	}
+	var err error
+	enc.member.each(func(key K, m indexMember[S, V]) bool {
+		if enc.idx.valueRank(m.score, m.value) == 0 {
+			err = fmt.Errorf("Validate map member %v is not in the index", key)
+		}
+		return err == nil
+	})
+	return err
+}
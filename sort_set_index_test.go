@@ -0,0 +1,649 @@
+package skiptablev2
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 所有的有序索引都要跑的测试
+var sortSetIndexCases = []struct {
+	name   string
+	config SortSetConfig
+}{
+	{"listpack", SortSetConfig{MaxLevel: SKIP_TABLE_DEFAULT_MAX_LEVEL, ListpackMaxEntries: 1 << 20}},
+	{"skiplist", SortSetConfig{MaxLevel: SKIP_TABLE_DEFAULT_MAX_LEVEL}},
+	{"btree", SortSetConfig{MaxLevel: SKIP_TABLE_DEFAULT_MAX_LEVEL, Index: SORT_SET_INDEX_BTREE}},
+	{"listpack-btree", SortSetConfig{MaxLevel: SKIP_TABLE_DEFAULT_MAX_LEVEL, ListpackMaxEntries: 16, Index: SORT_SET_INDEX_BTREE}},
+	{"skiplist-desc", SortSetConfig{MaxLevel: SKIP_TABLE_DEFAULT_MAX_LEVEL, Order: SCORE_ORDER_DESC}},
+	{"btree-desc", SortSetConfig{MaxLevel: SKIP_TABLE_DEFAULT_MAX_LEVEL, Index: SORT_SET_INDEX_BTREE, Order: SCORE_ORDER_DESC}},
+}
+
+// compare 只在分数相同时调用, 按key比较就行了
+// 更新分数时集合会保留原来的value, 原来value中的分数已经不对了, 所以不能用value中的分数比较
+func compareStItemKey(v1, v2 *StItem[string]) int {
+	return strings.Compare(v1.k, v2.k)
+}
+
+// sortSetModel
+// 用排好序的数组实现的有序集合, 作为正确结果
+// items 中的分数都是最新的分数
+type sortSetModel struct {
+	items []*StItem[string]
+	desc  bool
+}
+
+func (m *sortSetModel) less(v1, v2 *StItem[string]) bool {
+	c := compareStItemKey(v1, v2)
+	if v1.f != v2.f {
+		c = -1
+		if v1.f > v2.f {
+			c = 1
+		}
+	}
+	if m.desc {
+		return c > 0
+	}
+	return c < 0
+}
+
+// 结果和 model 中的 key 相同, 集合中元素的分数可能是旧的, 不比较
+func equalKeys(t *testing.T, op string, result, want []*StItem[string]) {
+	t.Helper()
+	if len(result) != len(want) {
+		t.Fatalf("%s result len:%d != %d", op, len(result), len(want))
+	}
+	for i := range result {
+		if result[i].k != want[i].k {
+			t.Fatalf("%s item:%v != %v at:%d", op, result[i], want[i], i)
+		}
+	}
+}
+
+func (m *sortSetModel) find(key string) int {
+	return slices.IndexFunc(m.items, func(item *StItem[string]) bool {
+		return item.k == key
+	})
+}
+
+func (m *sortSetModel) add(item *StItem[string]) {
+	if i := m.find(item.k); i >= 0 {
+		m.items = slices.Delete(m.items, i, i+1)
+	}
+	item = &StItem[string]{k: item.k, f: item.f}
+	i, _ := slices.BinarySearchFunc(m.items, item, func(e, t *StItem[string]) int {
+		if m.less(e, t) {
+			return -1
+		}
+		return 1
+	})
+	m.items = slices.Insert(m.items, i, item)
+}
+
+func (m *sortSetModel) inRange(score float64, findRange *SkipListFindRange) bool {
+	return (findRange.MinInf || score >= findRange.Min) && (findRange.MaxInf || score <= findRange.Max)
+}
+
+func (m *sortSetModel) rangeByScore(findRange *SkipListFindRange) (result []*StItem[string]) {
+	for _, item := range m.items {
+		if m.inRange(item.f, findRange) {
+			result = append(result, item)
+		}
+	}
+	return
+}
+
+// 和 Range 相同的索引规则, clampLeft 时和 RevRange 一样把小于0的开始位置当成0
+func rangeByIndex(items []*StItem[string], left, right int64, clampLeft bool) []*StItem[string] {
+	n := int64(len(items))
+	if left < 0 {
+		left += n
+	}
+	if right < 0 {
+		right += n
+	}
+	if clampLeft && left < 0 {
+		left = 0
+	}
+	if left < 0 || left > right || left >= n {
+		return nil
+	}
+	return items[left:min(right+1, n)]
+}
+
+func reversed(items []*StItem[string]) []*StItem[string] {
+	result := slices.Clone(items)
+	slices.Reverse(result)
+	return result
+}
+
+func TestSortSet_Index(t *testing.T) {
+	for _, c := range sortSetIndexCases {
+		t.Run(c.name, func(t *testing.T) {
+			set, err := NewSortSetWithConfig[string, *StItem[string]](c.config, compareStItemKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testSortSetIndex(t, set, c.config.Order == SCORE_ORDER_DESC)
+		})
+	}
+	//跳表通过 orderedIndex 接口使用, 和 B树 走同样的代码
+	t.Run("skiplist-index", func(t *testing.T) {
+		set, err := NewSortSetWithConfig[string, *StItem[string]](SortSetConfig{MaxLevel: SKIP_TABLE_DEFAULT_MAX_LEVEL}, compareStItemKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if set.enc, err = newIndexEncoding[string, float64, *StItem[string]](SORT_SET_INDEX_SKIPLIST, SKIP_TABLE_DEFAULT_MAX_LEVEL, SCORE_ORDER_ASC, compareStItemKey); err != nil {
+			t.Fatal(err)
+		}
+		testSortSetIndex(t, set, false)
+	})
+	if _, err := NewSortSetWithConfig[string, *StItem[string]](SortSetConfig{Index: "unknown"}, compareStItemKey); err == nil {
+		t.Fatal("unknown index should fail")
+	}
+}
+
+func testSortSetIndex(t *testing.T, set *SortSet[string, *StItem[string]], desc bool) {
+	const SIZE = 1000
+	model := &sortSetModel{desc: desc}
+	newItem := func() *StItem[string] {
+		//分数只有少量的取值,用来测试分数相同的情况
+		return &StItem[string]{
+			f: float64(rand.Intn(50)),
+			k: strconv.Itoa(rand.Intn(SIZE)),
+		}
+	}
+	randRange := func() *SkipListFindRange {
+		return &SkipListFindRange{
+			Min:    float64(rand.Intn(60) - 5),
+			Max:    float64(rand.Intn(60) - 5),
+			MinInf: rand.Intn(4) == 0,
+			MaxInf: rand.Intn(4) == 0,
+		}
+	}
+
+	for i := 0; i < SIZE*5; i++ {
+		switch rand.Intn(8) {
+		case 0, 1, 2, 3:
+			item := newItem()
+			set.Add(item)
+			model.add(item)
+		case 4:
+			key := strconv.Itoa(rand.Intn(SIZE))
+			if exist := model.find(key); exist >= 0 {
+				model.items = slices.Delete(model.items, exist, exist+1)
+			}
+			set.Remove(key)
+		case 5:
+			l := rand.Int63n(SIZE/10+1) - SIZE/20
+			r := l + rand.Int63n(3)
+			want := slices.Clone(rangeByIndex(model.items, l, r, false))
+			if n := set.RemoveRangeByRank(l, r); n != len(want) {
+				t.Fatalf("RemoveRangeByRank(%d,%d) %d != %d", l, r, n, len(want))
+			}
+			for _, item := range want {
+				j := model.find(item.k)
+				model.items = slices.Delete(model.items, j, j+1)
+			}
+		case 6:
+			score := float64(rand.Intn(50))
+			findRange := &SkipListFindRange{Min: score, Max: score}
+			want := model.rangeByScore(findRange)
+			if n := set.RemoveRangeByScore(score, score); n != len(want) {
+				t.Fatalf("RemoveRangeByScore(%f) %d != %d", score, n, len(want))
+			}
+			model.items = slices.DeleteFunc(model.items, func(item *StItem[string]) bool {
+				return item.f == score
+			})
+		case 7:
+			key := strconv.Itoa(rand.Intn(SIZE))
+			want := int64(model.find(key))
+			if rank := set.Rank(key); (want >= 0 || rank != 0) && rank != want {
+				t.Fatalf("Rank(%s) %d != %d", key, rank, want)
+			}
+			if want >= 0 && set.RevRank(key) != int64(len(model.items))-1-want {
+				t.Fatalf("RevRank(%s) %d != %d", key, set.RevRank(key), int64(len(model.items))-1-want)
+			}
+			if want >= 0 && set.Score(key) != model.items[want].f {
+				t.Fatalf("Score(%s) %f != %f", key, set.Score(key), model.items[want].f)
+			}
+		}
+		if set.Count() != int64(len(model.items)) {
+			t.Fatalf("count %d != %d", set.Count(), len(model.items))
+		}
+		if i%500 == 0 {
+			if err := set.Validate(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	equalKeys(t, "Range", set.Range(0, -1), model.items)
+	equalKeys(t, "RevRange", set.RevRange(0, -1), reversed(model.items))
+	for i := 0; i < 200; i++ {
+		l := rand.Int63n(SIZE) - SIZE/2
+		r := rand.Int63n(SIZE) - SIZE/2
+		op := fmt.Sprintf("(%d,%d)", l, r)
+		equalKeys(t, "Range"+op, set.Range(l, r), rangeByIndex(model.items, l, r, false))
+		equalKeys(t, "RevRange"+op, set.RevRange(l, r), rangeByIndex(reversed(model.items), l, r, true))
+
+		//model 中的元素已经是集合的顺序了
+		findRange := randRange()
+		equalKeys(t, "RangeByScore", set.RangeByScore(findRange), model.rangeByScore(findRange))
+		//反向查找时 Min 是开始的分数
+		revRange := &SkipListFindRange{Min: findRange.Max, Max: findRange.Min, MinInf: findRange.MaxInf, MaxInf: findRange.MinInf}
+		equalKeys(t, "RevRangeByScore", set.RevRangeByScore(revRange), reversed(model.rangeByScore(findRange)))
+	}
+
+	//分数都是整数, 分数之和没有误差
+	sumOf := func(items []*StItem[string]) ScoreAggregate {
+		var a ScoreAggregate
+		for _, item := range items {
+			a.Count++
+			a.Sum += item.f
+		}
+		return a
+	}
+	for i := 0; i < 200; i++ {
+		l := rand.Int63n(SIZE) - SIZE/2
+		r := rand.Int63n(SIZE) - SIZE/2
+		if got, want := set.AggregateByRank(l, r), sumOf(rangeByIndex(model.items, l, r, false)); got != want {
+			t.Fatalf("AggregateByRank(%d,%d) %v != %v", l, r, got, want)
+		}
+		findRange := randRange()
+		if got, want := set.AggregateByScore(findRange), sumOf(model.rangeByScore(findRange)); got != want {
+			t.Fatalf("AggregateByScore(%v) %v != %v", findRange, got, want)
+		}
+	}
+
+	//我和我前后几名
+	rev := reversed(model.items)
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(rand.Intn(SIZE))
+		before, after := rand.Int63n(6), rand.Int63n(6)
+		rank := int64(model.find(key))
+		if rank < 0 {
+			if set.Around(key, before, after) != nil || set.RevAround(key, before, after) != nil {
+				t.Fatalf("Around(%s) missing key should return nil", key)
+			}
+			continue
+		}
+		checkAround := func(op string, result []RankedValue[float64, *StItem[string]], items []*StItem[string], rank int64) {
+			t.Helper()
+			left := max(rank-before, 0)
+			want := items[left:min(rank+after+1, int64(len(items)))]
+			equalKeys(t, op, valuesOf(result), want)
+			for j, r := range result {
+				if r.Rank != left+int64(j) || r.Score != want[j].f {
+					t.Fatalf("%s(%s) rank:%d score:%f want:%d %f", op, key, r.Rank, r.Score, left+int64(j), want[j].f)
+				}
+			}
+		}
+		checkAround("Around", set.Around(key, before, after), model.items, rank)
+		checkAround("RevAround", set.RevAround(key, before, after), rev, int64(len(rev))-1-rank)
+	}
+	//前后元素的数量很大时不能溢出
+	for _, rank := range []int{0, len(model.items) / 2, len(model.items) - 1} {
+		key := model.items[rank].k
+		equalKeys(t, "Around MaxInt64", valuesOf(set.Around(key, math.MaxInt64, math.MaxInt64)), model.items)
+		equalKeys(t, "Around after MaxInt64", valuesOf(set.Around(key, 0, math.MaxInt64)), model.items[rank:])
+		equalKeys(t, "Around before MaxInt64", valuesOf(set.Around(key, math.MaxInt64, 0)), model.items[:rank+1])
+		equalKeys(t, "RevAround MaxInt64", valuesOf(set.RevAround(key, math.MaxInt64, math.MaxInt64)), rev)
+		equalKeys(t, "RevAround after MaxInt64", valuesOf(set.RevAround(key, 0, math.MaxInt64)), rev[len(rev)-1-rank:])
+	}
+
+	//并列的排名
+	modes := []RankMode{RANK_ORDINAL, RANK_COMPETITION, RANK_DENSE, RANK_MODIFIED_COMPETITION, RANK_FRACTIONAL}
+	ranks := make(map[RankMode][]float64)
+	for _, mode := range modes {
+		ranks[mode] = modelTieRanks(model.items, mode)
+	}
+	checkTieRanks := func(op string, result []TieRankedValue[float64, *StItem[string]], want []*StItem[string], mode RankMode) {
+		t.Helper()
+		equalKeys(t, op, valuesOfTie(result), want)
+		for _, r := range result {
+			j := model.find(r.Value.k)
+			if r.Rank != ranks[mode][j] || r.Score != model.items[j].f {
+				t.Fatalf("%s(%d) key:%s rank:%v score:%f want:%v %f", op, mode, r.Value.k, r.Rank, r.Score, ranks[mode][j], model.items[j].f)
+			}
+		}
+	}
+	for i := 0; i < 100; i++ {
+		mode := modes[rand.Intn(len(modes))]
+		key := strconv.Itoa(rand.Intn(SIZE))
+		j := model.find(key)
+		rank, ok := set.RankWithMode(key, mode)
+		if ok != (j >= 0) || (ok && rank != ranks[mode][j]) {
+			t.Fatalf("RankWithMode(%s,%d) %v %v", key, mode, rank, ok)
+		}
+		l, r := rand.Int63n(int64(len(model.items))+10)-5, rand.Int63n(int64(len(model.items))+10)-5
+		checkTieRanks("RangeWithRank", set.RangeWithRank(l, r, mode), rangeByIndex(model.items, l, r, false), mode)
+		findRange := randRange()
+		checkTieRanks("RangeByScoreWithRank", set.RangeByScoreWithRank(findRange, mode), model.rangeByScore(findRange), mode)
+	}
+
+	//快照不受之后修改的影响
+	snap := set.Snapshot()
+	before := slices.Clone(model.items)
+	set.RemoveRangeByRank(0, 10)
+	equalKeys(t, "Snapshot", snap.Range(0, -1), before)
+}
+
+// 按定义直接计算每个元素的排名
+func modelTieRanks(items []*StItem[string], mode RankMode) []float64 {
+	result := make([]float64, len(items))
+	groups := 0
+	for i := 0; i < len(items); {
+		j := i
+		for j < len(items) && items[j].f == items[i].f {
+			j++
+		}
+		groups++
+		for k := i; k < j; k++ {
+			switch mode {
+			case RANK_ORDINAL:
+				result[k] = float64(k + 1)
+			case RANK_COMPETITION:
+				result[k] = float64(i + 1)
+			case RANK_DENSE:
+				result[k] = float64(groups)
+			case RANK_MODIFIED_COMPETITION:
+				result[k] = float64(j)
+			case RANK_FRACTIONAL:
+				result[k] = float64(i+1+j) / 2
+			}
+		}
+		i = j
+	}
+	return result
+}
+
+func valuesOfTie(result []TieRankedValue[float64, *StItem[string]]) []*StItem[string] {
+	values := make([]*StItem[string], len(result))
+	for i := range result {
+		values[i] = result[i].Value
+	}
+	return values
+}
+
+func BenchmarkSortSet_Index(b *testing.B) {
+	for _, index := range []string{SORT_SET_INDEX_SKIPLIST, SORT_SET_INDEX_BTREE} {
+		b.Run(index, func(b *testing.B) {
+			set, err := NewSortSetWithConfig[string, *StItem[string]](SortSetConfig{
+				MaxLevel: SKIP_TABLE_DEFAULT_MAX_LEVEL,
+				Index:    index,
+			}, compareStItemKey)
+			if err != nil {
+				b.Fatal(err)
+			}
+			keys := make([]string, 100000)
+			for i := range keys {
+				keys[i] = strconv.Itoa(i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				key := keys[i%len(keys)]
+				set.Add(&StItem[string]{k: key, f: rand.Float64()})
+				set.Rank(key)
+			}
+		})
+	}
+}
+
+func valuesOf[S cmp.Ordered, V any](items []RankedValue[S, V]) []V {
+	result := make([]V, len(items))
+	for i, item := range items {
+		result[i] = item.Value
+	}
+	return result
+}
+
+// TestBTreeIndex_TieCounts
+// 元素较多时B树有三层, 不同分数的数量要跨过叶子结点和内部结点的边界累加
+func TestBTreeIndex_TieCounts(t *testing.T) {
+	tree, err := newBTreeIndex[string, float64, *StItem[string]](SCORE_ORDER_ASC, compareStItemKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const SIZE = 10000
+	items := make(map[string]*StItem[string])
+	for i := 0; i < SIZE*2; i++ {
+		item := &StItem[string]{f: float64(rand.Intn(SIZE / 20)), k: strconv.Itoa(rand.Intn(SIZE))}
+		if old, ok := items[item.k]; ok {
+			tree.remove(old.f, old)
+		}
+		tree.insert(item.f, item)
+		items[item.k] = item
+		//删除一部分, 产生不满的结点
+		if i%3 == 0 {
+			e := *tree.entryByRank(rand.Int63n(tree.Size()) + 1)
+			tree.remove(e.score, e.value)
+			delete(items, e.value.k)
+		}
+	}
+	if err = tree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	scores := make([]float64, 0, len(items))
+	for _, item := range items {
+		scores = append(scores, item.f)
+	}
+	slices.Sort(scores)
+	for score := float64(-1); score <= SIZE/20; score++ {
+		var before, equal, groups int64
+		for i, s := range scores {
+			if s < score {
+				before++
+				if i == 0 || scores[i-1] != s {
+					groups++
+				}
+			} else if s == score {
+				equal++
+			}
+		}
+		b, e, g := tree.tieCounts(score)
+		if b != before || e != equal || g != groups {
+			t.Fatalf("tieCounts(%v) %d %d %d want:%d %d %d", score, b, e, g, before, equal, groups)
+		}
+	}
+}
+
+// TestBTreeIndex_SplitConcat
+// 切开和拼接不同高度的B树, 每一层的计数、和、分组数都要保持正确
+func TestBTreeIndex_SplitConcat(t *testing.T) {
+	newTree := func() *btreeIndex[string, float64, *StItem[string]] {
+		tree, err := newBTreeIndex[string, float64, *StItem[string]](SCORE_ORDER_ASC, compareStItemKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tree
+	}
+	check := func(op string, tree *btreeIndex[string, float64, *StItem[string]], want []*StItem[string]) {
+		if err := tree.Validate(); err != nil {
+			t.Fatalf("%s %v", op, err)
+		}
+		if tree.Size() != int64(len(want)) {
+			t.Fatalf("%s size:%d want:%d", op, tree.Size(), len(want))
+		}
+		i := 0
+		tree.scan(func(score float64, value *StItem[string]) bool {
+			if value != want[i] {
+				t.Fatalf("%s rank %d is %s, want %s", op, i+1, value.k, want[i].k)
+			}
+			i++
+			return true
+		})
+	}
+	for _, n := range []int{0, 1, 64, 65, 4096, 4097, 20000} {
+		tree := newTree()
+		all := make([]*StItem[string], n)
+		for i := range all {
+			all[i] = &StItem[string]{f: float64(i / 3), k: strconv.Itoa(100000 + i)}
+		}
+		for _, i := range rand.Perm(n) {
+			tree.insert(all[i].f, all[i])
+		}
+		for _, rank := range []int64{-1, 0, 1, 2, int64(n / 64), int64(n / 2), int64(n) - 1, int64(n), rand.Int63n(int64(n) + 1)} {
+			k := min(max(rank, 0), int64(n))
+			right := tree.splitIndex(rank).(*btreeIndex[string, float64, *StItem[string]])
+			check("splitIndex left", tree, all[:k])
+			check("splitIndex right", right, all[k:])
+			//高度不同的树拼接时, 矮的树挂到高的树的一侧
+			if !tree.concatIndex(right) {
+				t.Fatal("concatIndex")
+			}
+			check("concatIndex", tree, all)
+			check("concatIndex other", right, nil)
+		}
+		//切成很多段再拼回去
+		var parts []orderedIndex[string, float64, *StItem[string]]
+		for tree.Size() > 0 {
+			parts = append(parts, tree.splitIndex(tree.Size()-rand.Int63n(tree.Size()/2+1)-1))
+		}
+		for i := len(parts) - 1; i >= 0; i-- {
+			tree.concatIndex(parts[i])
+		}
+		check("concatIndex parts", tree, all)
+	}
+}
+
+// TestBTreeIndex_SplitConcatHeight
+// 反复切开再拼接, 结点仍然至少半满, 树的高度不会增长
+func TestBTreeIndex_SplitConcatHeight(t *testing.T) {
+	for _, c := range []struct {
+		size   int
+		height int
+		rounds int
+	}{
+		//高度是3时至少有 2*32*32 个元素, 高度是4时至少有 2*32*32*32 个元素
+		{1000, 2, 4000},
+		{20000, 3, 200},
+	} {
+		set, err := NewSortSetWithConfig[string, *StItem[string]](SortSetConfig{MaxLevel: SKIP_TABLE_DEFAULT_MAX_LEVEL, Index: SORT_SET_INDEX_BTREE}, compareStItemKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < c.size; i++ {
+			set.Add(&StItem[string]{f: float64(rand.Intn(c.size)), k: strconv.Itoa(i)})
+		}
+		want := set.Range(0, -1)
+		for i := 0; i < c.rounds; i++ {
+			right, err := set.SplitAtRank(rand.Int63n(int64(c.size) + 1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = set.Concat(right); err != nil {
+				t.Fatal(err)
+			}
+			tree := set.enc.(*indexEncoding[string, float64, *StItem[string]]).idx.(*btreeIndex[string, float64, *StItem[string]])
+			if h := btreeHeight(tree.root); h > c.height {
+				t.Fatalf("size %d round %d btree height is %d, want <= %d", c.size, i, h, c.height)
+			}
+			if i%50 == 0 {
+				if err = set.Validate(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if err = set.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		equalKeys(t, "SplitAtRank and Concat", set.Range(0, -1), want)
+	}
+}
+
+// TestBTreeIndex_RemoveRange
+// 删除大段的排名和分数范围后, B树仍然满足所有要求, 回调按顺序返回被删除的元素
+func TestBTreeIndex_RemoveRange(t *testing.T) {
+	tree, err := newBTreeIndex[string, float64, *StItem[string]](SCORE_ORDER_ASC, compareStItemKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const SIZE = 20000
+	all := make([]*StItem[string], SIZE)
+	for i := range all {
+		all[i] = &StItem[string]{f: float64(i / 3), k: strconv.Itoa(100000 + i)}
+	}
+	for _, i := range rand.Perm(SIZE) {
+		tree.insert(all[i].f, all[i])
+	}
+	for round := 0; len(all) > 0; round++ {
+		var removed []*StItem[string]
+		collect := func(score float64, value *StItem[string]) {
+			removed = append(removed, value)
+		}
+		var left, right int
+		if round%2 == 0 {
+			//范围的大小从1个元素到几千个元素
+			width := 1 + rand.Intn(min(len(all), []int{1, 100, 5000}[round/2%3]))
+			left = rand.Intn(len(all) - width + 1)
+			right = left + width
+			if n := tree.removeRangeByRank(int64(left+1), int64(right), collect); n != int64(width) {
+				t.Fatalf("removeRangeByRank(%d,%d) %d != %d", left+1, right, n, width)
+			}
+		} else {
+			from := all[rand.Intn(len(all))].f
+			to := from + float64(rand.Intn(1000))
+			for left < len(all) && all[left].f < from {
+				left++
+			}
+			for right = left; right < len(all) && all[right].f <= to; right++ {
+			}
+			if n := tree.removeRangeByScore(&ScoreRange[float64]{Min: from, Max: to}, collect); n != int64(right-left) {
+				t.Fatalf("removeRangeByScore(%v,%v) %d != %d", from, to, n, right-left)
+			}
+		}
+		if !slices.Equal(removed, all[left:right]) {
+			t.Fatalf("round %d removed %d items in the wrong order", round, len(removed))
+		}
+		all = slices.Delete(all, left, right)
+		if err = tree.Validate(); err != nil {
+			t.Fatalf("round %d %v", round, err)
+		}
+		if tree.Size() != int64(len(all)) {
+			t.Fatalf("round %d size:%d want:%d", round, tree.Size(), len(all))
+		}
+	}
+}
+
+// 排好序的数据线性构建有序索引, 检查结点数量刚好在分段边界附近时结构是否正确
+func TestSortSet_IndexLoad(t *testing.T) {
+	for _, c := range sortSetIndexCases {
+		t.Run(c.name, func(t *testing.T) {
+			for _, n := range []int{1, 32, 63, 64, 65, 128, 129, 4096, 4097, 5000} {
+				items := make([]*StItem[string], n)
+				for i := range items {
+					//分数有重复, 检查 groups
+					items[i] = &StItem[string]{k: strconv.Itoa(100000 + i), f: float64(i / 3)}
+				}
+				if c.config.Order == SCORE_ORDER_DESC {
+					slices.Reverse(items)
+				}
+				set, err := NewSortSetWithConfig[string, *StItem[string]](c.config, compareStItemKey)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if loaded := set.BulkLoad(items); loaded != n {
+					t.Fatalf("n:%d BulkLoad:%d", n, loaded)
+				}
+				if err := set.Validate(); err != nil {
+					t.Fatalf("n:%d %v", n, err)
+				}
+				equalKeys(t, "Range", set.Range(0, -1), items)
+				//构建后继续插入删除, 结构仍然正确
+				for i := 0; i < n; i += 2 {
+					set.Remove(items[i].k)
+				}
+				set.Add(&StItem[string]{k: "x", f: -1})
+				if err := set.Validate(); err != nil {
+					t.Fatalf("n:%d after update %v", n, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package skiptablev2
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+)
+
+// listpackEntry
+// 紧凑编码中的一个元素
+type listpackEntry[S cmp.Ordered, V any] struct {
+	score S
+	value V
+}
+
+// listpack
+// 有序集合的紧凑编码, 类似redis的listpack, 所有元素按 score(score相同时按compare) 和跳表相同的顺序存放在一个数组中
+// 没有map, 按key查找时直接遍历数组, 元素较少时比 map + 跳表 省很多内存
+type listpack[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
+	entries []listpackEntry[S, V]
+	//排序规则, 和跳表相同
+	scoreOrder[S, V]
+}
+
+func newListpack[K comparable, S cmp.Ordered, V ScoredItem[K, S]](order ScoreOrder, compare func(v1, v2 V) int) *listpack[K, S, V] {
+	return &listpack[K, S, V]{
+		scoreOrder: scoreOrder[S, V]{
+			compare: compare,
+			desc:    order == SCORE_ORDER_DESC,
+		},
+	}
+}
+
+// 按key查找元素的下标, 不存在返回 -1
+func (lp *listpack[K, S, V]) find(key K) int {
+	for i := range lp.entries {
+		if lp.entries[i].value.Key() == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// 元素e是否排在(score,value)前面
+func (lp *listpack[K, S, V]) less(e *listpackEntry[S, V], score S, value V) bool {
+	return lp.before(e.score, e.value, score, value)
+}
+
+// 插入一个元素, 二分查找插入的位置
+func (lp *listpack[K, S, V]) insert(score S, value V) {
+	i := sort.Search(len(lp.entries), func(i int) bool {
+		return !lp.less(&lp.entries[i], score, value)
+	})
+	var e listpackEntry[S, V]
+	lp.entries = append(lp.entries, e)
+	copy(lp.entries[i+1:], lp.entries[i:])
+	lp.entries[i] = listpackEntry[S, V]{score: score, value: value}
+}
+
+// 删除 [left,right) 范围内的元素
+func (lp *listpack[K, S, V]) cut(left, right int) {
+	n := copy(lp.entries[left:], lp.entries[right:])
+	//不再引用被删除的元素
+	var e listpackEntry[S, V]
+	for i := left + n; i < len(lp.entries); i++ {
+		lp.entries[i] = e
+	}
+	lp.entries = lp.entries[:left+n]
+}
+
+// 分数范围对应的下标区间 [left,right)
+func (lp *listpack[K, S, V]) scoreRange(findRange *ScoreRange[S]) (left, right int) {
+	left = sort.Search(len(lp.entries), func(i int) bool {
+		return !lp.beforeStart(lp.entries[i].score, findRange)
+	})
+	right = sort.Search(len(lp.entries), func(i int) bool {
+		return lp.afterEnd(lp.entries[i].score, findRange)
+	})
+	if right < left {
+		right = left
+	}
+	return
+}
+
+// 排名范围对应的下标区间 [left,right), 范围出错时返回 left >= right
+func (lp *listpack[K, S, V]) rankRange(left, right int64) (int, int) {
+	size := int64(len(lp.entries))
+	if size == 0 || left <= 0 || right <= 0 || right < left || left > size {
+		return 0, 0
+	}
+	if right > size {
+		right = size
+	}
+	return int(left - 1), int(right)
+}
+
+func (lp *listpack[K, S, V]) values(left, right int) []V {
+	if left >= right {
+		return nil
+	}
+	result := make([]V, 0, right-left)
+	for i := left; i < right; i++ {
+		result = append(result, lp.entries[i].value)
+	}
+	return result
+}
+
+// 从后向前返回下标区间 [left,right) 内的元素
+func (lp *listpack[K, S, V]) revValues(left, right int) []V {
+	if left >= right {
+		return nil
+	}
+	result := make([]V, 0, right-left)
+	for i := right - 1; i >= left; i-- {
+		result = append(result, lp.entries[i].value)
+	}
+	return result
+}
+
+func (lp *listpack[K, S, V]) name() string {
+	return SORT_SET_ENCODING_LISTPACK
+}
+
+func (lp *listpack[K, S, V]) count() int64 {
+	return int64(len(lp.entries))
+}
+
+func (lp *listpack[K, S, V]) add(item V) {
+	lp.addScore(item.Score(), item)
+}
+
+func (lp *listpack[K, S, V]) addScore(score S, item V) {
+	i := lp.find(item.Key())
+	if i < 0 {
+		lp.insert(score, item)
+		return
+	}
+	//已经有这个元素了,只更新分数, 和跳表一样保留原来的value
+	if lp.entries[i].score == score {
+		return
+	}
+	value := lp.entries[i].value
+	lp.cut(i, i+1)
+	lp.insert(score, value)
+}
+
+func (lp *listpack[K, S, V]) remove(key K) bool {
+	i := lp.find(key)
+	if i < 0 {
+		return false
+	}
+	lp.cut(i, i+1)
+	return true
+}
+
+func (lp *listpack[K, S, V]) score(key K) (S, bool) {
+	i := lp.find(key)
+	if i < 0 {
+		var score S
+		return score, false
+	}
+	return lp.entries[i].score, true
+}
+
+func (lp *listpack[K, S, V]) rank(key K) int64 {
+	return int64(lp.find(key) + 1)
+}
+
+func (lp *listpack[K, S, V]) valuesByRank(left, right int64) []V {
+	return lp.values(lp.rankRange(left, right))
+}
+
+func (lp *listpack[K, S, V]) valuesByScore(findRange *ScoreRange[S]) []V {
+	if findRange == nil {
+		return nil
+	}
+	return lp.values(lp.scoreRange(findRange))
+}
+
+func (lp *listpack[K, S, V]) revValuesByRank(left, right int64) []V {
+	l, r := lp.rankRange(left, right)
+	//反向排名的区间 [l,r) 对应正向的下标区间 [n-r,n-l)
+	n := len(lp.entries)
+	return lp.revValues(n-r, n-l)
+}
+
+func (lp *listpack[K, S, V]) revValuesByScore(findRange *ScoreRange[S]) []V {
+	if findRange == nil {
+		return nil
+	}
+	return lp.revValues(lp.scoreRange(findRange))
+}
+
+func (lp *listpack[K, S, V]) around(key K, before, after int64) []RankedValue[S, V] {
+	i := lp.find(key)
+	if i < 0 {
+		return nil
+	}
+	//先限制前后元素的数量, before、after 很大(比如 math.MaxInt64)时直接相加会溢出
+	left := int64(i) - min(before, int64(i))
+	right := int64(i) + min(after, int64(len(lp.entries)-1-i))
+	result := make([]RankedValue[S, V], 0, right-left+1)
+	for j := left; j <= right; j++ {
+		result = append(result, RankedValue[S, V]{Value: lp.entries[j].value, Score: lp.entries[j].score, Rank: j + 1})
+	}
+	return result
+}
+
+// 下标区间 [left,right) 内元素的数量和分数之和, 元素很少, 直接遍历
+func (lp *listpack[K, S, V]) aggregate(left, right int) ScoreAggregate {
+	var a ScoreAggregate
+	for i := left; i < right; i++ {
+		a.Count++
+		a.Sum += sumFloat(lp.entries[i].score)
+	}
+	return a
+}
+
+func (lp *listpack[K, S, V]) aggregateByRank(left, right int64) ScoreAggregate {
+	return lp.aggregate(lp.rankRange(left, right))
+}
+
+func (lp *listpack[K, S, V]) aggregateByScore(findRange *ScoreRange[S]) ScoreAggregate {
+	if findRange == nil {
+		return ScoreAggregate{}
+	}
+	return lp.aggregate(lp.scoreRange(findRange))
+}
+
+// 元素很少, 直接遍历
+func (lp *listpack[K, S, V]) tieCounts(score S) (before, equal, groups int64) {
+	for i := range lp.entries {
+		e := &lp.entries[i]
+		if !lp.scoreOrder.less(e.score, score) {
+			if e.score == score {
+				equal++
+				continue
+			}
+			break
+		}
+		before++
+		if i == 0 || lp.entries[i-1].score != e.score {
+			groups++
+		}
+	}
+	return
+}
+
+func (lp *listpack[K, S, V]) removeRangeByRank(left, right int64) int {
+	l, r := lp.rankRange(left, right)
+	if l >= r {
+		return 0
+	}
+	lp.cut(l, r)
+	return r - l
+}
+
+func (lp *listpack[K, S, V]) removeRangeByScore(findRange *ScoreRange[S]) int {
+	l, r := lp.scoreRange(findRange)
+	if l >= r {
+		return 0
+	}
+	lp.cut(l, r)
+	return r - l
+}
+
+func (lp *listpack[K, S, V]) bulkLoad(items []V) bool {
+	keys := make(map[K]struct{}, len(items))
+	for i, item := range items {
+		if i > 0 && lp.less(&listpackEntry[S, V]{score: item.Score(), value: item}, items[i-1].Score(), items[i-1]) {
+			return false
+		}
+		if _, e := keys[item.Key()]; e {
+			return false
+		}
+		keys[item.Key()] = struct{}{}
+	}
+	lp.entries = make([]listpackEntry[S, V], len(items))
+	for i, item := range items {
+		lp.entries[i] = listpackEntry[S, V]{score: item.Score(), value: item}
+	}
+	return true
+}
+
+func (lp *listpack[K, S, V]) scan(fn func(score S, value V) bool) {
+	for i := range lp.entries {
+		if !fn(lp.entries[i].score, lp.entries[i].value) {
+			return
+		}
+	}
+}
+
+// split
+// 元素很少, 直接复制移走的元素
+func (lp *listpack[K, S, V]) split(rank int64) sortSetEncoding[K, S, V] {
+	rank = min(rank, int64(len(lp.entries)))
+	c := &listpack[K, S, V]{scoreOrder: lp.scoreOrder}
+	c.entries = append(c.entries, lp.entries[rank:]...)
+	lp.cut(int(rank), len(lp.entries))
+	return c
+}
+
+func (lp *listpack[K, S, V]) concat(other sortSetEncoding[K, S, V]) bool {
+	o, ok := other.(*listpack[K, S, V])
+	if !ok {
+		return false
+	}
+	lp.entries = append(lp.entries, o.entries...)
+	o.entries = nil
+	return true
+}
+
+func (lp *listpack[K, S, V]) clone() sortSetEncoding[K, S, V] {
+	c := &listpack[K, S, V]{scoreOrder: lp.scoreOrder}
+	c.entries = append(c.entries, lp.entries...)
+	return c
+}
+
+// validate
+// 检查元素是否按顺序排列, key 是否重复
+func (lp *listpack[K, S, V]) validate() error {
+	keys := make(map[K]struct{}, len(lp.entries))
+	for i := range lp.entries {
+		e := &lp.entries[i]
+		if i > 0 && !lp.less(&lp.entries[i-1], e.score, e.value) {
+			return fmt.Errorf("Validate listpack entry %v at index %d is not after the previous entry", e.value.Key(), i)
+		}
+		if _, ok := keys[e.value.Key()]; ok {
+			return fmt.Errorf("Validate listpack key %v is duplicated", e.value.Key())
+		}
+		keys[e.value.Key()] = struct{}{}
+	}
+	return nil
+}
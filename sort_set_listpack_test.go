@@ -0,0 +1,158 @@
+package skiptablev2
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newEncodingTestSortSet(listpackMaxEntries int) *SortSet[string, *StItem[string]] {
+	return newOrderTestSortSet(listpackMaxEntries, SCORE_ORDER_ASC)
+}
+
+func newOrderTestSortSet(listpackMaxEntries int, order ScoreOrder) *SortSet[string, *StItem[string]] {
+	sortSet, err := NewSortSetWithConfig[string, *StItem[string]](SortSetConfig{
+		MaxLevel:           SKIP_TABLE_DEFAULT_MAX_LEVEL,
+		ListpackMaxEntries: listpackMaxEntries,
+		Order:              order,
+	}, func(v1, v2 *StItem[string]) int {
+		//分数相同时按key比较, 保证只有同一个元素才相等
+		if v1.f == v2.f {
+			return strings.Compare(v1.k, v2.k)
+		} else if v1.f < v2.f {
+			return -1
+		} else {
+			return 1
+		}
+	})
+	if err != nil {
+		panic(err)
+	}
+	return sortSet
+}
+
+func equalItems(t *testing.T, op string, result1, result2 []*StItem[string]) {
+	if len(result1) != len(result2) {
+		t.Fatalf("%s result len:%d != %d", op, len(result1), len(result2))
+	}
+	for i := range result1 {
+		if !compareItem(result1[i], result2[i]) {
+			t.Fatalf("%s item:%v != %v at:%d", op, result1[i], result2[i], i)
+		}
+	}
+}
+
+func TestSortSet_ListpackConvert(t *testing.T) {
+	sortSet := newEncodingTestSortSet(SORT_SET_DEFAULT_LISTPACK_ENTRIES)
+	if sortSet.Encoding() != SORT_SET_ENCODING_LISTPACK {
+		t.Fatalf("encoding:%s want:%s", sortSet.Encoding(), SORT_SET_ENCODING_LISTPACK)
+	}
+	items := make([]*StItem[string], 0, SORT_SET_DEFAULT_LISTPACK_ENTRIES+1)
+	for i := 0; i < SORT_SET_DEFAULT_LISTPACK_ENTRIES; i++ {
+		item := CreateStItem()
+		sortSet.Add(item)
+		items = append(items, item)
+	}
+	if sortSet.Encoding() != SORT_SET_ENCODING_LISTPACK {
+		t.Fatalf("encoding:%s want:%s", sortSet.Encoding(), SORT_SET_ENCODING_LISTPACK)
+	}
+	before := sortSet.Range(0, -1)
+	snap := sortSet.Snapshot()
+
+	//超过阈值后转换成跳表
+	item := CreateStItem()
+	sortSet.Add(item)
+	items = append(items, item)
+	if sortSet.Encoding() != SORT_SET_ENCODING_SKIPLIST {
+		t.Fatalf("encoding:%s want:%s", sortSet.Encoding(), SORT_SET_ENCODING_SKIPLIST)
+	}
+	if sortSet.Count() != int64(len(items)) {
+		t.Fatalf("count:%d want:%d", sortSet.Count(), len(items))
+	}
+	for _, item := range items {
+		if sortSet.Score(item.k) != item.f {
+			t.Fatalf("item:%v score:%f", item, sortSet.Score(item.k))
+		}
+		result := sortSet.Range(sortSet.Rank(item.k), sortSet.Rank(item.k))
+		if len(result) != 1 || !compareItem(result[0], item) {
+			t.Fatalf("item:%v rank:%d error", item, sortSet.Rank(item.k))
+		}
+	}
+
+	//快照不受转换影响
+	equalItems(t, "snapshot", before, snap.Range(0, -1))
+}
+
+// 同样的操作分别作用在紧凑编码和跳表编码上, 结果必须完全相同
+func TestSortSet_ListpackSameAsSkipList(t *testing.T) {
+	const SIZE = 1000
+	listpackSet := newEncodingTestSortSet(SIZE * 2)
+	skipListSet := newEncodingTestSortSet(0)
+	if skipListSet.Encoding() != SORT_SET_ENCODING_SKIPLIST {
+		t.Fatalf("encoding:%s want:%s", skipListSet.Encoding(), SORT_SET_ENCODING_SKIPLIST)
+	}
+
+	//分数只有少量的取值,用来测试分数相同的情况
+	newItem := func() *StItem[string] {
+		return &StItem[string]{
+			f: float64(rand.Intn(50)),
+			k: strconv.Itoa(rand.Intn(SIZE)),
+		}
+	}
+	for i := 0; i < SIZE*5; i++ {
+		switch rand.Intn(6) {
+		case 0, 1, 2:
+			item := newItem()
+			if n1, n2 := listpackSet.Add(item), skipListSet.Add(&StItem[string]{f: item.f, k: item.k}); n1 != n2 {
+				t.Fatalf("Add %d != %d", n1, n2)
+			}
+		case 3:
+			key := strconv.Itoa(rand.Intn(SIZE))
+			listpackSet.Remove(key)
+			skipListSet.Remove(key)
+		case 4:
+			l := rand.Int63n(SIZE/10+1) - SIZE/20
+			r := l + rand.Int63n(3)
+			if n1, n2 := listpackSet.RemoveRangeByRank(l, r), skipListSet.RemoveRangeByRank(l, r); n1 != n2 {
+				t.Fatalf("RemoveRangeByRank(%d,%d) %d != %d", l, r, n1, n2)
+			}
+		case 5:
+			score := float64(rand.Intn(50))
+			if n1, n2 := listpackSet.RemoveRangeByScore(score, score), skipListSet.RemoveRangeByScore(score, score); n1 != n2 {
+				t.Fatalf("RemoveRangeByScore(%f) %d != %d", score, n1, n2)
+			}
+		}
+		if listpackSet.Count() != skipListSet.Count() {
+			t.Fatalf("count %d != %d", listpackSet.Count(), skipListSet.Count())
+		}
+	}
+	if listpackSet.Encoding() != SORT_SET_ENCODING_LISTPACK {
+		t.Fatalf("encoding:%s want:%s", listpackSet.Encoding(), SORT_SET_ENCODING_LISTPACK)
+	}
+
+	equalItems(t, "Range", listpackSet.Range(0, -1), skipListSet.Range(0, -1))
+	equalItems(t, "RevRange", listpackSet.RevRange(0, -1), skipListSet.RevRange(0, -1))
+	for i := 0; i < 100; i++ {
+		l := rand.Int63n(SIZE) - SIZE/2
+		r := rand.Int63n(SIZE) - SIZE/2
+		equalItems(t, "Range", listpackSet.Range(l, r), skipListSet.Range(l, r))
+		equalItems(t, "RevRange", listpackSet.RevRange(l, r), skipListSet.RevRange(l, r))
+
+		findRange := &SkipListFindRange{
+			Min:    float64(rand.Intn(60) - 5),
+			Max:    float64(rand.Intn(60) - 5),
+			MinInf: rand.Intn(4) == 0,
+			MaxInf: rand.Intn(4) == 0,
+		}
+		equalItems(t, "RangeByScore", listpackSet.RangeByScore(findRange), skipListSet.RangeByScore(findRange))
+
+		key := strconv.Itoa(rand.Intn(SIZE))
+		if listpackSet.Rank(key) != skipListSet.Rank(key) || listpackSet.RevRank(key) != skipListSet.RevRank(key) {
+			t.Fatalf("key:%s rank %d != %d", key, listpackSet.Rank(key), skipListSet.Rank(key))
+		}
+		if listpackSet.Score(key) != skipListSet.Score(key) {
+			t.Fatalf("key:%s score %f != %f", key, listpackSet.Score(key), skipListSet.Score(key))
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package skiptablev2
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ZAddFlag
+// ZAdd 的选项, 和 redis ZADD 的选项相同, 可以组合使用
+type ZAddFlag int
+
+const (
+	//ZADD_NX 只添加新元素, 不更新已经存在的元素
+	ZADD_NX ZAddFlag = 1 << iota
+	//ZADD_XX 只更新已经存在的元素, 不添加新元素
+	ZADD_XX
+	//ZADD_GT 新分数比当前分数大时才更新, 不影响添加新元素
+	ZADD_GT
+	//ZADD_LT 新分数比当前分数小时才更新, 不影响添加新元素
+	ZADD_LT
+	//ZADD_CH 返回新添加和分数改变的元素数量, 默认只返回新添加的元素数量
+	ZADD_CH
+)
+
+// 这个文件中的方法和 redis 同名命令的行为(参数规则和返回值)保持一致,
+// 成员不存在时通过返回 false 区分(redis 返回 nil), 不使用 0 或者 -1 表示
+
+// ZAdd
+// 按顺序添加元素, 元素已经存在时按选项决定是否更新分数, 返回值和 redis 的 ZADD 相同
+// NX 和 XX 不能同时使用, GT、LT、NX 不能同时使用, 分数不能是 NaN
+func (set *ScoredSortSet[K, S, V]) ZAdd(flags ZAddFlag, items ...V) (int, error) {
+	nx, xx, gt, lt := flags&ZADD_NX != 0, flags&ZADD_XX != 0, flags&ZADD_GT != 0, flags&ZADD_LT != 0
+	if nx && xx {
+		return 0, errors.New("ZAdd XX and NX options at the same time are not compatible")
+	}
+	if (gt && lt) || (nx && (gt || lt)) {
+		return 0, errors.New("ZAdd GT, LT, and/or NX options at the same time are not compatible")
+	}
+	//和 redis 一样, 先检查所有的分数, 有错误时不修改集合
+	for _, item := range items {
+		if scoreIsNaN(item.Score()) {
+			return 0, fmt.Errorf("ZAdd score of member %v is not a number (NaN)", item.Key())
+		}
+	}
+	if len(items) == 0 {
+		return 0, nil
+	}
+	set.copyOnWrite()
+	defer set.convert()
+	added, changed := 0, 0
+	for _, item := range items {
+		score := item.Score()
+		current, exist := set.enc.score(item.Key())
+		if !exist {
+			if !xx {
+				set.enc.add(item)
+				added++
+			}
+			continue
+		}
+		if nx || (gt && score <= current) || (lt && score >= current) || score == current {
+			continue
+		}
+		set.enc.add(item)
+		changed++
+	}
+	if flags&ZADD_CH != 0 {
+		return added + changed, nil
+	}
+	return added, nil
+}
+
+// ZRem
+// 删除元素, 返回实际删除的元素数量, 不存在的元素不计算
+func (set *ScoredSortSet[K, S, V]) ZRem(keys ...K) int {
+	set.copyOnWrite()
+	removed := 0
+	for _, key := range keys {
+		if set.enc.remove(key) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// ZCard
+// 元素数量
+func (set *ScoredSortSet[K, S, V]) ZCard() int64 {
+	return set.enc.count()
+}
+
+// ZScore
+// 元素的分数, 元素不存在返回 false
+func (set *ScoredSortSet[K, S, V]) ZScore(key K) (S, bool) {
+	return set.enc.score(key)
+}
+
+// ZRank
+// 元素的排名(从0开始), 元素不存在返回 false
+func (set *ScoredSortSet[K, S, V]) ZRank(key K) (int64, bool) {
+	rank := set.enc.rank(key)
+	return rank - 1, rank != 0
+}
+
+// ZRevRank
+// 元素的反向排名(从0开始), 元素不存在返回 false
+func (set *ScoredSortSet[K, S, V]) ZRevRank(key K) (int64, bool) {
+	rank := set.enc.rank(key)
+	if rank == 0 {
+		return -1, false
+	}
+	return set.enc.count() - rank, true
+}
+
+// ZCount
+// 分数范围内的元素数量, 复杂度 O(log n)
+func (set *ScoredSortSet[K, S, V]) ZCount(findRange *ScoreRange[S]) int64 {
+	return set.AggregateByScore(findRange).Count
+}
+
+// redisRange
+// 按 redis 的规则处理索引区间: 负数从结尾开始算, 开始位置小于0时从0开始, 结束位置超过最后一个元素时到最后一个元素
+// 返回从1开始的排名区间, 区间是空的时返回 false
+func redisRange(start, stop, count int64) (int64, int64, bool) {
+	if start < 0 {
+		start += count
+	}
+	if stop < 0 {
+		stop += count
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > stop || start >= count {
+		return 0, 0, false
+	}
+	if stop >= count {
+		stop = count - 1
+	}
+	return start + 1, stop + 1, true
+}
+
+// ZRange
+// 索引区间 [start,stop] 内的元素, 索引的规则和 redis 的 ZRANGE 相同
+func (set *ScoredSortSet[K, S, V]) ZRange(start, stop int64) []V {
+	left, right, ok := redisRange(start, stop, set.enc.count())
+	if !ok {
+		return nil
+	}
+	return set.enc.valuesByRank(left, right)
+}
+
+// ZRevRange
+// 反向索引区间 [start,stop] 内的元素, 索引的规则和 redis 的 ZREVRANGE 相同
+func (set *ScoredSortSet[K, S, V]) ZRevRange(start, stop int64) []V {
+	left, right, ok := redisRange(start, stop, set.enc.count())
+	if !ok {
+		return nil
+	}
+	return set.enc.revValuesByRank(left, right)
+}
+
+// ZRangeByScore
+// 分数范围内的元素, 和 redis 的 ZRANGEBYSCORE 相同, 开区间使用 ScoreRange 的 MinEx、MaxEx
+func (set *ScoredSortSet[K, S, V]) ZRangeByScore(findRange *ScoreRange[S]) []V {
+	return set.RangeByScore(findRange)
+}
+
+// ZRevRangeByScore
+// 按分数从高到低返回分数范围内的元素, 和 redis 的 ZREVRANGEBYSCORE 一样, Min 是较大的分数, Max 是较小的分数
+func (set *ScoredSortSet[K, S, V]) ZRevRangeByScore(findRange *ScoreRange[S]) []V {
+	return set.RevRangeByScore(findRange)
+}
+
+// ZRemRangeByRank
+// 删除索引区间 [start,stop] 内的元素, 索引的规则和 ZRange 相同, 返回删除的元素数量
+func (set *ScoredSortSet[K, S, V]) ZRemRangeByRank(start, stop int64) int {
+	left, right, ok := redisRange(start, stop, set.enc.count())
+	if !ok {
+		return 0
+	}
+	set.copyOnWrite()
+	return set.enc.removeRangeByRank(left, right)
+}
+
+// ZRemRangeByScore
+// 删除分数范围内的元素, 返回删除的元素数量
+func (set *ScoredSortSet[K, S, V]) ZRemRangeByScore(findRange *ScoreRange[S]) int {
+	if findRange == nil || set.enc.count() == 0 {
+		return 0
+	}
+	set.copyOnWrite()
+	return set.enc.removeRangeByScore(findRange)
+}
@@ -0,0 +1,251 @@
+package skiptablev2
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type redisSortSet = SortSet[string, *StItem[string]]
+
+// redisCmd
+// 一条 redis 命令和 redis 文档中的返回值
+type redisCmd struct {
+	//redis 命令, 只用来在失败时显示
+	cmd  string
+	run  func(set *redisSortSet) any
+	want any
+}
+
+func zitem(key string, score float64) *StItem[string] {
+	return &StItem[string]{k: key, f: score}
+}
+
+// 和 redis-cli 一样显示成员(和分数)的列表, 空列表是 []string{}
+func zmembers(set *redisSortSet, values []*StItem[string], withScores bool) []string {
+	result := []string{}
+	for _, v := range values {
+		result = append(result, v.k)
+		if withScores {
+			//value 中的分数可能是旧的, 使用集合中记录的分数
+			score, _ := set.ZScore(v.k)
+			result = append(result, strconv.FormatFloat(score, 'f', -1, 64))
+		}
+	}
+	return result
+}
+
+// 成员不存在时 redis 返回 nil
+func znil[T any](v T, ok bool) any {
+	if !ok {
+		return nil
+	}
+	return v
+}
+
+func zadd(items ...*StItem[string]) func(set *redisSortSet) any {
+	return func(set *redisSortSet) any {
+		n, err := set.ZAdd(0, items...)
+		if err != nil {
+			return err
+		}
+		return n
+	}
+}
+
+func zaddFlags(flags ZAddFlag, items ...*StItem[string]) func(set *redisSortSet) any {
+	return func(set *redisSortSet) any {
+		n, err := set.ZAdd(flags, items...)
+		if err != nil {
+			return "ERR"
+		}
+		return n
+	}
+}
+
+func zrange(start, stop int64, withScores bool) func(set *redisSortSet) any {
+	return func(set *redisSortSet) any {
+		return zmembers(set, set.ZRange(start, stop), withScores)
+	}
+}
+
+func zrevrange(start, stop int64) func(set *redisSortSet) any {
+	return func(set *redisSortSet) any {
+		return zmembers(set, set.ZRevRange(start, stop), false)
+	}
+}
+
+func zrangebyscore(findRange SkipListFindRange) func(set *redisSortSet) any {
+	return func(set *redisSortSet) any {
+		return zmembers(set, set.ZRangeByScore(&findRange), false)
+	}
+}
+
+func zrevrangebyscore(findRange SkipListFindRange) func(set *redisSortSet) any {
+	return func(set *redisSortSet) any {
+		return zmembers(set, set.ZRevRangeByScore(&findRange), false)
+	}
+}
+
+// 文档中大多数例子的初始数据: ZADD myzset 1 "one" 2 "two" 3 "three"
+var zaddOneTwoThree = redisCmd{`ZADD myzset 1 "one" 2 "two" 3 "three"`, zadd(zitem("one", 1), zitem("two", 2), zitem("three", 3)), 3}
+
+// redisConformance
+// 来自 redis 文档(https://redis.io/commands)中每个命令的例子, 最后一组是 ZADD 选项的例子
+var redisConformance = []struct {
+	name string
+	cmds []redisCmd
+}{
+	{"ZADD", []redisCmd{
+		{`ZADD myzset 1 "one"`, zadd(zitem("one", 1)), 1},
+		{`ZADD myzset 1 "uno"`, zadd(zitem("uno", 1)), 1},
+		{`ZADD myzset 2 "two" 3 "three"`, zadd(zitem("two", 2), zitem("three", 3)), 2},
+		{`ZRANGE myzset 0 -1 WITHSCORES`, zrange(0, -1, true), []string{"one", "1", "uno", "1", "two", "2", "three", "3"}},
+	}},
+	{"ZCARD", []redisCmd{
+		{`ZADD myzset 1 "one"`, zadd(zitem("one", 1)), 1},
+		{`ZADD myzset 2 "two"`, zadd(zitem("two", 2)), 1},
+		{`ZCARD myzset`, func(set *redisSortSet) any { return set.ZCard() }, int64(2)},
+	}},
+	{"ZCOUNT", []redisCmd{
+		zaddOneTwoThree,
+		{`ZCOUNT myzset -inf +inf`, func(set *redisSortSet) any {
+			return set.ZCount(&SkipListFindRange{MinInf: true, MaxInf: true})
+		}, int64(3)},
+		{`ZCOUNT myzset (1 3`, func(set *redisSortSet) any {
+			return set.ZCount(&SkipListFindRange{Min: 1, MinEx: true, Max: 3})
+		}, int64(2)},
+	}},
+	{"ZRANGE", []redisCmd{
+		zaddOneTwoThree,
+		{`ZRANGE myzset 0 -1`, zrange(0, -1, false), []string{"one", "two", "three"}},
+		{`ZRANGE myzset 2 3`, zrange(2, 3, false), []string{"three"}},
+		{`ZRANGE myzset -2 -1`, zrange(-2, -1, false), []string{"two", "three"}},
+		{`ZRANGE myzset 0 1 WITHSCORES`, zrange(0, 1, true), []string{"one", "1", "two", "2"}},
+		{`ZRANGE myzset -100 100`, zrange(-100, 100, false), []string{"one", "two", "three"}},
+		{`ZRANGE myzset 5 10`, zrange(5, 10, false), []string{}},
+	}},
+	{"ZRANGEBYSCORE", []redisCmd{
+		zaddOneTwoThree,
+		{`ZRANGEBYSCORE myzset -inf +inf`, zrangebyscore(SkipListFindRange{MinInf: true, MaxInf: true}), []string{"one", "two", "three"}},
+		{`ZRANGEBYSCORE myzset 1 2`, zrangebyscore(SkipListFindRange{Min: 1, Max: 2}), []string{"one", "two"}},
+		{`ZRANGEBYSCORE myzset (1 2`, zrangebyscore(SkipListFindRange{Min: 1, Max: 2, MinEx: true}), []string{"two"}},
+		{`ZRANGEBYSCORE myzset (1 (2`, zrangebyscore(SkipListFindRange{Min: 1, Max: 2, MinEx: true, MaxEx: true}), []string{}},
+	}},
+	{"ZRANK", []redisCmd{
+		zaddOneTwoThree,
+		{`ZRANK myzset "three"`, func(set *redisSortSet) any { return znil(set.ZRank("three")) }, int64(2)},
+		{`ZRANK myzset "four"`, func(set *redisSortSet) any { return znil(set.ZRank("four")) }, nil},
+	}},
+	{"ZREM", []redisCmd{
+		zaddOneTwoThree,
+		{`ZREM myzset "two"`, func(set *redisSortSet) any { return set.ZRem("two") }, 1},
+		{`ZRANGE myzset 0 -1 WITHSCORES`, zrange(0, -1, true), []string{"one", "1", "three", "3"}},
+		{`ZREM myzset "one" "one" "four"`, func(set *redisSortSet) any { return set.ZRem("one", "one", "four") }, 1},
+	}},
+	{"ZREMRANGEBYRANK", []redisCmd{
+		zaddOneTwoThree,
+		{`ZREMRANGEBYRANK myzset 0 1`, func(set *redisSortSet) any { return set.ZRemRangeByRank(0, 1) }, 2},
+		{`ZRANGE myzset 0 -1 WITHSCORES`, zrange(0, -1, true), []string{"three", "3"}},
+	}},
+	{"ZREMRANGEBYSCORE", []redisCmd{
+		zaddOneTwoThree,
+		{`ZREMRANGEBYSCORE myzset -inf (2`, func(set *redisSortSet) any {
+			return set.ZRemRangeByScore(&SkipListFindRange{MinInf: true, Max: 2, MaxEx: true})
+		}, 1},
+		{`ZRANGE myzset 0 -1 WITHSCORES`, zrange(0, -1, true), []string{"two", "2", "three", "3"}},
+	}},
+	{"ZREVRANGE", []redisCmd{
+		zaddOneTwoThree,
+		{`ZREVRANGE myzset 0 -1`, zrevrange(0, -1), []string{"three", "two", "one"}},
+		{`ZREVRANGE myzset 2 3`, zrevrange(2, 3), []string{"one"}},
+		{`ZREVRANGE myzset -2 -1`, zrevrange(-2, -1), []string{"two", "one"}},
+	}},
+	{"ZREVRANGEBYSCORE", []redisCmd{
+		zaddOneTwoThree,
+		{`ZREVRANGEBYSCORE myzset +inf -inf`, zrevrangebyscore(SkipListFindRange{MinInf: true, MaxInf: true}), []string{"three", "two", "one"}},
+		{`ZREVRANGEBYSCORE myzset 2 1`, zrevrangebyscore(SkipListFindRange{Min: 2, Max: 1}), []string{"two", "one"}},
+		{`ZREVRANGEBYSCORE myzset 2 (1`, zrevrangebyscore(SkipListFindRange{Min: 2, Max: 1, MaxEx: true}), []string{"two"}},
+		{`ZREVRANGEBYSCORE myzset (2 (1`, zrevrangebyscore(SkipListFindRange{Min: 2, Max: 1, MinEx: true, MaxEx: true}), []string{}},
+	}},
+	{"ZREVRANK", []redisCmd{
+		zaddOneTwoThree,
+		{`ZREVRANK myzset "one"`, func(set *redisSortSet) any { return znil(set.ZRevRank("one")) }, int64(2)},
+		{`ZREVRANK myzset "four"`, func(set *redisSortSet) any { return znil(set.ZRevRank("four")) }, nil},
+	}},
+	{"ZSCORE", []redisCmd{
+		{`ZADD myzset 1 "one"`, zadd(zitem("one", 1)), 1},
+		{`ZSCORE myzset "one"`, func(set *redisSortSet) any { return znil(set.ZScore("one")) }, float64(1)},
+		{`ZSCORE myzset "two"`, func(set *redisSortSet) any { return znil(set.ZScore("two")) }, nil},
+	}},
+	{"ZADD options", []redisCmd{
+		{`ZADD myzset 1 "a"`, zadd(zitem("a", 1)), 1},
+		{`ZADD myzset NX 5 "a" 2 "b"`, zaddFlags(ZADD_NX, zitem("a", 5), zitem("b", 2)), 1},
+		{`ZSCORE myzset "a"`, func(set *redisSortSet) any { return znil(set.ZScore("a")) }, float64(1)},
+		{`ZADD myzset XX 3 "a" 4 "c"`, zaddFlags(ZADD_XX, zitem("a", 3), zitem("c", 4)), 0},
+		{`ZSCORE myzset "a"`, func(set *redisSortSet) any { return znil(set.ZScore("a")) }, float64(3)},
+		{`ZSCORE myzset "c"`, func(set *redisSortSet) any { return znil(set.ZScore("c")) }, nil},
+		{`ZADD myzset GT CH 2 "a" 6 "b"`, zaddFlags(ZADD_GT|ZADD_CH, zitem("a", 2), zitem("b", 6)), 1},
+		{`ZADD myzset LT CH 1 "a" 9 "d"`, zaddFlags(ZADD_LT|ZADD_CH, zitem("a", 1), zitem("d", 9)), 2},
+		{`ZADD myzset CH 1 "a"`, zaddFlags(ZADD_CH, zitem("a", 1)), 0},
+		{`ZRANGE myzset 0 -1 WITHSCORES`, zrange(0, -1, true), []string{"a", "1", "b", "6", "d", "9"}},
+		{`ZADD myzset NX XX 1 "a"`, zaddFlags(ZADD_NX|ZADD_XX, zitem("a", 1)), "ERR"},
+		{`ZADD myzset GT LT 1 "a"`, zaddFlags(ZADD_GT|ZADD_LT, zitem("a", 1)), "ERR"},
+		{`ZADD myzset NX GT 1 "a"`, zaddFlags(ZADD_NX|ZADD_GT, zitem("a", 1)), "ERR"},
+	}},
+}
+
+func TestSortSet_RedisConformance(t *testing.T) {
+	for _, c := range sortSetIndexCases {
+		//redis 的有序集合是从小到大排序的
+		if c.config.Order == SCORE_ORDER_DESC {
+			continue
+		}
+		t.Run(c.name, func(t *testing.T) {
+			for _, example := range redisConformance {
+				set, err := NewSortSetWithConfig[string, *StItem[string]](c.config, compareStItemKey)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, cmd := range example.cmds {
+					if got := cmd.run(set); !reflect.DeepEqual(got, cmd.want) {
+						t.Fatalf("%s: %s got %#v want %#v", example.name, cmd.cmd, got, cmd.want)
+					}
+				}
+				if err := set.Validate(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// 原来的方法保持不变: Add 包括更新分数的元素, Remove 总是返回 0, Rank 不存在返回 0, 需要 redis 的返回值时使用 Z* 方法
+func TestSortSet_LegacyCounts(t *testing.T) {
+	set, err := NewDefaultSortSet[string, *StItem[string]](compareStItemKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := set.Add(zitem("one", 1), zitem("two", 2)); n != 2 {
+		t.Fatalf("Add %d", n)
+	}
+	if n := set.Add(zitem("one", 5), zitem("three", 3), zitem("three", 4)); n != 2 {
+		t.Fatalf("Add with update %d", n)
+	}
+	if n := set.Add(zitem("one", 6)); n != 1 {
+		t.Fatalf("Add update %d", n)
+	}
+	if n := set.Add(zitem("nan", math.NaN())); n != 0 || set.Count() != 3 {
+		t.Fatalf("Add NaN %d count:%d", n, set.Count())
+	}
+	if n := set.Remove("one", "four"); n != 0 || set.Count() != 2 {
+		t.Fatalf("Remove %d count:%d", n, set.Count())
+	}
+	if set.Rank("one") != 0 || set.RevRank("one") != -1 {
+		t.Fatalf("Rank of missing member %d %d", set.Rank("one"), set.RevRank("one"))
+	}
+	if n := set.ZRem("two", "four"); n != 1 {
+		t.Fatalf("ZRem %d", n)
+	}
+}
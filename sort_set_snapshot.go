@@ -0,0 +1,88 @@
+package skiptablev2
+
+import "cmp"
+
+// ScoredSortSetSnapshot
+// 有序集合在某一时刻的只读视图
+// 快照和原集合共享底层数据, 原集合在下一次修改时才会复制一份(写时复制),
+// 所以创建快照的代价是 O(1), 快照之后原集合的修改不会影响快照
+// 使用B树索引(SORT_SET_INDEX_BTREE)时复制是结构共享的, 只复制修改路径上的结点, 快照之后的修改是 O(log n) 的
+// 跳表的结点不能共享, 使用跳表索引时快照之后的第一次修改要复制整个集合, 是 O(n) 的
+type ScoredSortSetSnapshot[K comparable, S cmp.Ordered, V ScoredItem[K, S]] struct {
+	set *ScoredSortSet[K, S, V]
+}
+
+// Snapshot
+// 获取当前有序集合的只读快照
+// 调用 Snapshot 时需要和写操作互斥, 拿到快照后, 读快照和写原集合可以并发进行
+// 创建快照是 O(1) 的, 代价在原集合之后的第一次修改上: B树索引复制修改路径上的结点, 跳表索引复制整个集合
+func (set *ScoredSortSet[K, S, V]) Snapshot() *ScoredSortSetSnapshot[K, S, V] {
+	set.shared = true
+	return &ScoredSortSetSnapshot[K, S, V]{
+		set: &ScoredSortSet[K, S, V]{
+			enc:     set.enc,
+			config:  set.config,
+			compare: set.compare,
+		},
+	}
+}
+
+// Count
+// 快照中元素数量
+func (snap *ScoredSortSetSnapshot[K, S, V]) Count() int64 {
+	return snap.set.Count()
+}
+
+// Rank
+// 返回快照中指定成员的索引(从0开始)不存在返回 0, 和 ScoredSortSet.Rank 相同
+func (snap *ScoredSortSetSnapshot[K, S, V]) Rank(key K) int64 {
+	return snap.set.Rank(key)
+}
+
+// RevRank
+// 返回快照中指定成员的反向索引(从0开始)不存在返回 -1
+func (snap *ScoredSortSetSnapshot[K, S, V]) RevRank(key K) int64 {
+	return snap.set.RevRank(key)
+}
+
+// Score
+// 获取快照中元素的分数
+func (snap *ScoredSortSetSnapshot[K, S, V]) Score(key K) S {
+	return snap.set.Score(key)
+}
+
+// Range
+// 通过索引区间返回快照指定区间内的成员,分数从低到高
+func (snap *ScoredSortSetSnapshot[K, S, V]) Range(min, max int64) []V {
+	return snap.set.Range(min, max)
+}
+
+// RevRange
+// 通过索引区间返回快照指定区间内的成员,分数从高到低
+func (snap *ScoredSortSetSnapshot[K, S, V]) RevRange(min, max int64) []V {
+	return snap.set.RevRange(min, max)
+}
+
+// RangeByScore
+// 返回快照中指定分数区间内的成员，分数从低到高排序
+func (snap *ScoredSortSetSnapshot[K, S, V]) RangeByScore(findRange *ScoreRange[S]) []V {
+	return snap.set.RangeByScore(findRange)
+}
+
+// RevRangeByScore
+// 返回快照中指定分数区间内的成员，分数从高到低排序
+func (snap *ScoredSortSetSnapshot[K, S, V]) RevRangeByScore(findRange *ScoreRange[S]) []V {
+	return snap.set.RevRangeByScore(findRange)
+}
+
+// Around
+// 返回快照中 key 和它前后的元素
+func (snap *ScoredSortSetSnapshot[K, S, V]) Around(key K, before, after int64) []RankedValue[S, V] {
+	return snap.set.Around(key, before, after)
+}
+
+// RevAround
+// 按反向顺序返回快照中 key 和它前后的元素
+func (snap *ScoredSortSetSnapshot[K, S, V]) RevAround(key K, before, after int64) []RankedValue[S, V] {
+	return snap.set.RevAround(key, before, after)
+}
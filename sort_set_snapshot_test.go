@@ -0,0 +1,181 @@
+package skiptablev2
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSortSet_Snapshot(t *testing.T) {
+	sortSet := NewTestSortSet()
+	for i := 0; i < N; i++ {
+		sortSet.Add(CreateStItem())
+	}
+	before := sortSet.Range(0, -1)
+
+	snap := sortSet.Snapshot()
+
+	//快照之后修改原集合
+	sortSet.RemoveRangeByRank(0, N/2)
+	for i := 0; i < N; i++ {
+		sortSet.Add(CreateStItem())
+	}
+	if sortSet.Count() != N+N-(N/2+1) {
+		t.Fatalf("sortSet count:%d error", sortSet.Count())
+	}
+
+	//快照中的数据不受影响
+	if snap.Count() != N {
+		t.Fatalf("snapshot count:%d want:%d", snap.Count(), N)
+	}
+	after := snap.Range(0, -1)
+	if len(after) != len(before) {
+		t.Fatalf("snapshot range len:%d want:%d", len(after), len(before))
+	}
+	for i := range before {
+		if !compareItem(before[i], after[i]) {
+			t.Fatalf("snapshot item:%v rank:%d want:%v", after[i], i, before[i])
+		}
+		if snap.Rank(before[i].k) != int64(i) {
+			t.Fatalf("snapshot rank:%d want:%d", snap.Rank(before[i].k), i)
+		}
+		if snap.Score(before[i].k) != before[i].f {
+			t.Fatalf("snapshot score:%f want:%f", snap.Score(before[i].k), before[i].f)
+		}
+	}
+	rev := snap.RevRange(0, -1)
+	for i := range rev {
+		if !compareItem(rev[i], before[len(before)-1-i]) {
+			t.Fatalf("snapshot rev item:%v rank:%d error", rev[i], i)
+		}
+	}
+	byScore := snap.RangeByScore(&SkipListFindRange{MinInf: true, MaxInf: true})
+	if len(byScore) != N {
+		t.Fatalf("snapshot range by score len:%d want:%d", len(byScore), N)
+	}
+}
+
+func TestSortSet_SnapshotConcurrent(t *testing.T) {
+	sortSet := NewTestSortSet()
+	for i := 0; i < N; i++ {
+		sortSet.Add(CreateStItem())
+	}
+	snap := sortSet.Snapshot()
+	want := snap.Range(0, -1)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			result := snap.Range(0, -1)
+			for j := range result {
+				if !compareItem(result[j], want[j]) {
+					t.Errorf("snapshot item:%v rank:%d error", result[j], j)
+					return
+				}
+			}
+		}
+	}()
+	for i := 0; i < N; i++ {
+		sortSet.Add(CreateStItem())
+		sortSet.RemoveRangeByRank(0, 0)
+	}
+	wg.Wait()
+}
+
+// BenchmarkSortSet_FirstWriteAfterSnapshot
+// 快照之后的第一次修改: 跳表索引要复制整个集合, 代价和集合大小成正比, B树索引只复制路径上的结点
+// no-snapshot 是没有快照时同样的修改
+func BenchmarkSortSet_FirstWriteAfterSnapshot(b *testing.B) {
+	for _, index := range []string{SORT_SET_INDEX_SKIPLIST, SORT_SET_INDEX_BTREE} {
+		for _, size := range []int{1000, 100000} {
+			for _, snapshot := range []bool{false, true} {
+				name := index + "/" + strconv.Itoa(size) + "/no-snapshot"
+				if snapshot {
+					name = index + "/" + strconv.Itoa(size) + "/snapshot"
+				}
+				b.Run(name, func(b *testing.B) {
+					set, err := NewSortSetWithConfig[string, *StItem[string]](SortSetConfig{
+						MaxLevel: SKIP_TABLE_DEFAULT_MAX_LEVEL,
+						Index:    index,
+					}, compareStItemKey)
+					if err != nil {
+						b.Fatal(err)
+					}
+					for i := 0; i < size; i++ {
+						set.Add(&StItem[string]{f: float64(i), k: strconv.Itoa(i)})
+					}
+					item := &StItem[string]{k: "0"}
+					b.ReportAllocs()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						if snapshot {
+							set.Snapshot()
+						}
+						//修改一个已经存在的元素, 集合的大小不变
+						item.f = float64(i % size)
+						set.Add(item)
+					}
+				})
+			}
+		}
+	}
+}
+
+// 多个快照和原集合交替修改, 每个快照都保持创建时的数据, B树索引的快照和原集合共享没有修改过的结点
+func TestSortSet_SnapshotSharing(t *testing.T) {
+	for _, c := range sortSetIndexCases {
+		t.Run(c.name, func(t *testing.T) {
+			set, err := NewSortSetWithConfig[string, *StItem[string]](c.config, compareStItemKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			model := &sortSetModel{desc: c.config.Order == SCORE_ORDER_DESC}
+			type snapshotCase struct {
+				snap  *SortSetSnapshot[string, *StItem[string]]
+				items []*StItem[string]
+			}
+			var snaps []snapshotCase
+			for i := 0; i < 5000; i++ {
+				if i%250 == 0 {
+					snaps = append(snaps, snapshotCase{set.Snapshot(), slices.Clone(model.items)})
+				}
+				key := strconv.Itoa(rand.Intn(2000))
+				switch r := rand.Intn(20); {
+				case r == 0 && len(model.items) > 0:
+					left := rand.Intn(len(model.items))
+					right := min(left+rand.Intn(100), len(model.items)-1)
+					set.RemoveRangeByRank(int64(left), int64(right))
+					model.items = slices.Delete(model.items, left, right+1)
+				case r < 6:
+					set.Remove(key)
+					if j := model.find(key); j >= 0 {
+						model.items = slices.Delete(model.items, j, j+1)
+					}
+				default:
+					item := &StItem[string]{k: key, f: float64(rand.Intn(100))}
+					set.Add(item)
+					model.add(item)
+				}
+			}
+			if err := set.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			equalKeys(t, "set", set.Range(0, -1), model.items)
+			for i, s := range snaps {
+				if err := s.snap.set.Validate(); err != nil {
+					t.Fatalf("snapshot:%d %v", i, err)
+				}
+				equalKeys(t, "snapshot "+strconv.Itoa(i), s.snap.Range(0, -1), s.items)
+				for rank, item := range s.items {
+					if s.snap.Score(item.k) != item.f || s.snap.Rank(item.k) != int64(rank) {
+						t.Fatalf("snapshot:%d key:%s score:%v rank:%d want:%v %d", i, item.k, s.snap.Score(item.k), s.snap.Rank(item.k), item.f, rank)
+					}
+				}
+			}
+		})
+	}
+}
@@ -2,9 +2,12 @@ package skiptablev2
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -426,3 +429,275 @@ func BenchmarkSortSet_RankBenchArr(b *testing.B) {
 		})
 	}
 }
+
+func TestSortSet_BulkLoad(t *testing.T) {
+	arr := make(SortStItem[string], 0, N)
+	for i := 0; i < N; i++ {
+		arr = append(arr, CreateStItem())
+	}
+
+	//没有排序的数据,退化成逐个添加
+	sortSet := NewTestSortSet()
+	if n := sortSet.BulkLoad(arr); n != N || sortSet.Count() != N {
+		t.Fatalf("BulkLoad unsorted n:%d count:%d", n, sortSet.Count())
+	}
+
+	sort.Sort(arr)
+	sortSet = NewTestSortSet()
+	if n := sortSet.BulkLoad(arr); n != N || sortSet.Count() != N {
+		t.Fatalf("BulkLoad n:%d count:%d", n, sortSet.Count())
+	}
+	for i, r := range sortSet.Range(0, -1) {
+		if !compareItem(arr[i], r) {
+			t.Fatalf("item:%v rank:%d arrItem:%v error", r, i, arr[i])
+		}
+		if sortSet.Rank(r.k) != int64(i) {
+			t.Fatalf("item:%v rank:%d want:%d", r, sortSet.Rank(r.k), i)
+		}
+	}
+
+	//有重复的key,退化成逐个添加
+	dup := append(SortStItem[string]{}, arr...)
+	dup = append(dup, &StItem[string]{f: arr[N-1].f + 1, k: arr[0].k})
+	sortSet = NewTestSortSet()
+	if n := sortSet.BulkLoad(dup); n != N || sortSet.Count() != N {
+		t.Fatalf("BulkLoad duplicate key n:%d count:%d", n, sortSet.Count())
+	}
+	if sortSet.Score(arr[0].k) != arr[N-1].f+1 {
+		t.Fatalf("duplicate key score:%f want:%f", sortSet.Score(arr[0].k), arr[N-1].f+1)
+	}
+}
+
+func BenchmarkSortSet_BulkLoad(b *testing.B) {
+	const SIZE = 100000
+	arr := make(SortStItem[string], 0, SIZE)
+	for i := 0; i < SIZE; i++ {
+		arr = append(arr, CreateStItem())
+	}
+	sort.Sort(arr)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sortSet := NewTestSortSet()
+		sortSet.BulkLoad(arr)
+	}
+}
+
+func TestSortSet_Allocs(t *testing.T) {
+	const SIZE = 10000
+	sortSet := NewTestSortSet()
+	items := make([]*StItem[string], SIZE)
+	for i := range items {
+		items[i] = CreateStItem()
+	}
+
+	//新增元素: 只有结点本身的一次内存分配(map扩容均摊后可以忽略)
+	i := 0
+	allocs := testing.AllocsPerRun(SIZE-1, func() {
+		sortSet.Add(items[i])
+		i++
+	})
+	if allocs > 1 {
+		t.Fatalf("Add new member allocs:%f want <= 1", allocs)
+	}
+
+	//更新分数: 复用原来的结点,不分配内存
+	i = 0
+	allocs = testing.AllocsPerRun(SIZE-1, func() {
+		items[i].f = rand.Float64()
+		sortSet.Add(items[i])
+		i++
+	})
+	if allocs > 0 {
+		t.Fatalf("Add update member allocs:%f want 0", allocs)
+	}
+	for _, item := range items {
+		if sortSet.Score(item.k) != item.f {
+			t.Fatalf("item:%v score:%f want:%f", item, sortSet.Score(item.k), item.f)
+		}
+	}
+	sorted := append(SortStItem[string]{}, items...)
+	sort.Sort(sorted)
+	for rank, item := range sorted {
+		if sortSet.Rank(item.k) != int64(rank) {
+			t.Fatalf("item:%v rank:%d want:%d", item, sortSet.Rank(item.k), rank)
+		}
+	}
+
+	//删除元素: 不分配内存
+	i = 0
+	allocs = testing.AllocsPerRun(SIZE-1, func() {
+		sortSet.Remove(items[i].k)
+		i++
+	})
+	if allocs > 0 {
+		t.Fatalf("Remove allocs:%f want 0", allocs)
+	}
+}
+
+func BenchmarkSortSet_Add(b *testing.B) {
+	items := make([]*StItem[string], b.N)
+	for i := range items {
+		items[i] = CreateStItem()
+	}
+	sortSet := NewTestSortSet()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sortSet.Add(items[i])
+	}
+}
+
+type Int64Item struct {
+	k string
+	s int64
+}
+
+func (s *Int64Item) Key() string {
+	return s.k
+}
+
+func (s *Int64Item) Score() int64 {
+	return s.s
+}
+
+func TestScoredSortSet_Int64(t *testing.T) {
+	for _, listpackMaxEntries := range []int{0, SORT_SET_DEFAULT_LISTPACK_ENTRIES} {
+		sortSet, err := NewScoredSortSet[string, int64, *Int64Item](SortSetConfig{
+			MaxLevel:           SKIP_TABLE_DEFAULT_MAX_LEVEL,
+			ListpackMaxEntries: listpackMaxEntries,
+		}, func(v1, v2 *Int64Item) int {
+			return strings.Compare(v1.k, v2.k)
+		})
+		if err != nil {
+			panic(err)
+		}
+
+		//超过 2^53 的分数, 用 float64 表示时相邻的值会相等
+		const base = int64(1) << 60
+		perm := rand.Perm(N)
+		for _, i := range perm {
+			sortSet.Add(&Int64Item{k: strconv.Itoa(i), s: base + int64(i)})
+		}
+		for i, r := range sortSet.Range(0, -1) {
+			if r.s != base+int64(i) {
+				t.Fatalf("item:%v rank:%d error", r, i)
+			}
+			if sortSet.Score(r.k) != base+int64(i) || sortSet.Rank(r.k) != int64(i) {
+				t.Fatalf("item:%v score:%d rank:%d error", r, sortSet.Score(r.k), sortSet.Rank(r.k))
+			}
+		}
+		result := sortSet.RangeByScore(&ScoreRange[int64]{Min: base + 10, Max: base + 11})
+		if len(result) != 2 || result[0].s != base+10 || result[1].s != base+11 {
+			t.Fatalf("range by score result:%v error", result)
+		}
+		if n := sortSet.RemoveRangeByScore(base+1, base+1); n != 1 || sortSet.Count() != N-1 {
+			t.Fatalf("remove range by score n:%d count:%d", n, sortSet.Count())
+		}
+	}
+}
+
+// 从大到小的集合, 正向的结果和从小到大的集合反向的结果相同
+func TestSortSet_Desc(t *testing.T) {
+	const SIZE = 500
+	asc := newOrderTestSortSet(0, SCORE_ORDER_ASC)
+	descSets := map[string]*SortSet[string, *StItem[string]]{
+		SORT_SET_ENCODING_LISTPACK: newOrderTestSortSet(SIZE*2, SCORE_ORDER_DESC),
+		SORT_SET_ENCODING_SKIPLIST: newOrderTestSortSet(0, SCORE_ORDER_DESC),
+	}
+	for i := 0; i < SIZE*3; i++ {
+		item := &StItem[string]{
+			f: float64(rand.Intn(50)),
+			k: strconv.Itoa(rand.Intn(SIZE)),
+		}
+		asc.Add(item)
+		for _, desc := range descSets {
+			desc.Add(&StItem[string]{f: item.f, k: item.k})
+		}
+		if i%10 == 0 {
+			key := strconv.Itoa(rand.Intn(SIZE))
+			asc.Remove(key)
+			for _, desc := range descSets {
+				desc.Remove(key)
+			}
+		}
+	}
+
+	for name, desc := range descSets {
+		if desc.Encoding() != name {
+			t.Fatalf("encoding:%s want:%s", desc.Encoding(), name)
+		}
+		equalItems(t, name+" Range", desc.Range(0, -1), asc.RevRange(0, -1))
+		equalItems(t, name+" RevRange", desc.RevRange(0, -1), asc.Range(0, -1))
+		for i := 0; i < 100; i++ {
+			l := rand.Int63n(SIZE) - SIZE/2
+			r := rand.Int63n(SIZE) - SIZE/2
+			equalItems(t, name+" Range", desc.Range(l, r), asc.RevRange(l, r))
+
+			findRange := &SkipListFindRange{
+				Min:    float64(rand.Intn(60) - 5),
+				Max:    float64(rand.Intn(60) - 5),
+				MinInf: rand.Intn(4) == 0,
+				MaxInf: rand.Intn(4) == 0,
+			}
+			result := asc.RangeByScore(findRange)
+			slices.Reverse(result)
+			equalItems(t, name+" RangeByScore", desc.RangeByScore(findRange), result)
+
+			key := strconv.Itoa(rand.Intn(SIZE))
+			if desc.Rank(key) != asc.RevRank(key) && asc.RevRank(key) != -1 {
+				t.Fatalf("%s key:%s rank %d != %d", name, key, desc.Rank(key), asc.RevRank(key))
+			}
+		}
+	}
+}
+
+func TestSortSet_NaNAndInf(t *testing.T) {
+	for _, c := range sortSetIndexCases {
+		t.Run(c.name, func(t *testing.T) {
+			set, err := NewSortSetWithConfig[string, *StItem[string]](c.config, compareStItemKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			//分数是 NaN 的元素被忽略, 同一个 key 前面的元素仍然有效
+			if n := set.Add(&StItem[string]{k: "a", f: 1}, &StItem[string]{k: "a", f: math.NaN()}, &StItem[string]{k: "b", f: math.NaN()}); n != 1 {
+				t.Fatalf("Add with NaN:%d", n)
+			}
+			if score, ok := set.ZScore("a"); !ok || score != 1 {
+				t.Fatalf("ZScore a:%f %v", score, ok)
+			}
+			if _, err = set.ZAdd(0, &StItem[string]{k: "c", f: 3}, &StItem[string]{k: "a", f: math.NaN()}); err == nil || set.Count() != 1 {
+				t.Fatalf("ZAdd NaN err:%v count:%d", err, set.Count())
+			}
+			if n := set.Add(&StItem[string]{k: "max", f: math.Inf(1)}, &StItem[string]{k: "min", f: math.Inf(-1)}, &StItem[string]{k: "zero", f: 0}); n != 3 {
+				t.Fatalf("Add Inf:%d", n)
+			}
+			want := []*StItem[string]{{k: "min"}, {k: "zero"}, {k: "a"}, {k: "max"}}
+			if c.config.Order == SCORE_ORDER_DESC {
+				slices.Reverse(want)
+			}
+			equalKeys(t, "Range", set.Range(0, -1), want)
+			if a := set.AggregateByScore(&SkipListFindRange{MinInf: true, MaxInf: true}); a.Count != 4 || a.Sum != 1 {
+				t.Fatalf("AggregateByScore:%v", a)
+			}
+			set.Remove("max", "min")
+			if a := set.AggregateByRank(0, -1); a.Count != 2 || a.Sum != 1 {
+				t.Fatalf("AggregateByRank after remove:%v", a)
+			}
+			if n := len(set.RangeByScore(&SkipListFindRange{Min: math.NaN(), MaxInf: true})); n != 0 {
+				t.Fatalf("RangeByScore NaN:%d", n)
+			}
+			if err = set.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			//BulkLoad 中有 NaN 时退化成 Add, NaN 的元素被忽略
+			bulk, _ := NewSortSetWithConfig[string, *StItem[string]](c.config, compareStItemKey)
+			if n := bulk.BulkLoad([]*StItem[string]{{k: "a", f: 1}, {k: "b", f: math.NaN()}, {k: "c", f: 3}}); n != 2 {
+				t.Fatalf("BulkLoad with NaN:%d", n)
+			}
+			if err = bulk.Validate(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
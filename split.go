@@ -0,0 +1,262 @@
+package skiptablev2
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SplitAtRank
+// 把排名 > rank 的结点移到一个新的跳表中返回, 原跳表保留排名 <= rank 的结点, rank 超过 Size 时新跳表是空的
+// 结点不会被复制也不会重新插入, 只修改分界处每一层的指针、span、分数之和和不同分数的数量,
+// 再把被移走的结点标记成属于新跳表(调用过 EnableUniqueKeys 时同时移动它们的 key), 复杂度 O(log n + 移走的结点数量)
+// 被移走的元素的句柄仍然有效, 但是只能在新跳表上使用
+func (list *ScoredSkipList[K, S, V]) SplitAtRank(rank int64) (*ScoredSkipList[K, S, V], error) {
+	if rank < 0 {
+		return nil, fmt.Errorf("SplitAtRank rank %d is negative", rank)
+	}
+	bounds := make([]rangeBound[K, S, V], list.level)
+	list.descend(bounds, func(_ *ScoredSkipListNode[K, S, V], r int64) bool {
+		return r <= rank
+	})
+	return list.splitAt(bounds), nil
+}
+
+// SplitAtScore
+// 把分数不排在 score 前面的结点(从小到大排序时就是分数 >= score 的结点)移到一个新的跳表中返回, 其余和 SplitAtRank 相同
+func (list *ScoredSkipList[K, S, V]) SplitAtScore(score S) (*ScoredSkipList[K, S, V], error) {
+	if scoreIsNaN(score) {
+		return nil, errors.New("SplitAtScore score is NaN")
+	}
+	bounds := make([]rangeBound[K, S, V], list.level)
+	list.descend(bounds, func(node *ScoredSkipListNode[K, S, V], _ int64) bool {
+		return list.less(node.score, score)
+	})
+	return list.splitAt(bounds), nil
+}
+
+// splitAt
+// bounds[i] 是第i层最后一个留在原跳表的结点, 把它们后面的结点都移到新跳表中
+func (list *ScoredSkipList[K, S, V]) splitAt(bounds []rangeBound[K, S, V]) *ScoredSkipList[K, S, V] {
+	nl := list.newEmpty()
+	cut := bounds[0].node
+	first := cut.Next(0)
+	if first == nil {
+		return nl
+	}
+	//留在原跳表的结点的数量、分数之和和不同分数的数量
+	rank, sum, groups := bounds[0].rank, bounds[0].sum, bounds[0].groups
+	//first 在新跳表中一定是一组相同分数的第一个结点
+	delta := 1 - list.groupStart(cut, first.score)
+	for i := 0; i < list.level; i++ {
+		b := bounds[i]
+		u := &b.node.level[i]
+		h := &nl.head.level[i]
+		if u.forward != nil {
+			//u 的下一个结点是新跳表第i层的第一个结点
+			h.forward = u.forward
+			h.span = b.rank + u.span - rank
+			h.sum = b.sum + u.sum - sum
+			h.groups = b.groups + u.groups - groups + delta
+			nl.level = i + 1
+		} else {
+			h.span = list.size - rank
+			h.sum = list.sum - sum
+			h.groups = list.groups - groups + delta
+		}
+		//u 变成原跳表第i层的最后一个结点
+		u.forward = nil
+		u.span = rank - b.rank
+		u.sum = sum - b.sum
+		u.groups = groups - b.groups
+	}
+	nl.size = list.size - rank
+	nl.sum = list.sum - sum
+	nl.groups = list.groups - groups + delta
+	nl.tail = list.tail
+	first.backward = nil
+
+	list.size = rank
+	list.sum = sum
+	list.groups = groups
+	list.tail = cut
+	if cut == list.head {
+		list.tail = nil
+	}
+	for list.level > 1 && list.head.Next(list.level-1) == nil {
+		list.level--
+	}
+	for t := first; t != nil; t = t.Next(0) {
+		t.owner = nl.id
+		if list.keys != nil {
+			delete(list.keys, t.value.Key())
+			nl.keys[t.value.Key()] = t
+		}
+	}
+	return nl
+}
+
+// Concat
+// 把 other 的所有结点接到跳表的末尾, other 变成空跳表, 结点不会被复制也不会重新插入
+// other 的第一个结点必须排在跳表的最后一个结点后面, 两个跳表的排序方向必须相同, other 的层数不能超过跳表的最大层数;
+// 调用过 EnableUniqueKeys 时 other 中不能有跳表中已经存在的 key, 不满足时返回错误, 两个跳表都不会被修改
+// 复杂度 O(log n + other 的结点数量), 需要把 other 的结点标记成属于这个跳表, 有 key 的索引时同时移动 key;
+// other 中元素的句柄仍然有效, 但是只能在这个跳表上使用
+func (list *ScoredSkipList[K, S, V]) Concat(other *ScoredSkipList[K, S, V]) error {
+	if other == list {
+		return errors.New("Concat a skip list with itself")
+	}
+	if other.desc != list.desc {
+		return errors.New("Concat skip lists have different orders")
+	}
+	if other.size == 0 {
+		return nil
+	}
+	if other.level > list.maxLevel {
+		return fmt.Errorf("Concat other has %d levels, more than max level %d", other.level, list.maxLevel)
+	}
+	first := other.head.Next(0)
+	if last := list.tail; last != nil && !list.before(last.score, last.value, first.score, first.value) {
+		return fmt.Errorf("Concat first key %v of other is not after last key %v", first.value.Key(), last.value.Key())
+	}
+	if list.keys != nil {
+		seen := make(map[K]struct{}, other.size)
+		for t := first; t != nil; t = t.Next(0) {
+			_, dup := seen[t.value.Key()]
+			if _, ok := list.keys[t.value.Key()]; ok || dup {
+				return fmt.Errorf("Concat key %v already exists", t.value.Key())
+			}
+			seen[t.value.Key()] = struct{}{}
+		}
+	}
+
+	level := max(list.level, other.level)
+	bounds := make([]rangeBound[K, S, V], level)
+	list.descend(bounds, func(*ScoredSkipListNode[K, S, V], int64) bool {
+		return true
+	})
+	for i := list.level; i < level; i++ {
+		bounds[i] = rangeBound[K, S, V]{node: list.head}
+	}
+	//first 在 other 中是一组相同分数的第一个结点, 接在 tail 后面时可能不是了
+	delta := list.groupStart(list.tail, first.score) - 1
+	for i := 0; i < level; i++ {
+		b := bounds[i]
+		u := &b.node.level[i]
+		h := &other.head.level[i]
+		//b.node 是第i层的最后一个结点, 它的 span 是到结尾的距离
+		if i < other.level && h.forward != nil {
+			u.forward = h.forward
+			u.span = list.size - b.rank + h.span
+			u.sum = list.sum - b.sum + h.sum
+			u.groups = list.groups - b.groups + h.groups + delta
+		} else {
+			u.span = list.size - b.rank + other.size
+			u.sum = list.sum - b.sum + other.sum
+			u.groups = list.groups - b.groups + other.groups + delta
+		}
+	}
+	first.backward = list.tail
+	list.tail = other.tail
+	list.size += other.size
+	list.sum += other.sum
+	list.groups += other.groups + delta
+	list.level = level
+	for t := first; t != nil; t = t.Next(0) {
+		t.owner = list.id
+		if list.keys != nil {
+			list.keys[t.value.Key()] = t
+		}
+	}
+	other.detach()
+	return nil
+}
+
+// SplitAtRank
+// 把排名 > rank 的元素(也就是从下标 rank 开始的元素)移到一个新的有序集合中返回, 原集合保留前 rank 个元素
+// 新集合的配置、比较函数和内存池都和原集合相同; 使用跳表编码或B树索引时只沿着分界处切开跳表或B树并移动被移走元素的map项, 不会重新插入
+func (set *ScoredSortSet[K, S, V]) SplitAtRank(rank int64) (*ScoredSortSet[K, S, V], error) {
+	if rank < 0 {
+		return nil, fmt.Errorf("SplitAtRank rank %d is negative", rank)
+	}
+	return set.splitAt(min(rank, set.enc.count())), nil
+}
+
+// SplitAtScore
+// 把分数不排在 score 前面的元素(从小到大排序时就是分数 >= score 的元素)移到一个新的有序集合中返回, 其余和 SplitAtRank 相同
+func (set *ScoredSortSet[K, S, V]) SplitAtScore(score S) (*ScoredSortSet[K, S, V], error) {
+	if scoreIsNaN(score) {
+		return nil, errors.New("SplitAtScore score is NaN")
+	}
+	before, _, _ := set.enc.tieCounts(score)
+	return set.splitAt(before), nil
+}
+
+// splitAt
+// 把排名 > rank 的元素移到新集合中, 两个集合之后都可能需要转换编码
+func (set *ScoredSortSet[K, S, V]) splitAt(rank int64) *ScoredSortSet[K, S, V] {
+	set.copyOnWrite()
+	nset := &ScoredSortSet[K, S, V]{
+		enc:     set.enc.split(rank),
+		config:  set.config,
+		compare: set.compare,
+		pool:    set.pool,
+	}
+	nset.convert()
+	return nset
+}
+
+// Concat
+// 把 other 的所有元素移到集合的末尾, other 变成空集合
+// other 的第一个元素必须排在集合的最后一个元素后面, 两个集合的排序方向必须相同, 不能有相同的 key, 不满足时返回错误, 两个集合都不会被修改
+// 两个集合的编码相同时直接拼接底层数据, 使用跳表编码或B树索引时复杂度 O(log n + other 的元素数量), 其余情况逐个添加 other 的元素
+func (set *ScoredSortSet[K, S, V]) Concat(other *ScoredSortSet[K, S, V]) error {
+	if other == set {
+		return errors.New("Concat a sort set with itself")
+	}
+	if other.config.Order != set.config.Order {
+		return errors.New("Concat sort sets have different orders")
+	}
+	n := other.enc.count()
+	if n == 0 {
+		return nil
+	}
+	if set.enc.count() > 0 {
+		order := scoreOrder[S, V]{compare: set.compare, desc: set.config.Order == SCORE_ORDER_DESC}
+		last := set.enc.revValuesByRank(1, 1)[0]
+		lastScore, _ := set.enc.score(last.Key())
+		var err error
+		other.enc.scan(func(score S, value V) bool {
+			if !order.before(lastScore, last, score, value) {
+				err = fmt.Errorf("Concat first key %v of other is not after last key %v", value.Key(), last.Key())
+				return false
+			}
+			if _, ok := set.enc.score(value.Key()); ok {
+				err = fmt.Errorf("Concat key %v already exists", value.Key())
+				return false
+			}
+			//other 本身是有序的, 和前一个元素比较一定成立, 实际上只有第一个元素需要检查顺序
+			lastScore, last = score, value
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	set.copyOnWrite()
+	other.copyOnWrite()
+	defer set.convert()
+	if set.enc.concat(other.enc) {
+		return nil
+	}
+	//编码不同, 逐个添加
+	if lp, ok := set.enc.(*listpack[K, S, V]); ok && lp.count()+n > int64(set.config.ListpackMaxEntries) {
+		set.expand(lp)
+	}
+	other.enc.scan(func(score S, value V) bool {
+		set.enc.addScore(score, value)
+		return true
+	})
+	other.enc = other.newEmptyEncoding()
+	return nil
+}
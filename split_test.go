@@ -0,0 +1,271 @@
+package skiptablev2
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newSplitTestList(t *testing.T, order ScoreOrder, n int, unique bool) (*SkipList[string, *S1[string]], []*S1[string]) {
+	st, err := NewSkipTableWithOrder[string, *S1[string]](SKIP_TABLE_DEFAULT_MAX_LEVEL, order, func(v1, v2 *S1[string]) int {
+		return strings.Compare(v1.key, v2.key)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unique {
+		if err = st.EnableUniqueKeys(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		score := float64(rand.Intn(n/4 + 1))
+		st.InsertByScore(score, &S1[string]{key: strconv.Itoa(i), f: score})
+	}
+	return st, st.GetValuesByRank(1, st.Size())
+}
+
+func checkSplitList(t *testing.T, op string, st *SkipList[string, *S1[string]], want []*S1[string]) {
+	t.Helper()
+	if err := st.Validate(); err != nil {
+		t.Fatalf("%s: %v", op, err)
+	}
+	got := st.GetValuesByRank(1, st.Size())
+	if len(got) != len(want) {
+		t.Fatalf("%s size:%d want:%d", op, len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s item:%v want:%v at:%d", op, got[i], want[i], i)
+		}
+	}
+	if st.keys != nil {
+		for _, v := range want {
+			if node, ok := st.GetNodeByKey(v.key); !ok || node.value != v {
+				t.Fatalf("%s key %s is not in the key index", op, v.key)
+			}
+		}
+	}
+}
+
+func TestSkipList_SplitConcat(t *testing.T) {
+	for _, order := range []ScoreOrder{SCORE_ORDER_ASC, SCORE_ORDER_DESC} {
+		for _, unique := range []bool{false, true} {
+			for i := 0; i < 50; i++ {
+				n := rand.Intn(300)
+				st, all := newSplitTestList(t, order, n, unique)
+				rank := rand.Int63n(int64(n) + 2)
+				right, err := st.SplitAtRank(rank)
+				if err != nil {
+					t.Fatal(err)
+				}
+				k := min(int(rank), n)
+				checkSplitList(t, "SplitAtRank left", st, all[:k])
+				checkSplitList(t, "SplitAtRank right", right, all[k:])
+
+				//再按分数切一次
+				score := float64(rand.Intn(n/4 + 2))
+				tail, err := right.SplitAtScore(score)
+				if err != nil {
+					t.Fatal(err)
+				}
+				j := k
+				for j < n && right.less(all[j].f, score) {
+					j++
+				}
+				checkSplitList(t, "SplitAtScore left", right, all[k:j])
+				checkSplitList(t, "SplitAtScore right", tail, all[j:])
+
+				//按顺序接回去
+				if err = st.Concat(right); err != nil {
+					t.Fatal(err)
+				}
+				checkSplitList(t, "Concat", st, all[:j])
+				checkSplitList(t, "Concat other", right, nil)
+				if err = st.Concat(tail); err != nil {
+					t.Fatal(err)
+				}
+				checkSplitList(t, "Concat all", st, all)
+				//接回去之后仍然可以正常插入和删除
+				st.InsertByScore(-1, &S1[string]{key: "new", f: -1})
+				st.DeleteRangeByRank(1, 3, nil)
+				if err = st.Validate(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+}
+
+func TestSkipList_ConcatError(t *testing.T) {
+	st, _ := newSplitTestList(t, SCORE_ORDER_ASC, 100, true)
+	right, _ := st.SplitAtRank(50)
+	//顺序不对
+	if err := right.Concat(st); err == nil {
+		t.Fatal("Concat overlapping lists")
+	}
+	if err := st.Concat(st); err == nil {
+		t.Fatal("Concat itself")
+	}
+	//key 重复
+	dup, _ := newSplitTestList(t, SCORE_ORDER_ASC, 0, false)
+	dup.InsertByScore(1000, &S1[string]{key: right.tail.value.key, f: 1000})
+	if err := right.Concat(dup); err == nil {
+		t.Fatal("Concat duplicated key")
+	}
+	desc, _ := newSplitTestList(t, SCORE_ORDER_DESC, 10, false)
+	if err := right.Concat(desc); err == nil {
+		t.Fatal("Concat lists with different orders")
+	}
+	if _, err := st.SplitAtRank(-1); err == nil {
+		t.Fatal("SplitAtRank negative rank")
+	}
+	if err := st.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := right.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkSplitSet(t *testing.T, op string, set *SortSet[string, *StItem[string]], want []*StItem[string]) {
+	t.Helper()
+	if err := set.Validate(); err != nil {
+		t.Fatalf("%s: %v", op, err)
+	}
+	equalKeys(t, op, set.Range(0, -1), want)
+	for i, item := range want {
+		if set.Score(item.k) != item.f || set.Rank(item.k) != int64(i) {
+			t.Fatalf("%s key %s score:%v rank:%d", op, item.k, set.Score(item.k), set.Rank(item.k))
+		}
+	}
+}
+
+func TestSortSet_SplitConcat(t *testing.T) {
+	for _, c := range sortSetIndexCases {
+		t.Run(c.name, func(t *testing.T) {
+			testSortSetSplitConcat(t, func() *SortSet[string, *StItem[string]] {
+				set, err := NewSortSetWithConfig[string, *StItem[string]](c.config, compareStItemKey)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return set
+			}, c.config.Order == SCORE_ORDER_DESC)
+		})
+	}
+	//跳表通过 orderedIndex 接口使用
+	t.Run("skiplist-index", func(t *testing.T) {
+		testSortSetSplitConcat(t, func() *SortSet[string, *StItem[string]] {
+			set, err := NewSortSetWithConfig[string, *StItem[string]](SortSetConfig{MaxLevel: SKIP_TABLE_DEFAULT_MAX_LEVEL}, compareStItemKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if set.enc, err = newIndexEncoding[string, float64, *StItem[string]](SORT_SET_INDEX_SKIPLIST, SKIP_TABLE_DEFAULT_MAX_LEVEL, SCORE_ORDER_ASC, compareStItemKey); err != nil {
+				t.Fatal(err)
+			}
+			return set
+		}, false)
+	})
+}
+
+func testSortSetSplitConcat(t *testing.T, newSet func() *SortSet[string, *StItem[string]], desc bool) {
+	for i := 0; i < 30; i++ {
+		set := newSet()
+		n := rand.Intn(100)
+		model := &sortSetModel{desc: desc}
+		for j := 0; j < n; j++ {
+			item := &StItem[string]{f: float64(rand.Intn(n/4 + 1)), k: strconv.Itoa(j)}
+			set.Add(item)
+			model.add(item)
+		}
+		//快照不受影响
+		snap := set.Snapshot()
+		all := model.items
+
+		rank := rand.Int63n(int64(n) + 2)
+		right, err := set.SplitAtRank(rank)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k := min(int(rank), n)
+		checkSplitSet(t, "SplitAtRank left", set, all[:k])
+		checkSplitSet(t, "SplitAtRank right", right, all[k:])
+		equalKeys(t, "Snapshot", snap.Range(0, -1), all)
+
+		score := float64(rand.Intn(n/4 + 2))
+		tail, err := right.SplitAtScore(score)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j := k
+		for j < n && (all[j].f < score && !desc || all[j].f > score && desc) {
+			j++
+		}
+		checkSplitSet(t, "SplitAtScore left", right, all[k:j])
+		checkSplitSet(t, "SplitAtScore right", tail, all[j:])
+
+		if err = set.Concat(right); err != nil {
+			t.Fatal(err)
+		}
+		if err = set.Concat(tail); err != nil {
+			t.Fatal(err)
+		}
+		checkSplitSet(t, "Concat", set, all)
+		checkSplitSet(t, "Concat other", tail, nil)
+		//被清空的集合仍然可以使用
+		tail.Add(&StItem[string]{f: 1, k: "x"})
+		checkSplitSet(t, "Add after Concat", tail, []*StItem[string]{{f: 1, k: "x"}})
+	}
+}
+
+// TestSortSet_ConcatEncodings
+// 两个集合的编码不同时逐个添加
+func TestSortSet_ConcatEncodings(t *testing.T) {
+	small, _ := NewSortSetWithConfig[string, *StItem[string]](SortSetConfig{MaxLevel: SKIP_TABLE_DEFAULT_MAX_LEVEL, ListpackMaxEntries: 8}, compareStItemKey)
+	big, _ := NewSortSetWithConfig[string, *StItem[string]](SortSetConfig{MaxLevel: SKIP_TABLE_DEFAULT_MAX_LEVEL, Index: SORT_SET_INDEX_BTREE}, compareStItemKey)
+	var want []*StItem[string]
+	for i := 0; i < 20; i++ {
+		item := &StItem[string]{f: float64(i), k: strconv.Itoa(i)}
+		want = append(want, item)
+		if i < 4 {
+			small.Add(item)
+		} else {
+			big.Add(item)
+		}
+	}
+	if err := small.Concat(big); err != nil {
+		t.Fatal(err)
+	}
+	checkSplitSet(t, "Concat", small, want)
+	checkSplitSet(t, "Concat other", big, nil)
+	if small.Encoding() != SORT_SET_ENCODING_SKIPLIST {
+		t.Fatalf("Concat encoding:%s", small.Encoding())
+	}
+
+	//顺序不对和 key 重复时不做任何修改
+	other, _ := NewSortSetWithConfig[string, *StItem[string]](SortSetConfig{MaxLevel: SKIP_TABLE_DEFAULT_MAX_LEVEL}, compareStItemKey)
+	other.Add(&StItem[string]{f: 10, k: "a"})
+	if err := small.Concat(other); err == nil {
+		t.Fatal("Concat overlapping sets")
+	}
+	other.Add(&StItem[string]{f: 100, k: "0"})
+	other.Remove("a")
+	if err := small.Concat(other); err == nil {
+		t.Fatal("Concat duplicated key")
+	}
+	if err := small.Concat(small); err == nil {
+		t.Fatal("Concat itself")
+	}
+	checkSplitSet(t, "Concat error", small, want)
+	if other.Count() != 1 {
+		t.Fatalf("Concat error other count:%d", other.Count())
+	}
+	if _, err := small.SplitAtScore(math.NaN()); err == nil {
+		t.Fatal("SplitAtScore NaN")
+	}
+	if _, err := small.SplitAtRank(-1); err == nil {
+		t.Fatal("SplitAtRank negative rank")
+	}
+}
@@ -0,0 +1,227 @@
+package skiptablev2
+
+import (
+	"cmp"
+	"unsafe"
+)
+
+const (
+	//SKIP_TABLE_STATS_SAMPLES
+	//Stats 统计查找路径长度时采样的查找次数
+	SKIP_TABLE_STATS_SAMPLES = 128
+)
+
+// SkipListStats
+// 跳表的统计信息, 用来根据真实数据调整 SKIPLIST_P 和最大层数
+type SkipListStats struct {
+	//结点数量
+	Size int64
+	//当前的最高层数
+	Level int
+	//最大层数
+	MaxLevel int
+	//LevelNodes[i] 是有第i层(从0开始)的结点数量, LevelNodes[0] 就是结点数量
+	LevelNodes []int64
+	//采样的查找次数
+	Samples int
+	//采样查找时平均比较的结点数量
+	AvgSearchPath float64
+	//采样查找时最多比较的结点数量
+	MaxSearchPath int
+	//所有结点(含头结点)占用的内存, 不包含 value 指向的数据, 是估算值
+	NodeBytes int64
+}
+
+// SortSetStats
+// 有序集合的统计信息
+type SortSetStats struct {
+	//当前的编码
+	Encoding string
+	//元素数量
+	Count int64
+	//有序索引(跳表、B树, 紧凑编码时是数组)占用的内存, 不包含 value 指向的数据, 是估算值
+	IndexBytes int64
+	//map 占用的内存, 紧凑编码没有map, 是估算值
+	MapBytes int64
+	//底层是跳表时跳表的统计信息, 其他编码是 nil
+	SkipList *SkipListStats
+}
+
+// Bytes
+// 有序集合占用的内存
+func (s SortSetStats) Bytes() int64 {
+	return s.IndexBytes + s.MapBytes
+}
+
+// skipListNodeBytes
+// 有 levels 层的跳表结点占用的内存
+func skipListNodeBytes[K comparable, S cmp.Ordered, V ScoredItem[K, S]](levels int) int64 {
+	var node ScoredSkipListNode[K, S, V]
+	var level ScoredSkipListLevel[K, S, V]
+	return int64(unsafe.Sizeof(node)) + int64(levels)*int64(unsafe.Sizeof(level))
+}
+
+// mapEntryBytes
+// map 中平均每个元素占用的内存
+// go 的 map 是 swiss table, 每组8个槽位和8字节的控制字, 最大负载因子是 7/8
+func mapEntryBytes[K comparable, V any]() float64 {
+	var k K
+	var v V
+	return float64(unsafe.Sizeof(k)+unsafe.Sizeof(v)+1) * 8 / 7
+}
+
+// memberEntryBytes
+// memberMap 中平均每个元素占用的内存, hamt 的内部结点很少, 只计算元素本身
+func memberEntryBytes[K comparable, T any](m memberMap[K, T]) float64 {
+	if _, ok := m.(*hamt[K, T]); ok {
+		var e hamtEntry[K, T]
+		return float64(unsafe.Sizeof(e))
+	}
+	return mapEntryBytes[K, T]()
+}
+
+// Stats
+// 统计跳表每一层的结点数量、查找路径的长度和占用的内存, 复杂度 O(n)
+// 查找路径是对 SKIP_TABLE_STATS_SAMPLES 个均匀分布的结点模拟查找得到的
+func (list *ScoredSkipList[K, S, V]) Stats() SkipListStats {
+	stats := SkipListStats{
+		Size:       list.size,
+		Level:      list.level,
+		MaxLevel:   list.maxLevel,
+		LevelNodes: make([]int64, list.level),
+		NodeBytes:  skipListNodeBytes[K, S, V](list.maxLevel),
+	}
+	step := max(list.size/SKIP_TABLE_STATS_SAMPLES, 1)
+	total := 0
+	rank := int64(0)
+	for t := list.head.Next(0); t != nil; t = t.Next(0) {
+		rank++
+		for i := range t.level {
+			stats.LevelNodes[i]++
+		}
+		stats.NodeBytes += skipListNodeBytes[K, S, V](len(t.level))
+		if rank%step == 0 && stats.Samples < SKIP_TABLE_STATS_SAMPLES {
+			path := list.searchPath(t)
+			total += path
+			stats.MaxSearchPath = max(stats.MaxSearchPath, path)
+			stats.Samples++
+		}
+	}
+	if stats.Samples > 0 {
+		stats.AvgSearchPath = float64(total) / float64(stats.Samples)
+	}
+	return stats
+}
+
+// searchPath
+// 从头结点查找 node 时需要比较的结点数量
+func (list *ScoredSkipList[K, S, V]) searchPath(node *ScoredSkipListNode[K, S, V]) int {
+	path := 0
+	t := list.head
+	for i := list.level - 1; i >= 0; i-- {
+		for t.Next(i) != nil {
+			path++
+			if !list.before(t.Next(i).score, t.Next(i).value, node.score, node.value) {
+				break
+			}
+			t = t.Next(i)
+		}
+	}
+	return path
+}
+
+// listpackEntryBytes
+// 紧凑编码和B树中一个元素占用的内存
+func listpackEntryBytes[S cmp.Ordered, V any]() int64 {
+	var e listpackEntry[S, V]
+	return int64(unsafe.Sizeof(e))
+}
+
+func (lp *listpack[K, S, V]) stats() SortSetStats {
+	return SortSetStats{
+		Encoding:   lp.name(),
+		Count:      lp.count(),
+		IndexBytes: int64(unsafe.Sizeof(*lp)) + int64(cap(lp.entries))*listpackEntryBytes[S, V](),
+	}
+}
+
+func (lp *listpack[K, S, V]) memoryUsage(key K) (int64, bool) {
+	if lp.find(key) < 0 {
+		return 0, false
+	}
+	return listpackEntryBytes[S, V](), true
+}
+
+// indexBytes
+// B树所有结点占用的内存
+func (tree *btreeIndex[K, S, V]) indexBytes() int64 {
+	var bytes func(n *btreeNode[S, V]) int64
+	bytes = func(n *btreeNode[S, V]) int64 {
+		b := int64(unsafe.Sizeof(*n)) +
+			int64(cap(n.items)+cap(n.keys))*listpackEntryBytes[S, V]() +
+			int64(cap(n.children))*int64(unsafe.Sizeof(n)) +
+			int64(cap(n.counts))*8 + int64(cap(n.sums))*8
+		for _, child := range n.children {
+			b += bytes(child)
+		}
+		return b
+	}
+	return int64(unsafe.Sizeof(*tree)) + bytes(tree.root)
+}
+
+// memberBytes
+// 一个元素在B树中占用的内存, 内部结点很少, 不计算
+func (tree *btreeIndex[K, S, V]) memberBytes(score S, value V) int64 {
+	return listpackEntryBytes[S, V]()
+}
+
+// indexBytes
+// 跳表所有结点占用的内存
+func (list *ScoredSkipList[K, S, V]) indexBytes() int64 {
+	return list.Stats().NodeBytes
+}
+
+// memberBytes
+// 一个元素的结点占用的内存
+func (list *ScoredSkipList[K, S, V]) memberBytes(score S, value V) int64 {
+	node, _ := list.findNode(score, value)
+	if node == nil {
+		return 0
+	}
+	return skipListNodeBytes[K, S, V](len(node.level))
+}
+
+func (enc *indexEncoding[K, S, V]) stats() SortSetStats {
+	stats := SortSetStats{
+		Encoding:   enc.name(),
+		Count:      enc.count(),
+		IndexBytes: enc.idx.indexBytes(),
+		MapBytes:   int64(float64(enc.member.count()) * memberEntryBytes(enc.member)),
+	}
+	if sl, ok := enc.idx.(*ScoredSkipList[K, S, V]); ok {
+		s := sl.Stats()
+		stats.SkipList = &s
+	}
+	return stats
+}
+
+func (enc *indexEncoding[K, S, V]) memoryUsage(key K) (int64, bool) {
+	m, ok := enc.member.get(key)
+	if !ok {
+		return 0, false
+	}
+	return enc.idx.memberBytes(m.score, m.value) + int64(memberEntryBytes(enc.member)), true
+}
+
+// Stats
+// 返回有序集合的编码、元素数量和占用的内存, 底层是跳表时还会返回跳表的统计信息, 复杂度 O(n)
+func (set *ScoredSortSet[K, S, V]) Stats() SortSetStats {
+	return set.enc.stats()
+}
+
+// MemoryUsage
+// 返回一个元素占用的内存(字节), 类似 redis 的 MEMORY USAGE, 不包含 value 指向的数据, 是估算值
+// 元素不存在时返回 false
+func (set *ScoredSortSet[K, S, V]) MemoryUsage(key K) (int64, bool) {
+	return set.enc.memoryUsage(key)
+}
@@ -0,0 +1,69 @@
+package skiptablev2
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSkipList_Stats(t *testing.T) {
+	st, err := NewDefaultSkipTable[string, *S1[string]](func(v1, v2 *S1[string]) int {
+		return strings.Compare(v1.key, v2.key)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const N = 20000
+	for i := 0; i < N; i++ {
+		st.InsertByScore(float64(i), &S1[string]{key: strconv.Itoa(i), f: float64(i)})
+	}
+	stats := st.Stats()
+	if stats.Size != N || stats.Level != st.level || len(stats.LevelNodes) != st.level || stats.LevelNodes[0] != N {
+		t.Fatalf("stats:%+v", stats)
+	}
+	for i := 1; i < len(stats.LevelNodes); i++ {
+		if stats.LevelNodes[i] > stats.LevelNodes[i-1] || stats.LevelNodes[i] == 0 {
+			t.Fatalf("LevelNodes:%v", stats.LevelNodes)
+		}
+	}
+	//每一层的结点数量大约是下一层的 SKIPLIST_P
+	if p := float64(stats.LevelNodes[1]) / N; p < SKIPLIST_P*0.8 || p > SKIPLIST_P*1.2 {
+		t.Fatalf("level 1 ratio:%f", p)
+	}
+	if stats.Samples != SKIP_TABLE_STATS_SAMPLES || stats.AvgSearchPath <= 0 || float64(stats.MaxSearchPath) < stats.AvgSearchPath {
+		t.Fatalf("search path stats:%+v", stats)
+	}
+	if stats.NodeBytes < N*skipListNodeBytes[string, float64, *S1[string]](1) {
+		t.Fatalf("NodeBytes:%d", stats.NodeBytes)
+	}
+}
+
+func TestSortSet_Stats(t *testing.T) {
+	for _, c := range sortSetIndexCases {
+		t.Run(c.name, func(t *testing.T) {
+			set, err := NewSortSetWithConfig[string, *StItem[string]](c.config, compareStItemKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 1000; i++ {
+				set.Add(&StItem[string]{k: strconv.Itoa(i), f: float64(i % 100)})
+			}
+			stats := set.Stats()
+			if stats.Encoding != set.Encoding() || stats.Count != 1000 || stats.IndexBytes <= 0 {
+				t.Fatalf("stats:%+v", stats)
+			}
+			if (stats.Encoding == SORT_SET_ENCODING_LISTPACK) != (stats.MapBytes == 0) {
+				t.Fatalf("MapBytes:%d encoding:%s", stats.MapBytes, stats.Encoding)
+			}
+			if (stats.Encoding == SORT_SET_ENCODING_SKIPLIST) != (stats.SkipList != nil) {
+				t.Fatalf("SkipList stats:%v encoding:%s", stats.SkipList, stats.Encoding)
+			}
+			if bytes, ok := set.MemoryUsage("10"); !ok || bytes <= 0 || bytes > stats.Bytes() {
+				t.Fatalf("MemoryUsage %d %v", bytes, ok)
+			}
+			if _, ok := set.MemoryUsage("missing"); ok {
+				t.Fatal("MemoryUsage missing key")
+			}
+		})
+	}
+}
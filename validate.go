@@ -0,0 +1,198 @@
+package skiptablev2
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// sumNear
+// 分数之和是增量维护的, 有舍入误差, scale 是参与计算的分数的绝对值之和
+func sumNear(got, want, scale float64) bool {
+	if got == want {
+		return true
+	}
+	return math.Abs(got-want) <= 1e-9*(scale+1)
+}
+
+// Validate
+// 检查跳表的结构是否正确, 发现问题时返回描述问题的错误, 复杂度 O(n * level)
+// 检查的内容包括: 第0层的顺序、每一层的 forward 指针、span、分数之和和不同分数的数量、后指针、tail、size 和 level,
+// 分数不能是 NaN, 调用过 EnableUniqueKeys 时还会检查 key 的索引
+func (list *ScoredSkipList[K, S, V]) Validate() error {
+	if list.head == nil {
+		return fmt.Errorf("Validate head is nil")
+	}
+	if list.level < 1 || list.level > list.maxLevel {
+		return fmt.Errorf("Validate level %d out of range [1,%d]", list.level, list.maxLevel)
+	}
+	if len(list.head.level) != list.maxLevel {
+		return fmt.Errorf("Validate head has %d levels, want %d", len(list.head.level), list.maxLevel)
+	}
+
+	//第0层: 顺序、后指针、结点的层数, 同时记录每个结点的排名和前缀和
+	rank := make(map[*ScoredSkipListNode[K, S, V]]int64, list.size)
+	rank[list.head] = 0
+	prefixSum := []float64{0}
+	prefixAbs := []float64{0}
+	prefixGroups := []int64{0}
+	var pre *ScoredSkipListNode[K, S, V]
+	for t := list.head.Next(0); t != nil; t = t.Next(0) {
+		r := int64(len(prefixSum))
+		if _, ok := rank[t]; ok {
+			return fmt.Errorf("Validate level 0 has a cycle at rank %d", r)
+		}
+		if r > list.size {
+			return fmt.Errorf("Validate level 0 has more than size %d nodes", list.size)
+		}
+		if t.freed {
+			return fmt.Errorf("Validate node %v at rank %d is freed", t.value.Key(), r)
+		}
+		if len(t.level) < 1 || len(t.level) > list.maxLevel {
+			return fmt.Errorf("Validate node %v at rank %d has %d levels, want [1,%d]", t.value.Key(), r, len(t.level), list.maxLevel)
+		}
+		if len(t.level) > list.level {
+			return fmt.Errorf("Validate node %v at rank %d has %d levels, more than list level %d", t.value.Key(), r, len(t.level), list.level)
+		}
+		if t.backward != pre {
+			return fmt.Errorf("Validate node %v at rank %d backward pointer is wrong", t.value.Key(), r)
+		}
+		if scoreIsNaN(t.score) {
+			return fmt.Errorf("Validate node %v at rank %d score is NaN", t.value.Key(), r)
+		}
+		if list.keys != nil && list.keys[t.value.Key()] != t {
+			return fmt.Errorf("Validate node %v at rank %d is not the node of its key", t.value.Key(), r)
+		}
+		if pre != nil && !list.before(pre.score, pre.value, t.score, t.value) {
+			return fmt.Errorf("Validate node %v at rank %d is not after node %v", t.value.Key(), r, pre.value.Key())
+		}
+		rank[t] = r
+		f := sumFloat(t.score)
+		prefixSum = append(prefixSum, prefixSum[r-1]+f)
+		prefixAbs = append(prefixAbs, prefixAbs[r-1]+math.Abs(f))
+		prefixGroups = append(prefixGroups, prefixGroups[r-1]+list.groupStart(pre, t.score))
+		pre = t
+	}
+	size := int64(len(prefixSum)) - 1
+	if size != list.size {
+		return fmt.Errorf("Validate size is %d, level 0 has %d nodes", list.size, size)
+	}
+	if list.tail != pre {
+		return fmt.Errorf("Validate tail is not the last node")
+	}
+	if list.keys != nil && int64(len(list.keys)) != size {
+		return fmt.Errorf("Validate key index has %d keys, level 0 has %d nodes", len(list.keys), size)
+	}
+	if !sumNear(list.sum, prefixSum[size], prefixAbs[size]) {
+		return fmt.Errorf("Validate sum is %f, want %f", list.sum, prefixSum[size])
+	}
+	if list.groups != prefixGroups[size] {
+		return fmt.Errorf("Validate groups is %d, want %d", list.groups, prefixGroups[size])
+	}
+
+	//最高层不能是空的, 更高的层不能有结点
+	if list.level > 1 && list.head.Next(list.level-1) == nil {
+		return fmt.Errorf("Validate level %d is empty, level is not trimmed", list.level-1)
+	}
+	for i := list.level; i < list.maxLevel; i++ {
+		if list.head.Next(i) != nil {
+			return fmt.Errorf("Validate level %d is above list level %d but not empty", i, list.level)
+		}
+	}
+
+	//每一层: 经过的结点都有这一层, 排名递增, span、分数之和、不同分数的数量和第0层一致, 没有漏掉结点
+	for i := 0; i < list.level; i++ {
+		count := int64(0)
+		for t := list.head; t != nil; t = t.Next(i) {
+			if t != list.head {
+				count++
+			}
+			r := rank[t]
+			next := size
+			if t.Next(i) != nil {
+				n, ok := rank[t.Next(i)]
+				if !ok {
+					return fmt.Errorf("Validate level %d rank %d forward points to a node not in level 0", i, r)
+				}
+				if n <= r {
+					return fmt.Errorf("Validate level %d rank %d forward points back to rank %d", i, r, n)
+				}
+				if len(t.Next(i).level) <= i {
+					return fmt.Errorf("Validate level %d rank %d forward points to a node with %d levels", i, r, len(t.Next(i).level))
+				}
+				next = n
+			}
+			if t.Span(i) != next-r {
+				return fmt.Errorf("Validate level %d rank %d span is %d, want %d", i, r, t.Span(i), next-r)
+			}
+			if want := prefixSum[next] - prefixSum[r]; !sumNear(t.Sum(i), want, prefixAbs[next]-prefixAbs[r]) {
+				return fmt.Errorf("Validate level %d rank %d sum is %f, want %f", i, r, t.Sum(i), want)
+			}
+			if want := prefixGroups[next] - prefixGroups[r]; t.level[i].groups != want {
+				return fmt.Errorf("Validate level %d rank %d groups is %d, want %d", i, r, t.level[i].groups, want)
+			}
+		}
+		//这一层经过的结点数量要等于层数 > i 的结点数量
+		want := int64(0)
+		for t := list.head.Next(0); t != nil; t = t.Next(0) {
+			if len(t.level) > i {
+				want++
+			}
+		}
+		if count != want {
+			return fmt.Errorf("Validate level %d links %d nodes, want %d", i, count, want)
+		}
+	}
+	return nil
+}
+
+// Rebuild
+// 根据第0层的 forward 指针重新构建跳表的其他部分(各层的指针、span、分数之和、后指针、tail、size、level)
+// 结点对象和它们的层数不变, 所以持有结点的地方(比如 SortSet 的map)不需要更新
+// 第0层的顺序不对时会重新排序, 第0层有环时在环的入口处断开, 复杂度 O(n log n)
+func (list *ScoredSkipList[K, S, V]) Rebuild() {
+	seen := make(map[*ScoredSkipListNode[K, S, V]]struct{})
+	var nodes []*ScoredSkipListNode[K, S, V]
+	for t := list.head.Next(0); t != nil; t = t.Next(0) {
+		if _, ok := seen[t]; ok {
+			break
+		}
+		seen[t] = struct{}{}
+		nodes = append(nodes, t)
+	}
+	slices.SortStableFunc(nodes, func(a, b *ScoredSkipListNode[K, S, V]) int {
+		if list.before(a.score, a.value, b.score, b.value) {
+			return -1
+		}
+		if list.before(b.score, b.value, a.score, a.value) {
+			return 1
+		}
+		return 0
+	})
+
+	clear(list.head.level)
+	list.tail = nil
+	list.size = 0
+	list.level = 1
+	list.sum = 0
+	list.groups = 0
+	if list.keys != nil {
+		list.keys = make(map[K]*ScoredSkipListNode[K, S, V], len(nodes))
+	}
+	b := list.newBuilder()
+	for _, node := range nodes {
+		if len(node.level) > list.maxLevel {
+			node.level = node.level[:list.maxLevel]
+		}
+		clear(node.level)
+		b.appendNode(node)
+	}
+	b.finish()
+}
+
+// Validate
+// 检查有序集合底层数据结构是否正确, 发现问题时返回描述问题的错误
+// 编码是 map + 跳表(或其他有序索引)时, 还会检查 map 和有序索引中的元素是否一一对应
+func (set *ScoredSortSet[K, S, V]) Validate() error {
+	return set.enc.validate()
+}
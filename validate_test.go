@@ -0,0 +1,134 @@
+package skiptablev2
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newValidateTestList(t *testing.T) *SkipList[string, *S1[string]] {
+	st, err := NewDefaultSkipTable[string, *S1[string]](func(v1, v2 *S1[string]) int {
+		return strings.Compare(v1.key, v2.key)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := make(map[string]*SkipListNode[string, *S1[string]])
+	for i := 0; i < 2000; i++ {
+		key := strconv.Itoa(rand.Intn(500))
+		score := float64(rand.Intn(50))
+		node, ok := nodes[key]
+		switch {
+		case !ok:
+			nodes[key] = st.InsertByScore(score, &S1[string]{key: key, f: score})
+		case rand.Intn(3) == 0:
+			st.Delete(node, st.GetUpdateList(node))
+			delete(nodes, key)
+		default:
+			st.UpdateScore(node, score)
+		}
+		if i%100 == 0 {
+			if err := st.Validate(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := st.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestSkipList_Validate(t *testing.T) {
+	cases := []struct {
+		name    string
+		corrupt func(st *SkipList[string, *S1[string]])
+		want    string
+	}{
+		{"span", func(st *SkipList[string, *S1[string]]) {
+			st.head.Next(0).level[0].span++
+		}, "span"},
+		{"sum", func(st *SkipList[string, *S1[string]]) {
+			st.head.Next(0).level[0].sum += 1
+		}, "sum"},
+		{"groups", func(st *SkipList[string, *S1[string]]) {
+			st.head.level[st.level-1].groups++
+		}, "groups"},
+		{"backward", func(st *SkipList[string, *S1[string]]) {
+			st.tail.backward = st.tail
+		}, "backward"},
+		{"tail", func(st *SkipList[string, *S1[string]]) {
+			st.tail = st.head.Next(0)
+		}, "tail"},
+		{"size", func(st *SkipList[string, *S1[string]]) {
+			st.size++
+		}, "size"},
+		{"level", func(st *SkipList[string, *S1[string]]) {
+			st.level++
+		}, "not trimmed"},
+		{"order", func(st *SkipList[string, *S1[string]]) {
+			first, last := st.head.Next(0), st.tail
+			first.score, last.score = last.score, first.score
+		}, "is not after"},
+		{"forward", func(st *SkipList[string, *S1[string]]) {
+			//跳过最高层的第一个结点
+			i := st.level - 1
+			st.head.setNext(i, st.head.Next(i).Next(i))
+		}, "level"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			st := newValidateTestList(t)
+			c.corrupt(st)
+			err := st.Validate()
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Fatalf("Validate error:%v want:%s", err, c.want)
+			}
+			first := st.head.Next(0)
+			st.Rebuild()
+			if err := st.Validate(); err != nil {
+				t.Fatalf("Validate after Rebuild: %v", err)
+			}
+			if first == nil || st.Size() == 0 {
+				t.Fatal("Rebuild lost all nodes")
+			}
+		})
+	}
+}
+
+func TestSortSet_Validate(t *testing.T) {
+	for _, c := range sortSetIndexCases {
+		t.Run(c.name, func(t *testing.T) {
+			set, err := NewSortSetWithConfig[string, *StItem[string]](c.config, compareStItemKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 2000; i++ {
+				key := strconv.Itoa(rand.Intn(500))
+				if rand.Intn(4) == 0 {
+					set.Remove(key)
+				} else {
+					set.Add(&StItem[string]{k: key, f: float64(rand.Intn(50))})
+				}
+			}
+			if err := set.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			//map 和有序索引不一致
+			switch enc := set.enc.(type) {
+			case *indexEncoding[string, float64, *StItem[string]]:
+				enc.member.each(func(key string, m indexMember[float64, *StItem[string]]) bool {
+					m.score++
+					enc.member.set(key, m)
+					return false
+				})
+			case *listpack[string, float64, *StItem[string]]:
+				enc.entries[0], enc.entries[1] = enc.entries[1], enc.entries[0]
+			}
+			if err := set.Validate(); err == nil {
+				t.Fatal("Validate should find the corruption")
+			}
+		})
+	}
+}